@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	internalclient "kava/internal/database/client"
+	"kava/pkg/client"
+)
+
+const usage = `Usage: kava-bench [flags]
+
+Opens -connections connections to the KaVa and runs -requests queries
+(or runs for -duration) with the GET/SET/DEL mix, then reports
+throughput and latency percentiles per operation. Ctrl+C stops the
+run early and prints the report.
+
+Flags:
+`
+
+// settings -- параметры нагрузки
+type settings struct {
+	address     string
+	connections int
+	requests    int64
+	duration    time.Duration
+	mix         mix
+	keys        keySpace
+	values      valueSizes
+	populate    bool
+	seed        uint64
+	quiet       bool
+}
+
+func main() {
+	address := flag.String("address", "localhost:8080", "Address of the KaVa, unix:///path/kava.sock for unix socket")
+	connections := flag.Int("connections", 50, "Number of concurrent connections")
+	requests := flag.Int64("requests", 100000, "Total number of requests")
+	duration := flag.Duration("duration", 0, "Run for the duration instead of -requests")
+	mixValue := flag.String("mix", "get=80,set=20", "Operations mix as op=weight pairs: get, set, del")
+	keys := flag.Uint64("keys", 10000, "Size of the key space")
+	keyPrefix := flag.String("key_prefix", "bench:", "Prefix of the generated keys")
+	keyDistribution := flag.String("key_distribution", uniformDistribution, "Key distribution: uniform or zipf")
+	zipfS := flag.Float64("zipf_s", 1.1, "Exponent of the zipf distribution, greater than 1")
+	valueSize := flag.String("value_size", "64", "Value size in bytes, min-max for uniform sizes")
+	populate := flag.Bool("populate", false, "SET every key of the key space before the run")
+	timeout := flag.Duration("timeout", 5*time.Second, "Timeout of a single request")
+	seed := flag.Uint64("seed", 0, "Seed of the random generators, 0 - random")
+	output := flag.String("output", "text", "Report format: text or json")
+	quiet := flag.Bool("quiet", false, "Do not print progress")
+	user := flag.String("user", "", "User for AUTH")
+	password := flag.String("password", "", "Password for AUTH")
+	useTLS := flag.Bool("tls", false, "Use TLS for connection")
+	tlsCert := flag.String("tls_cert", "", "Client certificate for mutual TLS")
+	tlsKey := flag.String("tls_key", "", "Client private key for mutual TLS")
+	tlsCA := flag.String("tls_ca", "", "CA certificate to verify the KaVa")
+	tlsServerName := flag.String("tls_server_name", "", "Server name to verify, host of the address by default")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cfg := settings{
+		address:     *address,
+		connections: *connections,
+		requests:    *requests,
+		duration:    *duration,
+		keys: keySpace{
+			prefix:       *keyPrefix,
+			keys:         *keys,
+			distribution: *keyDistribution,
+			zipfS:        *zipfS,
+		},
+		populate: *populate,
+		seed:     *seed,
+		quiet:    *quiet,
+	}
+
+	var err error
+	if cfg.mix, err = parseMix(*mixValue); err != nil {
+		failUsage(err)
+	}
+	if cfg.values, err = parseValueSize(*valueSize); err != nil {
+		failUsage(err)
+	}
+	if err := cfg.keys.validate(); err != nil {
+		failUsage(err)
+	}
+	switch {
+	case cfg.connections <= 0:
+		failUsage(errors.New("connections must be positive"))
+	case cfg.duration < 0:
+		failUsage(errors.New("duration must not be negative"))
+	case cfg.duration == 0 && cfg.requests <= 0:
+		failUsage(errors.New("requests must be positive"))
+	case *output != "text" && *output != "json":
+		failUsage(fmt.Errorf("unsupported output format: %s", *output))
+	}
+	if cfg.seed == 0 {
+		cfg.seed = rand.Uint64()
+	}
+
+	options := []client.Option{
+		client.WithPoolSize(cfg.connections),
+		client.WithRequestTimeout(*timeout),
+		// повторы исказили бы задержки, ошибки попадают в отчет
+		client.WithRetries(0, 0),
+	}
+	if *user != "" {
+		options = append(options, client.WithAuth(*user, *password))
+	}
+	if *useTLS || *tlsCert != "" || *tlsCA != "" {
+		var tlsConfig *tls.Config
+		tlsConfig, err = internalclient.NewTLSConfig(*tlsCert, *tlsKey, *tlsCA, *tlsServerName)
+		if err != nil {
+			failUsage(fmt.Errorf("failed to load tls settings: %w", err))
+		}
+		options = append(options, client.WithTLS(tlsConfig))
+	}
+
+	kava, err := client.New(cfg.address, options...)
+	if err != nil {
+		fail(err)
+	}
+	defer kava.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	result, elapsed, err := run(ctx, kava, cfg)
+	if err != nil {
+		kava.Close()
+		fail(err)
+	}
+
+	r := newReport(cfg, result, elapsed)
+	if *output == "json" {
+		if err := r.writeJSON(os.Stdout); err != nil {
+			fail(err)
+		}
+		return
+	}
+	r.writeText(os.Stdout)
+}
+
+// run -- открывает соединения, при необходимости заполняет ключи
+// и выполняет нагрузку, время подключения в замеры не входит
+func run(ctx context.Context, kava *client.Client, cfg settings) (*recorder, time.Duration, error) {
+	// все соединения открываются до замеров, иначе первые запросы
+	// включали бы время подключения
+	if err := kava.Warmup(ctx, cfg.connections); err != nil {
+		return nil, 0, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	data := newValues(cfg.values, rand.New(rand.NewPCG(cfg.seed, 0)))
+	if cfg.populate {
+		if err := populate(ctx, kava, cfg, data); err != nil {
+			return nil, 0, fmt.Errorf("failed to populate keys: %w", err)
+		}
+	}
+
+	// остановка по -duration или сигналу не отменяет выполняющиеся запросы
+	var stopped atomic.Bool
+	if cfg.duration > 0 {
+		timer := time.AfterFunc(cfg.duration, func() { stopped.Store(true) })
+		defer timer.Stop()
+	}
+	cancelStop := context.AfterFunc(ctx, func() { stopped.Store(true) })
+	defer cancelStop()
+
+	var issued, completed atomic.Int64
+	next := func() bool {
+		if stopped.Load() {
+			return false
+		}
+		return cfg.duration > 0 || issued.Add(1) <= cfg.requests
+	}
+
+	progressDone := make(chan struct{})
+	var progressWG sync.WaitGroup
+	if !cfg.quiet {
+		progressWG.Add(1)
+		go func() {
+			defer progressWG.Done()
+			printProgress(&completed, progressDone)
+		}()
+	}
+
+	recorders := make([]*recorder, cfg.connections)
+	started := time.Now()
+	_ = forEachWorker(cfg.connections, func(worker int) error {
+		recorders[worker] = work(kava, cfg, data, uint64(worker), next, &completed)
+		return nil
+	})
+	elapsed := time.Since(started)
+
+	close(progressDone)
+	progressWG.Wait()
+
+	result := newRecorder()
+	for _, r := range recorders {
+		result.merge(r)
+	}
+
+	return result, elapsed, nil
+}
+
+// work -- цикл одного воркера, next сообщает, нужно ли выполнить следующий запрос
+func work(
+	kava *client.Client,
+	cfg settings,
+	data *values,
+	worker uint64,
+	next func() bool,
+	completed *atomic.Int64,
+) *recorder {
+	r := rand.New(rand.NewPCG(cfg.seed, worker+1))
+	nextKey := cfg.keys.generator(r)
+	result := newRecorder()
+	ctx := context.Background()
+
+	for next() {
+		op, key := cfg.mix.pick(r), nextKey()
+
+		var err error
+		started := time.Now()
+		switch op {
+		case opGet:
+			_, err = kava.Get(ctx, key)
+		case opSet:
+			err = kava.Set(ctx, key, data.next(r))
+		case opDel:
+			err = kava.Del(ctx, key)
+		}
+		latency := time.Since(started)
+		completed.Add(1)
+
+		switch {
+		case errors.Is(err, client.ErrNotFound):
+			result.misses[op]++
+			fallthrough
+		case err == nil:
+			result.recordLatency(op, latency)
+		default:
+			result.errors[op]++
+			result.lastError = err
+		}
+	}
+
+	return result
+}
+
+func populate(ctx context.Context, kava *client.Client, cfg settings, data *values) error {
+	workers := uint64(cfg.connections)
+	return forEachWorker(cfg.connections, func(worker int) error {
+		r := rand.New(rand.NewPCG(cfg.seed, uint64(worker)+workers+1))
+		for index := uint64(worker); index < cfg.keys.keys; index += workers {
+			if err := kava.Set(ctx, cfg.keys.key(index), data.next(r)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// forEachWorker -- запускает action в count горутинах и возвращает первую ошибку
+func forEachWorker(count int, action func(worker int) error) error {
+	errs := make([]error, count)
+	var wg sync.WaitGroup
+	for worker := 0; worker < count; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[worker] = action(worker)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func printProgress(completed *atomic.Int64, done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var previous int64
+	for {
+		select {
+		case <-done:
+			fmt.Fprintln(os.Stderr)
+			return
+		case <-ticker.C:
+		}
+
+		current := completed.Load()
+		fmt.Fprintf(os.Stderr, "\r%d requests, %d requests/s   ", current, current-previous)
+		previous = current
+	}
+}
+
+func failUsage(err error) {
+	fmt.Fprintf(os.Stderr, "kava-bench: %s\n", err)
+	os.Exit(2)
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "kava-bench: %s\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"math/bits"
+	"time"
+)
+
+const (
+	// histogramSubBucketBits -- каждый диапазон [2^k, 2^(k+1)) делится на 2^7 корзин,
+	// поэтому погрешность задержки в отчете не больше 1/128 ее значения
+	histogramSubBucketBits = 7
+	histogramSubBuckets    = 1 << histogramSubBucketBits
+	// histogramMax -- задержки дольше попадают в последнюю корзину, max хранится точно
+	histogramMax = time.Minute
+)
+
+var histogramBuckets = bucketIndex(histogramMax) + 1
+
+// histogram -- задержки в лог-линейных корзинах, как в HdrHistogram: память не
+// зависит от числа запросов. Задержки меньше 256ns хранятся точно
+type histogram struct {
+	counts []uint64
+	count  int
+	sum    time.Duration
+	max    time.Duration
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, histogramBuckets)}
+}
+
+func (h *histogram) record(latency time.Duration) {
+	latency = max(latency, 0)
+	h.counts[bucketIndex(min(latency, histogramMax))]++
+	h.count++
+	h.sum += latency
+	h.max = max(h.max, latency)
+}
+
+func (h *histogram) merge(other *histogram) {
+	for index, count := range other.counts {
+		h.counts[index] += count
+	}
+	h.count += other.count
+	h.sum += other.sum
+	h.max = max(h.max, other.max)
+}
+
+func (h *histogram) mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	return h.sum / time.Duration(h.count)
+}
+
+// percentile -- значение, не меньше которого p процентов задержек: верхняя
+// граница корзины, в которую попала задержка с этим рангом, но не больше max.
+// Для последней корзины с задержками дольше histogramMax - max
+func (h *histogram) percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := uint64(max(1, min(int(float64(h.count)*p/100+0.5), h.count)))
+	var seen uint64
+	for index, count := range h.counts[:len(h.counts)-1] {
+		seen += count
+		if seen >= rank {
+			return min(bucketUpperBound(index), h.max)
+		}
+	}
+
+	return h.max
+}
+
+// bucketIndex -- до 2*histogramSubBuckets корзина на каждую наносекунду, дальше
+// каждое удвоение задержки добавляет histogramSubBuckets корзин вдвое шире прежних
+func bucketIndex(latency time.Duration) int {
+	value := uint64(latency)
+	if value < 2*histogramSubBuckets {
+		return int(value)
+	}
+
+	shift := bits.Len64(value) - 1 - histogramSubBucketBits
+	return shift*histogramSubBuckets + int(value>>shift)
+}
+
+func bucketUpperBound(index int) time.Duration {
+	if index < 2*histogramSubBuckets {
+		return time.Duration(index)
+	}
+
+	shift := index/histogramSubBuckets - 1
+	base := index - shift*histogramSubBuckets
+	return time.Duration(uint64(base+1)<<shift - 1)
+}
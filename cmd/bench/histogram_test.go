@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramPercentile(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		latencies func() []time.Duration
+		p         float64
+
+		expected time.Duration
+	}{
+		"empty": {
+			latencies: func() []time.Duration { return nil },
+			p:         50,
+			expected:  0,
+		},
+		"single": {
+			latencies: func() []time.Duration { return []time.Duration{time.Millisecond} },
+			p:         99.9,
+			expected:  time.Millisecond,
+		},
+		"exact small values": {
+			latencies: func() []time.Duration { return sequence(1, 100, 1) },
+			p:         50,
+			expected:  50,
+		},
+		"median": {
+			latencies: func() []time.Duration { return sequence(time.Microsecond, 1000, time.Microsecond) },
+			p:         50,
+			expected:  500 * time.Microsecond,
+		},
+		"tail": {
+			latencies: func() []time.Duration { return sequence(time.Microsecond, 1000, time.Microsecond) },
+			p:         99,
+			expected:  990 * time.Microsecond,
+		},
+		"maximum is exact": {
+			latencies: func() []time.Duration { return []time.Duration{time.Microsecond, 1234567 * time.Nanosecond} },
+			p:         100,
+			expected:  1234567 * time.Nanosecond,
+		},
+		"longer than histogram range": {
+			latencies: func() []time.Duration { return []time.Duration{time.Second, 2 * time.Minute} },
+			p:         100,
+			expected:  2 * time.Minute,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h := newHistogram()
+			for _, latency := range test.latencies() {
+				h.record(latency)
+			}
+
+			// значение корзины не меньше точного и отличается не больше чем на 1/128
+			actual := h.percentile(test.p)
+			assert.GreaterOrEqual(t, actual, test.expected)
+			assert.LessOrEqual(t, actual, test.expected+test.expected/histogramSubBuckets)
+		})
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	t.Parallel()
+
+	previous := -1
+	for _, latency := range []time.Duration{0, 255, 256, 258, 511, 512, time.Millisecond, time.Second, histogramMax} {
+		index := bucketIndex(latency)
+		require.Greater(t, index, previous)
+		require.Less(t, index, histogramBuckets)
+		previous = index
+
+		upper := bucketUpperBound(index)
+		assert.GreaterOrEqual(t, upper, latency)
+		assert.Equal(t, index, bucketIndex(upper))
+		assert.Equal(t, index+1, bucketIndex(upper+1))
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	t.Parallel()
+
+	first, second := newHistogram(), newHistogram()
+	for _, latency := range sequence(time.Microsecond, 50, time.Microsecond) {
+		first.record(latency)
+	}
+	for _, latency := range sequence(51*time.Microsecond, 50, time.Microsecond) {
+		second.record(latency)
+	}
+
+	first.merge(second)
+	assert.Equal(t, 100, first.count)
+	assert.Equal(t, 100*time.Microsecond, first.max)
+	assert.Equal(t, 50500*time.Nanosecond, first.mean())
+	assert.InEpsilon(t, float64(50*time.Microsecond), float64(first.percentile(50)), 1.0/histogramSubBuckets)
+}
+
+// sequence -- count задержек от start с шагом step
+func sequence(start time.Duration, count int, step time.Duration) []time.Duration {
+	latencies := make([]time.Duration, 0, count)
+	for i := 0; i < count; i++ {
+		latencies = append(latencies, start+time.Duration(i)*step)
+	}
+
+	return latencies
+}
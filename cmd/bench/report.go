@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// recorder -- результаты одного воркера, объединяются после завершения
+type recorder struct {
+	latencies map[string]*histogram
+	misses    map[string]int
+	errors    map[string]int
+	lastError error
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		latencies: make(map[string]*histogram),
+		misses:    make(map[string]int),
+		errors:    make(map[string]int),
+	}
+}
+
+func (r *recorder) recordLatency(op string, latency time.Duration) {
+	r.histogram(op).record(latency)
+}
+
+// histogram -- гистограмма операции создается при первой задержке
+func (r *recorder) histogram(op string) *histogram {
+	h, exist := r.latencies[op]
+	if !exist {
+		h = newHistogram()
+		r.latencies[op] = h
+	}
+
+	return h
+}
+
+func (r *recorder) merge(other *recorder) {
+	for op, latencies := range other.latencies {
+		r.histogram(op).merge(latencies)
+	}
+	for op, count := range other.misses {
+		r.misses[op] += count
+	}
+	for op, count := range other.errors {
+		r.errors[op] += count
+	}
+	if other.lastError != nil {
+		r.lastError = other.lastError
+	}
+}
+
+// opReport -- итоги по операции, задержки в миллисекундах
+type opReport struct {
+	Operation  string  `json:"operation"`
+	Requests   int     `json:"requests"`
+	Throughput float64 `json:"throughput"`
+	Misses     int     `json:"misses"`
+	Errors     int     `json:"errors"`
+	Mean       float64 `json:"mean_ms"`
+	P50        float64 `json:"p50_ms"`
+	P90        float64 `json:"p90_ms"`
+	P99        float64 `json:"p99_ms"`
+	P999       float64 `json:"p99_9_ms"`
+	Max        float64 `json:"max_ms"`
+}
+
+type report struct {
+	Address     string     `json:"address"`
+	Connections int        `json:"connections"`
+	Mix         string     `json:"mix"`
+	Keys        string     `json:"keys"`
+	Values      string     `json:"values"`
+	Duration    float64    `json:"duration_seconds"`
+	Total       opReport   `json:"total"`
+	Operations  []opReport `json:"operations"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+func newReport(settings settings, result *recorder, elapsed time.Duration) report {
+	r := report{
+		Address:     settings.address,
+		Connections: settings.connections,
+		Mix:         settings.mix.String(),
+		Keys:        settings.keys.String(),
+		Values:      settings.values.String(),
+		Duration:    elapsed.Seconds(),
+	}
+	if result.lastError != nil {
+		r.LastError = result.lastError.Error()
+	}
+
+	all := newHistogram()
+	var misses, errors int
+	for _, op := range operations {
+		latencies, exist := result.latencies[op]
+		if !exist && result.errors[op] == 0 {
+			continue
+		}
+		if !exist {
+			latencies = newHistogram()
+		}
+
+		r.Operations = append(r.Operations, newOpReport(op, latencies, result.misses[op], result.errors[op], elapsed))
+		all.merge(latencies)
+		misses += result.misses[op]
+		errors += result.errors[op]
+	}
+	r.Total = newOpReport("TOTAL", all, misses, errors, elapsed)
+
+	return r
+}
+
+// newOpReport -- в задержки и пропускную способность входят все выполненные
+// запросы, в том числе промахи, запросы с ошибками не учитываются
+func newOpReport(op string, latencies *histogram, misses, errors int, elapsed time.Duration) opReport {
+	r := opReport{
+		Operation: op,
+		Requests:  latencies.count,
+		Misses:    misses,
+		Errors:    errors,
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(latencies.count) / elapsed.Seconds()
+	}
+	if latencies.count == 0 {
+		return r
+	}
+
+	r.Mean = milliseconds(latencies.mean())
+	r.P50 = milliseconds(latencies.percentile(50))
+	r.P90 = milliseconds(latencies.percentile(90))
+	r.P99 = milliseconds(latencies.percentile(99))
+	r.P999 = milliseconds(latencies.percentile(99.9))
+	r.Max = milliseconds(latencies.max)
+
+	return r
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (r report) writeJSON(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+func (r report) writeText(out io.Writer) {
+	fmt.Fprintf(out, "address: %s, connections: %d\n", r.Address, r.Connections)
+	fmt.Fprintf(out, "mix: %s, keys: %s, values: %s\n\n", r.Mix, r.Keys, r.Values)
+	fmt.Fprintf(out, "%d requests in %.2fs, %.0f requests/s, %d errors\n\n",
+		r.Total.Requests, r.Duration, r.Total.Throughput, r.Total.Errors)
+
+	fmt.Fprintf(out, "%-6s %10s %10s %8s %7s %9s %9s %9s %9s %9s %9s\n",
+		"op", "requests", "req/s", "misses", "errors", "mean ms", "p50 ms", "p90 ms", "p99 ms", "p99.9 ms", "max ms")
+	for _, op := range append(r.Operations, r.Total) {
+		fmt.Fprintf(out, "%-6s %10d %10.0f %8d %7d %9.3f %9.3f %9.3f %9.3f %9.3f %9.3f\n",
+			op.Operation, op.Requests, op.Throughput, op.Misses, op.Errors,
+			op.Mean, op.P50, op.P90, op.P99, op.P999, op.Max)
+	}
+
+	if r.LastError != "" {
+		fmt.Fprintf(out, "\nlast error: %s\n", r.LastError)
+	}
+}
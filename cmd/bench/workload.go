@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// Операции нагрузки
+const (
+	opGet = "GET"
+	opSet = "SET"
+	opDel = "DEL"
+)
+
+var operations = []string{opGet, opSet, opDel}
+
+// mix -- доли операций в нагрузке, сумма весов положительна
+type mix struct {
+	weights map[string]int
+	total   int
+}
+
+// parseMix -- строка вида "get=80,set=15,del=5", веса относительные
+func parseMix(value string) (mix, error) {
+	m := mix{weights: make(map[string]int)}
+	for _, item := range strings.Split(value, ",") {
+		name, weightValue, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found {
+			return mix{}, fmt.Errorf("invalid mix item %q, expected op=weight", item)
+		}
+
+		op := strings.ToUpper(name)
+		if op != opGet && op != opSet && op != opDel {
+			return mix{}, fmt.Errorf("unsupported operation %q in mix", name)
+		}
+
+		weight, err := strconv.Atoi(weightValue)
+		if err != nil || weight < 0 {
+			return mix{}, fmt.Errorf("invalid weight %q for %s", weightValue, op)
+		}
+
+		m.weights[op] += weight
+		m.total += weight
+	}
+
+	if m.total == 0 {
+		return mix{}, fmt.Errorf("mix %q has no operations", value)
+	}
+
+	return m, nil
+}
+
+func (m mix) pick(r *rand.Rand) string {
+	n := r.IntN(m.total)
+	for _, op := range operations {
+		if n < m.weights[op] {
+			return op
+		}
+		n -= m.weights[op]
+	}
+
+	return opGet
+}
+
+func (m mix) String() string {
+	var parts []string
+	for _, op := range operations {
+		if weight := m.weights[op]; weight != 0 {
+			parts = append(parts, fmt.Sprintf("%s %.0f%%", op, float64(weight)*100/float64(m.total)))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Распределения ключей
+const (
+	uniformDistribution = "uniform"
+	zipfDistribution    = "zipf"
+)
+
+// keySpace -- выбор ключа из keys ключей: uniform - равновероятно,
+// zipf - небольшая часть горячих ключей получает большинство запросов
+type keySpace struct {
+	prefix       string
+	keys         uint64
+	distribution string
+	zipfS        float64
+}
+
+func (k keySpace) validate() error {
+	switch {
+	case k.keys == 0:
+		return fmt.Errorf("keys must be positive")
+	case k.distribution != uniformDistribution && k.distribution != zipfDistribution:
+		return fmt.Errorf("unsupported key distribution %q", k.distribution)
+	case k.distribution == zipfDistribution && k.zipfS <= 1:
+		return fmt.Errorf("zipf exponent must be greater than 1, got %v", k.zipfS)
+	}
+
+	return nil
+}
+
+// generator -- ключи одного воркера, генераторы воркеров независимы
+func (k keySpace) generator(r *rand.Rand) func() string {
+	if k.distribution == zipfDistribution {
+		zipf := rand.NewZipf(r, k.zipfS, 1, k.keys-1)
+		return func() string {
+			return k.key(zipf.Uint64())
+		}
+	}
+
+	return func() string {
+		return k.key(r.Uint64N(k.keys))
+	}
+}
+
+func (k keySpace) key(index uint64) string {
+	return k.prefix + strconv.FormatUint(index, 10)
+}
+
+func (k keySpace) String() string {
+	if k.distribution == zipfDistribution {
+		return fmt.Sprintf("%d keys, zipf s=%v", k.keys, k.zipfS)
+	}
+
+	return fmt.Sprintf("%d keys, uniform", k.keys)
+}
+
+// valueSizes -- размер значения равномерно от min до max байт
+type valueSizes struct {
+	min, max int
+}
+
+// parseValueSize -- "64" - фиксированный размер, "16-256" - диапазон
+func parseValueSize(value string) (valueSizes, error) {
+	minValue, maxValue, isRange := strings.Cut(value, "-")
+	if !isRange {
+		maxValue = minValue
+	}
+
+	minSize, err := strconv.Atoi(minValue)
+	if err != nil {
+		return valueSizes{}, fmt.Errorf("invalid value size %q", value)
+	}
+	maxSize, err := strconv.Atoi(maxValue)
+	if err != nil {
+		return valueSizes{}, fmt.Errorf("invalid value size %q", value)
+	}
+
+	if minSize <= 0 || maxSize < minSize {
+		return valueSizes{}, fmt.Errorf("value size must be positive and min <= max, got %q", value)
+	}
+
+	return valueSizes{min: minSize, max: maxSize}, nil
+}
+
+func (v valueSizes) String() string {
+	if v.min == v.max {
+		return fmt.Sprintf("%d bytes", v.min)
+	}
+
+	return fmt.Sprintf("%d-%d bytes, uniform", v.min, v.max)
+}
+
+// valueAlphabet -- значения без пробелов, протокол разделяет аргументы пробелами
+const valueAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// values -- значения берутся срезами одного случайного буфера, чтобы
+// генерация не влияла на измерения
+type values struct {
+	sizes  valueSizes
+	buffer string
+}
+
+func newValues(sizes valueSizes, r *rand.Rand) *values {
+	buffer := make([]byte, sizes.max*2)
+	for i := range buffer {
+		buffer[i] = valueAlphabet[r.IntN(len(valueAlphabet))]
+	}
+
+	return &values{sizes: sizes, buffer: string(buffer)}
+}
+
+func (v *values) next(r *rand.Rand) string {
+	size := v.sizes.min
+	if v.sizes.max > v.sizes.min {
+		size += r.IntN(v.sizes.max - v.sizes.min + 1)
+	}
+
+	offset := r.IntN(len(v.buffer) - size + 1)
+	return v.buffer[offset : offset+size]
+}
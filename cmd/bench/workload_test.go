@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMix(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		value string
+
+		expectedWeights map[string]int
+		expectedTotal   int
+		expectedErr     string
+	}{
+		"all operations": {
+			value:           "get=80,set=15,del=5",
+			expectedWeights: map[string]int{opGet: 80, opSet: 15, opDel: 5},
+			expectedTotal:   100,
+		},
+		"spaces and case": {
+			value:           " GET=1 , Set=3",
+			expectedWeights: map[string]int{opGet: 1, opSet: 3},
+			expectedTotal:   4,
+		},
+		"repeated operation": {
+			value:           "get=1,get=2",
+			expectedWeights: map[string]int{opGet: 3},
+			expectedTotal:   3,
+		},
+		"zero weight": {
+			value:           "get=1,del=0",
+			expectedWeights: map[string]int{opGet: 1, opDel: 0},
+			expectedTotal:   1,
+		},
+		"missing weight": {
+			value:       "get",
+			expectedErr: `invalid mix item "get", expected op=weight`,
+		},
+		"unsupported operation": {
+			value:       "ping=1",
+			expectedErr: `unsupported operation "ping" in mix`,
+		},
+		"negative weight": {
+			value:       "get=-1",
+			expectedErr: `invalid weight "-1" for GET`,
+		},
+		"invalid weight": {
+			value:       "get=many",
+			expectedErr: `invalid weight "many" for GET`,
+		},
+		"no operations": {
+			value:       "get=0,set=0",
+			expectedErr: `mix "get=0,set=0" has no operations`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := parseMix(test.value)
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedWeights, m.weights)
+			assert.Equal(t, test.expectedTotal, m.total)
+		})
+	}
+}
+
+func TestParseValueSize(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		value string
+
+		expected    valueSizes
+		expectedErr string
+	}{
+		"fixed size":    {value: "64", expected: valueSizes{min: 64, max: 64}},
+		"range":         {value: "16-256", expected: valueSizes{min: 16, max: 256}},
+		"single range":  {value: "8-8", expected: valueSizes{min: 8, max: 8}},
+		"not a number":  {value: "big", expectedErr: `invalid value size "big"`},
+		"invalid max":   {value: "16-", expectedErr: `invalid value size "16-"`},
+		"zero size":     {value: "0", expectedErr: `value size must be positive and min <= max, got "0"`},
+		"reverse range": {value: "256-16", expectedErr: `value size must be positive and min <= max, got "256-16"`},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			sizes, err := parseValueSize(test.value)
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, sizes)
+		})
+	}
+}
@@ -2,63 +2,210 @@ package main
 
 import (
 	"bufio"
+	"crypto/tls"
 	"errors"
 	"flag"
 	"fmt"
-	"kava/internal/database/client"
+	"io"
 	"os"
-	"syscall"
+	"strings"
 	"time"
 
+	"kava/internal/database/client"
+
 	"code.cloudfoundry.org/bytefmt"
-	"go.uber.org/zap"
 )
 
+const prompt = "[kava] > "
+
+// Коды выхода
 const (
-	defaultBufferSize = 4 << 10
-	defaultIdleTimeout = 60 * time.Second
+	exitOK = 0
+	// exitQueryError -- хотя бы один запрос завершился ошибкой
+	exitQueryError = 1
+	// exitUsage -- некорректные флаги, как у пакета flag
+	exitUsage = 2
+	// exitConnectionError -- не удалось подключиться или восстановить соединение
+	exitConnectionError = 3
 )
 
+const usage = `Usage: kava-cli [flags] [command [arguments]]
+
+Without a command queries are read from -file, from stdin when it is
+a pipe, or interactively. Exit codes: 0 - all queries succeeded,
+1 - a query failed, 2 - invalid flags, 3 - connection failed.
+
+Flags:
+`
+
 func main() {
-	address := flag.String("address", "localhost:8080", "Address of the KaVa")
-	idleTimeout := flag.Duration("idle_timeout", time.Minute, "Idle timeout for connection")
-	maxMessageSizeStr := flag.String("max_message_size", "4KB", "Max message size for connection")
-	flag.Parse()
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
 
-	if *idleTimeout == 0 {
-		*idleTimeout = defaultIdleTimeout
+// run -- разбирает флаги, выполняет запросы и возвращает код выхода
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("kava-cli", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	address := flags.String("address", "localhost:8080", "Address of the KaVa, unix:///path/kava.sock for unix socket")
+	idleTimeout := flags.Duration("idle_timeout", time.Minute, "Idle timeout for connection")
+	maxMessageSizeStr := flags.String("max_message_size", "4KB", "Max message size for connection")
+	useTLS := flags.Bool("tls", false, "Use TLS for connection")
+	tlsCert := flags.String("tls_cert", "", "Client certificate for mutual TLS")
+	tlsKey := flags.String("tls_key", "", "Client private key for mutual TLS")
+	tlsCA := flags.String("tls_ca", "", "CA certificate to verify the KaVa")
+	tlsServerName := flags.String("tls_server_name", "", "Server name to verify, host of the address by default")
+	file := flags.String("file", "", "File with queries, one per line, # starts a comment")
+	output := flags.String("output", textOutput, "Output format: text or json (one object per line)")
+	reconnectAttempts := flags.Int("reconnect", 3, "Attempts to restore a lost connection, 0 disables reconnect")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	} else if err != nil {
+		return exitUsage
 	}
 
-	logger, _ := zap.NewProduction()
-	_, err := bytefmt.ToBytes(*maxMessageSizeStr)
+	if *idleTimeout <= 0 {
+		return fail(stderr, exitUsage, errors.New("idle timeout must be positive"))
+	}
 
+	maxMessageSize, err := bytefmt.ToBytes(*maxMessageSizeStr)
 	if err != nil {
-		logger.Fatal("failed to parse max message size", zap.Error(err))
+		return fail(stderr, exitUsage, fmt.Errorf("failed to parse max message size: %w", err))
+	}
+
+	if *output != textOutput && *output != jsonOutput {
+		return fail(stderr, exitUsage, fmt.Errorf("unsupported output format: %s", *output))
 	}
 
-	reader := bufio.NewReader(os.Stdin)
-	client, err := client.NewTCPClient(*address, defaultBufferSize, *idleTimeout)
+	if *reconnectAttempts < 0 {
+		return fail(stderr, exitUsage, errors.New("reconnect attempts must not be negative"))
+	}
 
-	if err != nil {
-		logger.Fatal("failed to create client", zap.Error(err))
+	var tlsConfig *tls.Config
+	if *useTLS || *tlsCert != "" || *tlsCA != "" {
+		tlsConfig, err = client.NewTLSConfig(*tlsCert, *tlsKey, *tlsCA, *tlsServerName)
+		if err != nil {
+			return fail(stderr, exitUsage, fmt.Errorf("failed to load tls settings: %w", err))
+		}
+	}
+
+	// ответ длиннее max_message_size не помещается в буфер клиента
+	bufferSize := int(maxMessageSize)
+	conn := &connection{
+		dial: func() (*client.TCPClient, error) {
+			if tlsConfig != nil {
+				return client.NewTLSClient(*address, bufferSize, *idleTimeout, tlsConfig)
+			}
+			return client.NewTCPClient(*address, bufferSize, *idleTimeout)
+		},
+		reconnectAttempts: *reconnectAttempts,
 	}
+	defer conn.close()
+
+	out := &printer{format: *output, out: stdout, errOut: stderr}
+
+	switch {
+	case flags.NArg() > 0:
+		return runQuery(conn, out, strings.Join(flags.Args(), " "))
+	case *file != "":
+		script, err := os.Open(*file)
+		if err != nil {
+			return fail(stderr, exitUsage, err)
+		}
+		defer script.Close()
+		return runScript(conn, out, script)
+	case !isTerminal(stdin):
+		return runScript(conn, out, stdin)
+	default:
+		return runInteractive(conn, out, stdin)
+	}
+}
 
+// runQuery -- один запрос из аргументов командной строки
+func runQuery(conn *connection, out *printer, query string) int {
+	failed, err := execute(conn, out, query)
+	switch {
+	case errors.Is(err, errConnection):
+		return exitConnectionError
+	case failed:
+		return exitQueryError
+	}
+
+	return exitOK
+}
+
+// runScript -- запросы из файла или pipe, пустые строки и комментарии
+// пропускаются, выполнение прерывается только при потере соединения
+func runScript(conn *connection, out *printer, in io.Reader) int {
+	code := exitOK
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		query := strings.TrimSpace(scanner.Text())
+		if query == "" || strings.HasPrefix(query, "#") {
+			continue
+		}
+
+		failed, err := execute(conn, out, query)
+		if errors.Is(err, errConnection) {
+			return exitConnectionError
+		} else if failed {
+			code = exitQueryError
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fail(out.errOut, exitQueryError, fmt.Errorf("failed to read queries: %w", err))
+	}
+
+	return code
+}
+
+// runInteractive -- запросы с терминала до конца ввода (Ctrl+D), ошибки
+// соединения не завершают сессию: следующий запрос подключается заново
+func runInteractive(conn *connection, out *printer, in io.Reader) int {
+	code := exitOK
+	reader := bufio.NewReader(in)
 	for {
-		fmt.Print("[kava] > ")
-		request, err := reader.ReadString('\n')
-		if errors.Is(err, syscall.EPIPE) {
-			logger.Fatal("connection was closed", zap.Error(err))
-		} else if err != nil {
-			logger.Error("failed to read query", zap.Error(err))
+		fmt.Fprint(out.out, prompt)
+		line, err := reader.ReadString('\n')
+		if query := strings.TrimSpace(line); query != "" {
+			if failed, _ := execute(conn, out, query); failed {
+				code = exitQueryError
+			}
 		}
 
-		response, err := client.Send([]byte(request))
-		if errors.Is(err, syscall.EPIPE) {
-			logger.Fatal("connection was closed", zap.Error(err))
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(out.out)
+			return code
 		} else if err != nil {
-			logger.Error("failed to send query", zap.Error(err))
+			return fail(out.errOut, exitQueryError, fmt.Errorf("failed to read query: %w", err))
 		}
+	}
+}
 
-		fmt.Println(string(response))
+// execute -- выполняет запрос и выводит ответ, failed - запрос не выполнен
+func execute(conn *connection, out *printer, query string) (failed bool, err error) {
+	response, err := conn.send(query)
+	r := newResult(query, response, err)
+	out.print(response, r, err)
+	return r.failed(), err
+}
+
+// isTerminal -- ввод с терминала, а не из файла или pipe
+func isTerminal(in io.Reader) bool {
+	file, ok := in.(*os.File)
+	if !ok {
+		return false
 	}
+
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func fail(errOut io.Writer, code int, err error) int {
+	fmt.Fprintf(errOut, "kava-cli: %s\n", err)
+	return code
 }
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kava/internal/database/client"
+)
+
+// fakeServer -- отвечает на запросы функцией handle, пустой ответ закрывает соединение
+type fakeServer struct {
+	address string
+
+	mutex   sync.Mutex
+	queries []string
+}
+
+func startServer(t *testing.T, handle func(query string) string) *fakeServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	server := &fakeServer{address: listener.Addr().String()}
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(connection, handle)
+		}
+	}()
+
+	return server
+}
+
+func (s *fakeServer) serve(connection net.Conn, handle func(query string) string) {
+	defer connection.Close()
+
+	reader := bufio.NewReader(connection)
+	for {
+		query, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		query = strings.TrimSuffix(query, "\n")
+		s.mutex.Lock()
+		s.queries = append(s.queries, query)
+		s.mutex.Unlock()
+
+		response := handle(query)
+		if response == "" {
+			return
+		}
+		if _, err := connection.Write([]byte(response + "\n")); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) received() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string(nil), s.queries...)
+}
+
+// storage -- ответы сервера с одним ключом foo
+func storage(query string) string {
+	switch query {
+	case "GET foo":
+		return "[ok] bar"
+	case "SET foo bar":
+		return "[ok]"
+	}
+
+	return "[error] key not exist"
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	server := startServer(t, storage)
+
+	script := filepath.Join(t.TempDir(), "queries.txt")
+	require.NoError(t, os.WriteFile(script, []byte("# comment\nSET foo bar\n\nGET foo\n"), 0600))
+
+	closed, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	closedAddress := closed.Addr().String()
+	require.NoError(t, closed.Close())
+
+	tests := map[string]struct {
+		args  []string
+		stdin string
+
+		expectedCode   int
+		expectedOutput string
+		expectedErr    string
+	}{
+		"one-shot query": {
+			args:           []string{"-address", server.address, "GET", "foo"},
+			expectedCode:   exitOK,
+			expectedOutput: "[ok] bar\n",
+		},
+		"one-shot failed query": {
+			args:           []string{"-address", server.address, "GET", "missing"},
+			expectedCode:   exitQueryError,
+			expectedOutput: "[error] key not exist\n",
+		},
+		"script file": {
+			args:           []string{"-address", server.address, "-file", script},
+			expectedCode:   exitOK,
+			expectedOutput: "[ok]\n[ok] bar\n",
+		},
+		"pipe": {
+			args:           []string{"-address", server.address},
+			stdin:          "GET foo\n# comment\nGET missing\nGET foo",
+			expectedCode:   exitQueryError,
+			expectedOutput: "[ok] bar\n[error] key not exist\n[ok] bar\n",
+		},
+		"json output": {
+			args:  []string{"-address", server.address, "-output", "json"},
+			stdin: "GET foo\nGET missing\n",
+			expectedOutput: `{"query":"GET foo","status":"ok","result":"bar"}` + "\n" +
+				`{"query":"GET missing","status":"error","error":"key not exist"}` + "\n",
+			expectedCode: exitQueryError,
+		},
+		"connection refused": {
+			args:         []string{"-address", closedAddress, "-reconnect", "0", "GET", "foo"},
+			expectedCode: exitConnectionError,
+			expectedErr:  "kava-cli: connection failed",
+		},
+		"missing script file": {
+			args:         []string{"-address", server.address, "-file", filepath.Join(t.TempDir(), "missing.txt")},
+			expectedCode: exitUsage,
+			expectedErr:  "no such file or directory",
+		},
+		"unsupported output": {
+			args:         []string{"-output", "xml", "GET", "foo"},
+			expectedCode: exitUsage,
+			expectedErr:  "unsupported output format: xml",
+		},
+		"unknown flag": {
+			args:         []string{"-unknown"},
+			expectedCode: exitUsage,
+			expectedErr:  "flag provided but not defined: -unknown",
+		},
+		"help": {
+			args:         []string{"-h"},
+			expectedCode: exitOK,
+			expectedErr:  "Usage: kava-cli",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr strings.Builder
+			code := run(test.args, strings.NewReader(test.stdin), &stdout, &stderr)
+			assert.Equal(t, test.expectedCode, code)
+			assert.Equal(t, test.expectedOutput, stdout.String())
+			if test.expectedErr == "" {
+				assert.Empty(t, stderr.String())
+			} else {
+				assert.Contains(t, stderr.String(), test.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRunReconnect(t *testing.T) {
+	t.Parallel()
+
+	// первый запрос обрывает соединение, следующие выполняются
+	dropFirst := func() func(string) string {
+		var once sync.Once
+		return func(query string) string {
+			dropped := false
+			once.Do(func() { dropped = true })
+			if dropped {
+				return ""
+			}
+			return storage(query)
+		}
+	}
+
+	tests := map[string]struct {
+		handle func(string) string
+		args   []string
+
+		expectedCode    int
+		expectedOutput  string
+		expectedErr     string
+		expectedQueries []string
+	}{
+		"read query is resent": {
+			handle:          dropFirst(),
+			args:            []string{"GET", "foo"},
+			expectedCode:    exitOK,
+			expectedOutput:  "[ok] bar\n",
+			expectedQueries: []string{"GET foo", "GET foo"},
+		},
+		"write query is not resent": {
+			handle:          dropFirst(),
+			args:            []string{"SET", "foo", "bar"},
+			expectedCode:    exitConnectionError,
+			expectedErr:     "the query is not resent",
+			expectedQueries: []string{"SET foo bar"},
+		},
+		"timeout is not a lost connection": {
+			handle: func(query string) string {
+				time.Sleep(200 * time.Millisecond)
+				return storage(query)
+			},
+			args:            []string{"-idle_timeout", "50ms", "GET", "foo"},
+			expectedCode:    exitQueryError,
+			expectedErr:     "timeout",
+			expectedQueries: []string{"GET foo"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			server := startServer(t, test.handle)
+
+			var stdout, stderr strings.Builder
+			args := append([]string{"-address", server.address}, test.args...)
+			code := run(args, strings.NewReader(""), &stdout, &stderr)
+			assert.Equal(t, test.expectedCode, code)
+			assert.Equal(t, test.expectedOutput, stdout.String())
+			if test.expectedErr != "" {
+				assert.Contains(t, stderr.String(), test.expectedErr)
+			}
+			assert.Equal(t, test.expectedQueries, server.received())
+		})
+	}
+}
+
+func TestConnectionIdleClosed(t *testing.T) {
+	t.Parallel()
+
+	// сервер закрывает соединение после ответа, как по idle timeout
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	closed := make(chan struct{}, 2)
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			if _, err := bufio.NewReader(connection).ReadString('\n'); err == nil {
+				_, _ = connection.Write([]byte("[ok]\n"))
+			}
+			_ = connection.Close()
+			closed <- struct{}{}
+		}
+	}()
+
+	conn := &connection{
+		dial: func() (*client.TCPClient, error) {
+			return client.NewTCPClient(listener.Addr().String(), 1024, time.Second)
+		},
+		reconnectAttempts: 0,
+	}
+	defer conn.close()
+
+	response, err := conn.send("SET foo bar")
+	require.NoError(t, err)
+	assert.Equal(t, "[ok]", response)
+	<-closed
+
+	// изменяющий запрос не повторяется, поэтому разрыв проверяется до отправки
+	response, err = conn.send("SET foo baz")
+	require.NoError(t, err)
+	assert.Equal(t, "[ok]", response)
+}
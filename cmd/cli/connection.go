@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"kava/internal/database/client"
+	"kava/internal/database/compute"
+)
+
+const reconnectDelay = 100 * time.Millisecond
+
+// errConnection -- соединение не удалось установить или восстановить,
+// выполнять следующие запросы бессмысленно
+var errConnection = errors.New("connection failed")
+
+// connection -- соединение с KaVa, которое открывается при первом запросе
+// и после обрыва устанавливается заново
+type connection struct {
+	dial func() (*client.TCPClient, error)
+	// reconnectAttempts -- попытки восстановить соединение, 0 - без переподключения
+	reconnectAttempts int
+
+	client *client.TCPClient
+}
+
+// send -- отправляет запрос, при обрыве соединения переподключается и повторяет
+// запрос один раз, если он только читает данные. Изменяющий запрос мог дойти
+// до сервера, поэтому он не повторяется, а обрыв возвращается как ошибка соединения.
+// Чтобы такой запрос не терялся после простоя дольше idle_timeout сервера,
+// перед ним соединение проверяется и при разрыве открывается заново
+func (c *connection) send(query string) (string, error) {
+	if c.client != nil && !resendable(query) && !c.client.Alive() {
+		c.close()
+	}
+
+	if c.client == nil {
+		if err := c.connect(1); err != nil {
+			return "", err
+		}
+	}
+
+	response, err := c.client.Send([]byte(query))
+	if err == nil {
+		return string(response), nil
+	}
+
+	// после ошибки поток ответов мог разойтись с запросами,
+	// следующий запрос пойдет в новое соединение
+	c.close()
+	if !isConnectionLost(err) {
+		return "", err
+	}
+
+	if c.reconnectAttempts == 0 {
+		return "", fmt.Errorf("%w: %w", errConnection, err)
+	}
+
+	if !resendable(query) {
+		return "", fmt.Errorf("%w: %w, the query is not resent because it may have been applied", errConnection, err)
+	}
+
+	if err := c.connect(c.reconnectAttempts); err != nil {
+		return "", err
+	}
+
+	response, err = c.client.Send([]byte(query))
+	if err != nil {
+		c.close()
+		return "", fmt.Errorf("%w: %w", errConnection, err)
+	}
+
+	return string(response), nil
+}
+
+func (c *connection) connect(attempts int) error {
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if c.client, err = c.dial(); err == nil {
+			return nil
+		}
+
+		if attempt < attempts {
+			time.Sleep(reconnectDelay * time.Duration(attempt))
+		}
+	}
+
+	return fmt.Errorf("%w: %w", errConnection, err)
+}
+
+func (c *connection) close() {
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+}
+
+// isConnectionLost -- сервер закрыл соединение, например, по idle timeout
+// или при перезапуске. Таймаут ожидания ответа обрывом не считается: сервер
+// может еще выполнять запрос
+func isConnectionLost(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED)
+}
+
+// resendable -- запрос можно повторить: команда только читает данные
+func resendable(query string) bool {
+	tokens := strings.Fields(query)
+	if len(tokens) == 0 {
+		return false
+	}
+
+	info, exist := compute.LookupCommand(tokens[0])
+	return exist && info.ReadOnly
+}
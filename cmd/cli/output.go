@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	textOutput = "text"
+	jsonOutput = "json"
+)
+
+// result -- ответ на запрос в формате -output json
+type result struct {
+	Query  string `json:"query"`
+	Status string `json:"status"`
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// newResult -- разбирает ответ сервера "[ok] value" или "[error] message",
+// err - ошибка отправки запроса
+func newResult(query, response string, err error) result {
+	if err != nil {
+		return result{Query: query, Status: "error", Error: err.Error()}
+	}
+
+	if message, failed := strings.CutPrefix(response, "[error]"); failed {
+		return result{Query: query, Status: "error", Error: strings.TrimSpace(message)}
+	}
+
+	value, _ := strings.CutPrefix(response, "[ok]")
+	return result{Query: query, Status: "ok", Result: strings.TrimPrefix(value, " ")}
+}
+
+func (r result) failed() bool {
+	return r.Status != "ok"
+}
+
+// printer -- вывод ответов, ошибки отправки в текстовом режиме пишутся в errOut
+type printer struct {
+	format string
+	out    io.Writer
+	errOut io.Writer
+}
+
+func (p *printer) print(response string, r result, err error) {
+	if p.format == jsonOutput {
+		data, _ := json.Marshal(r)
+		fmt.Fprintln(p.out, string(data))
+		return
+	}
+
+	if err != nil {
+		fmt.Fprintf(p.errOut, "kava-cli: %s\n", err)
+		return
+	}
+
+	fmt.Fprintln(p.out, response)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"kava/internal/configuration"
+)
+
+// serverFlags -- флаги командной строки, непустые значения переопределяют
+// конфигурацию из файла и переменных окружения
+type serverFlags struct {
+	configPath    string
+	dataDirectory string
+	logLevel      string
+	listen        listenAddresses
+	checkConfig   bool
+	hashPassword  bool
+	version       bool
+}
+
+func parseFlags() *serverFlags {
+	flags := &serverFlags{}
+	flag.StringVar(&flags.configPath, "config", "config.yaml", "Path to the configuration file")
+	flag.StringVar(&flags.dataDirectory, "data_directory", "", "WAL data directory, overrides wal.data_directory")
+	flag.StringVar(&flags.logLevel, "log_level", "", "Logging level, overrides logging.level")
+	flag.Var(&flags.listen, "listen",
+		"Server address [name=]host:port or [name=]unix:///path/kava.sock, overrides the address "+
+			"of the named server (the only tcp or unix server without a name), can be repeated")
+	flag.BoolVar(&flags.checkConfig, "check_config", false, "Load and validate the configuration and exit")
+	flag.BoolVar(&flags.checkConfig, "check-config", false, "Alias for -check_config")
+	flag.BoolVar(&flags.hashPassword, "hash_password", false,
+		"Read a password from stdin, print its hash for users[].password_hash and exit")
+	flag.BoolVar(&flags.version, "version", false, "Print build information and exit")
+	flag.Parse()
+
+	return flags
+}
+
+// overrides -- изменения конфигурации, заданные флагами, применяются
+// и при перезагрузке конфигурации по SIGHUP
+func (f *serverFlags) overrides() []configuration.Override {
+	var overrides []configuration.Override
+	if f.dataDirectory != "" {
+		overrides = append(overrides, configuration.WithDataDirectory(f.dataDirectory))
+	}
+
+	if f.logLevel != "" {
+		overrides = append(overrides, configuration.WithLoggingLevel(f.logLevel))
+	}
+
+	for _, listen := range f.listen {
+		overrides = append(overrides, configuration.WithListenAddress(listen.name, listen.address))
+	}
+
+	return overrides
+}
+
+type listenAddress struct {
+	name    string
+	address string
+}
+
+// listenAddresses -- значения повторяемого флага -listen
+type listenAddresses []listenAddress
+
+func (l *listenAddresses) String() string {
+	values := make([]string, 0, len(*l))
+	for _, listen := range *l {
+		if listen.name == "" {
+			values = append(values, listen.address)
+		} else {
+			values = append(values, listen.name+"="+listen.address)
+		}
+	}
+
+	return strings.Join(values, ",")
+}
+
+func (l *listenAddresses) Set(value string) error {
+	var listen listenAddress
+	if name, address, found := strings.Cut(value, "="); found {
+		listen = listenAddress{name: name, address: address}
+	} else {
+		listen = listenAddress{address: value}
+	}
+
+	if listen.address == "" {
+		return fmt.Errorf("address is empty in %q", value)
+	}
+
+	*l = append(*l, listen)
+	return nil
+}
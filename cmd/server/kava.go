@@ -1,41 +1,71 @@
 package main
 
 import (
-	"bytes"
+	"bufio"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"kava/internal/configuration"
-	"kava/internal/database"
+	"kava/internal/database/auth"
 	"kava/internal/database/compute"
-	"kava/internal/database/storage"
 	"kava/internal/database/storage/engine/in_memory"
 	initialization "kava/internal/initalization"
 	"log"
 	"os"
 	"os/signal"
-	"sync"
+	"strings"
+	"sync/atomic"
 	"syscall"
+
+	"go.uber.org/zap"
 )
 
 func main() {
+	flags := parseFlags()
+	if flags.version {
+		fmt.Println(buildInfo())
+		return
+	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
+	if flags.hashPassword {
+		hash, err := readPasswordHash(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(hash)
+		return
+	}
 
-	data, err := os.ReadFile("config.yaml")
+	overrides := flags.overrides()
+	cfg, err := loadConfig(flags.configPath, overrides)
 	if err != nil {
+		if flags.checkConfig {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 		log.Fatal(err)
 	}
 
-	reader := bytes.NewReader(data)
-	cfg, err := configuration.Load(reader)
-	if err != nil {
-		log.Fatal(err)
+	if flags.checkConfig {
+		for _, name := range cfg.UnknownEnv() {
+			fmt.Fprintf(os.Stderr, "warning: unknown environment variable %s is ignored\n", name)
+		}
+		fmt.Printf("configuration %s is valid\n", flags.configPath)
+		return
 	}
 
-	logger, err := initialization.CreateLogger(cfg.Logging)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger, level, err := initialization.CreateLoggerWithLevel(cfg.Logging)
 	if err != nil {
 		log.Fatal(err)
 	}
+	logger.Info("starting kava", zap.String("version", version), zap.String("config", flags.configPath))
+	for _, name := range cfg.UnknownEnv() {
+		logger.Warn("unknown environment variable is ignored", zap.String("name", name))
+	}
 
 	compute, err := compute.NewCompute(logger)
 	if err != nil {
@@ -46,32 +76,119 @@ func main() {
 		log.Fatal(err)
 	}
 
-	wal, err := initialization.CreateWAL(cfg.WAL, logger)
+	registry := initialization.CreateMetricsRegistry(cfg.Metrics)
+
+	wal, err := initialization.CreateWAL(cfg.WAL, registry, logger)
 	if err != nil {
 		log.Fatal("failed to initialize wal")
 	}
 
-	wal.Start(ctx)
-	
-	storage, err := storage.NewStorage(engine, wal, logger)
+	// WAL останавливается после серверов, чтобы записать запросы,
+	// которые завершаются во время остановки серверов
+	walCtx, stopWAL := context.WithCancel(context.Background())
+	defer stopWAL()
+
+	if wal != nil {
+		wal.Start(walCtx)
+	}
+
+	storage, err := initialization.CreateStorage(cfg.WAL, engine, wal, logger)
 	if err != nil {
 		log.Fatal(err)
 
 	}
-	database, err := database.NewDatabase(compute, storage, logger)
+
+	archiver, err := initialization.CreateSegmentsArchiver(cfg.WAL, logger)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if archiver != nil {
+		archiver.Start(ctx)
+	}
 
-	servers := initialization.NewServers(cfg, database, logger)
+	connections := new(atomic.Int64)
+	database, err := initialization.CreateDatabase(cfg, compute, storage, connections, registry, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	var wg sync.WaitGroup
-	for _, server := range servers {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			server.Start(ctx)
-		}()
+	options, err := initialization.ServerOptions(cfg, connections, registry)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// с политикой on_listen_error: fail процесс не запускается без всех серверов,
+	// уже запущенные серверы останавливаются так же, как по сигналу
+	servers := initialization.NewServerGroup(ctx, database, options, logger)
+	_, startErr := servers.Apply(cfg.Servers)
+	if startErr != nil {
+		logger.Error("failed to start servers", zap.Error(startErr))
+		stop()
 	}
-	wg.Wait()
+
+	metricsServer, err := initialization.CreateMetricsServer(cfg.Metrics, registry, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if metricsServer != nil {
+		servers.Start("metrics", metricsServer)
+	}
+
+	reloader, err := initialization.NewReloader(flags.configPath, cfg, level, wal, servers, logger, overrides...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	go reloader.Watch(ctx, hangup)
+
+	// Wait возвращается и без сигнала, если все серверы завершились сами,
+	// тогда остальные компоненты останавливаются так же, как по сигналу
+	waitErr := servers.Wait()
+	stop()
+	failed := waitErr != nil || startErr != nil
+
+	stopWAL()
+	if wal != nil {
+		if err := wal.Close(); err != nil {
+			logger.Error("failed to close wal", zap.Error(err))
+			failed = true
+		}
+	}
+
+	if failed {
+		logger.Warn("shutdown completed with errors")
+		_ = logger.Sync()
+		os.Exit(1)
+	}
+
+	logger.Info("shutdown completed")
+	_ = logger.Sync()
+}
+
+func loadConfig(path string, overrides []configuration.Override) (*configuration.Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return configuration.Load(file, overrides...)
+}
+
+// readPasswordHash -- хэш пароля из первой строки reader
+func readPasswordHash(reader io.Reader) (string, error) {
+	secret, err := bufio.NewReader(reader).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	secret = strings.TrimRight(secret, "\r\n")
+	if secret == "" {
+		return "", errors.New("password is empty")
+	}
+
+	return auth.HashPassword(secret)
 }
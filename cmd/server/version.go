@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Информация о сборке, задается при сборке:
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse --short HEAD) \
+//	  -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" -o kava ./cmd/server
+//
+// Без ldflags коммит и время берутся из информации о VCS, которую добавляет go build
+var (
+	version   = "dev"
+	commit    = ""
+	buildTime = ""
+)
+
+// buildInfo -- строка для -version
+func buildInfo() string {
+	revision, built := commit, buildTime
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && revision == "":
+				revision = setting.Value
+			case setting.Key == "vcs.time" && built == "":
+				built = setting.Value
+			}
+		}
+	}
+
+	if revision == "" {
+		revision = "unknown"
+	}
+	if built == "" {
+		built = "unknown"
+	}
+
+	return fmt.Sprintf("kava %s (commit %s, built %s, %s %s/%s)",
+		version, revision, built, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"kava/internal/database/compute"
+	"kava/internal/database/filesystem"
+	"kava/internal/database/storage/wal"
+)
+
+const usage = `Usage: walctl [-data_directory dir] [-archive_directory dir] <command> [flags]
+
+Commands:
+  segments   list segments with their size, logs count and LSN range
+  dump       print logs, flags: -format text|json, -from LSN, -to LSN, -key KEY;
+             logs of a corrupted segment are printed up to the broken record
+  verify     check that all segments are readable and LSNs are unique
+  truncate   cut the corrupted tail of a segment, flags: -segment NAME, -dry_run
+
+Segments moved to -archive_directory by the WAL retention are read too,
+compressed ones are decompressed.
+`
+
+var errVerificationFailed = errors.New("verification failed")
+
+func main() {
+	dataDirectory := flag.String("data_directory", "wal_data", "Directory with WAL segments")
+	archiveDirectory := flag.String("archive_directory", "", "Directory with segments archived by wal.retention")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	directories := walDirectories{data: *dataDirectory, archive: *archiveDirectory}
+	command, args := flag.Arg(0), flag.Args()[1:]
+	switch command {
+	case "segments":
+		err = listSegments(os.Stdout, directories)
+	case "dump":
+		err = dump(os.Stdout, os.Stderr, directories, args)
+	case "verify":
+		err = verify(os.Stdout, directories)
+	case "truncate":
+		err = truncate(os.Stdout, directories, args)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "walctl: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// walDirectories -- каталог сегментов и архив, куда их переносит retention
+type walDirectories struct {
+	data    string
+	archive string
+}
+
+// segmentName -- имя сегмента для вывода, сегменты архива отмечаются префиксом
+func (d walDirectories) segmentName(path string) string {
+	if d.archive != "" && filepath.Dir(path) == filepath.Clean(d.archive) {
+		return "archive/" + filepath.Base(path)
+	}
+
+	return filepath.Base(path)
+}
+
+// segmentPath -- путь сегмента по имени из вывода segments, сжатый сегмент
+// архива не обрезается: его данные проверены при сжатии
+func (d walDirectories) segmentPath(name string) (string, error) {
+	if archived, ok := strings.CutPrefix(name, "archive/"); ok {
+		if d.archive == "" {
+			return "", errors.New("archive directory is not set")
+		}
+		if strings.HasSuffix(archived, filesystem.CompressedSuffix) {
+			return "", fmt.Errorf("%s: compressed segment can't be truncated", name)
+		}
+		return filepath.Join(d.archive, archived), nil
+	}
+
+	return filepath.Join(d.data, name), nil
+}
+
+type segmentInfo struct {
+	name        string
+	size        int
+	validLength int
+	logs        []wal.Log
+	err         error
+}
+
+// readSegments -- сегменты архива и каталога в порядке имен, как их читает
+// восстановление, size сжатого сегмента - размер распакованных данных
+func readSegments(directories walDirectories) ([]segmentInfo, error) {
+	var options []filesystem.SegmentsDirectoryOption
+	if directories.archive != "" {
+		options = append(options, filesystem.WithArchiveDirectory(directories.archive))
+	}
+
+	var segments []segmentInfo
+	err := filesystem.NewSegmentsDirectory(directories.data, options...).Walk(func(path string, data []byte) error {
+		logs, validLength, err := wal.ReadSegment(data)
+		segments = append(segments, segmentInfo{
+			name:        directories.segmentName(path),
+			size:        len(data),
+			validLength: validLength,
+			logs:        logs,
+			err:         err,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return segments, nil
+}
+
+func listSegments(out io.Writer, directories walDirectories) error {
+	segments, err := readSegments(directories)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%-24s %10s %8s %10s %10s  %s\n", "SEGMENT", "SIZE", "LOGS", "FIRST LSN", "LAST LSN", "STATUS")
+	for _, segment := range segments {
+		var firstLSN, lastLSN int64
+		if len(segment.logs) != 0 {
+			firstLSN = segment.logs[0].LSN
+			lastLSN = segment.logs[len(segment.logs)-1].LSN
+		}
+
+		status := "ok"
+		if segment.err != nil {
+			status = fmt.Sprintf("corrupted at offset %d", segment.validLength)
+		}
+
+		fmt.Fprintf(out, "%-24s %10d %8d %10d %10d  %s\n", segment.name, segment.size, len(segment.logs), firstLSN, lastLSN, status)
+	}
+
+	return nil
+}
+
+type logRecord struct {
+	LSN       int64      `json:"lsn"`
+	Time      *time.Time `json:"time,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+	Command   string     `json:"command"`
+	Key       string     `json:"key,omitempty"`
+	Value     string     `json:"value,omitempty"`
+	Arguments []string   `json:"arguments,omitempty"`
+}
+
+func newLogRecord(log wal.Log) logRecord {
+	record := logRecord{
+		LSN:       log.LSN,
+		RequestID: log.RequestID,
+		Command:   compute.CommandName(log.CommandID),
+	}
+
+	if log.Timestamp != 0 {
+		timestamp := time.Unix(0, log.Timestamp).UTC()
+		record.Time = &timestamp
+	}
+
+	switch len(log.Arguments) {
+	case 0:
+	case 1:
+		record.Key = log.Arguments[0]
+	case 2:
+		record.Key, record.Value = log.Arguments[0], log.Arguments[1]
+	default:
+		record.Arguments = log.Arguments
+	}
+
+	return record
+}
+
+// dump -- печатает логи в порядке LSN, из поврежденного сегмента печатаются
+// логи до сломанной записи, место поломки сообщается в errOut
+func dump(out, errOut io.Writer, directories walDirectories, args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	format := flags.String("format", "text", "Output format: text or json")
+	fromLSN := flags.Int64("from", 0, "Minimal LSN of printed logs")
+	toLSN := flags.Int64("to", 0, "Maximal LSN of printed logs, 0 means no limit")
+	key := flags.String("key", "", "Print only logs for the key")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format: %s", *format)
+	}
+
+	segments, err := readSegments(directories)
+	if err != nil {
+		return err
+	}
+
+	var logs []wal.Log
+	for _, segment := range segments {
+		if segment.err != nil {
+			fmt.Fprintf(errOut, "%s: corrupted at offset %d of %d, %d logs before it: %s\n",
+				segment.name, segment.validLength, segment.size, len(segment.logs), segment.err)
+		}
+
+		logs = append(logs, segment.logs...)
+	}
+
+	// LSN выдаются до записи в WAL, конкурентные записи
+	// могут попасть в сегмент не по порядку
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].LSN < logs[j].LSN
+	})
+
+	encoder := json.NewEncoder(out)
+	for _, log := range logs {
+		if log.LSN < *fromLSN || (*toLSN != 0 && log.LSN > *toLSN) {
+			continue
+		}
+
+		record := newLogRecord(log)
+		if *key != "" && record.Key != *key {
+			continue
+		}
+
+		if *format == "json" {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+			continue
+		}
+
+		timestamp := "-"
+		if record.Time != nil {
+			timestamp = record.Time.Format(time.RFC3339Nano)
+		}
+
+		fmt.Fprintf(out, "%d\t%s\t%s", record.LSN, timestamp, record.Command)
+		for _, argument := range log.Arguments {
+			fmt.Fprintf(out, "\t%s", argument)
+		}
+		fmt.Fprintln(out)
+	}
+
+	return nil
+}
+
+// verify -- проверяет, что сегменты читаются полностью и LSN не повторяются.
+// Порядок LSN не проверяется: LSN выдаются до записи в WAL
+func verify(out io.Writer, directories walDirectories) error {
+	segments, err := readSegments(directories)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	var logsCount int
+	var lastLSN int64
+	owners := make(map[int64]string)
+	for _, segment := range segments {
+		if segment.err != nil {
+			failed = true
+			fmt.Fprintf(out, "%s: corrupted at offset %d of %d: %s\n", segment.name, segment.validLength, segment.size, segment.err)
+		}
+
+		for _, log := range segment.logs {
+			if owner, found := owners[log.LSN]; found {
+				failed = true
+				fmt.Fprintf(out, "%s: duplicate LSN %d, already written in %s\n", segment.name, log.LSN, owner)
+			} else {
+				owners[log.LSN] = segment.name
+			}
+
+			logsCount++
+			lastLSN = max(lastLSN, log.LSN)
+		}
+	}
+
+	if failed {
+		return errVerificationFailed
+	}
+
+	fmt.Fprintf(out, "%d segments verified, %d logs, last LSN %d\n", len(segments), logsCount, lastLSN)
+	return nil
+}
+
+// truncate -- обрезает хвост сегмента каталога или несжатого сегмента архива,
+// по умолчанию последнего сегмента каталога, куда дописывает WAL
+func truncate(out io.Writer, directories walDirectories, args []string) error {
+	flags := flag.NewFlagSet("truncate", flag.ContinueOnError)
+	segmentName := flags.String("segment", "", "Segment to truncate, the last segment by default")
+	dryRun := flags.Bool("dry_run", false, "Only report what would be truncated")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *segmentName == "" {
+		var err error
+		if *segmentName, err = filesystem.SegmentLast(directories.data); err != nil {
+			return err
+		} else if *segmentName == "" {
+			return errors.New("no segments found")
+		}
+	}
+
+	filename, err := directories.segmentPath(*segmentName)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	validLength := wal.ValidLength(data)
+	if validLength == len(data) {
+		fmt.Fprintf(out, "%s: nothing to truncate\n", *segmentName)
+		return nil
+	}
+
+	fmt.Fprintf(out, "%s: truncating %d bytes, %d bytes remain\n", *segmentName, len(data)-validLength, validLength)
+	if *dryRun {
+		return nil
+	}
+
+	return os.Truncate(filename, int64(validLength))
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kava/internal/database/compute"
+	"kava/internal/database/filesystem"
+	"kava/internal/database/storage/wal"
+)
+
+// segment -- сегмент из логов с указанными LSN, tail дописывается после них
+type segment struct {
+	name string
+	lsns []int64
+	tail []byte
+}
+
+func setLog(lsn int64) wal.Log {
+	return wal.Log{LSN: lsn, CommandID: compute.SetCommandID, Arguments: []string{"key", "value"}}
+}
+
+func createSegments(t *testing.T, segments ...segment) string {
+	t.Helper()
+
+	directory := t.TempDir()
+	for _, s := range segments {
+		var buffer bytes.Buffer
+		for _, lsn := range s.lsns {
+			log := setLog(lsn)
+			require.NoError(t, log.Encode(&buffer))
+		}
+		buffer.Write(s.tail)
+
+		require.NoError(t, os.WriteFile(filepath.Join(directory, s.name), buffer.Bytes(), 0600))
+	}
+
+	return directory
+}
+
+// tornLog -- начало лога, запись которого оборвалась
+func tornLog(t *testing.T) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	log := setLog(100)
+	require.NoError(t, log.Encode(&buffer))
+	return buffer.Bytes()[:buffer.Len()/2]
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		segments []segment
+
+		expectedOutput []string
+		expectedErr    error
+	}{
+		"ordered segments": {
+			segments: []segment{
+				{name: "wal_1000.log", lsns: []int64{1, 2}},
+				{name: "wal_2000.log", lsns: []int64{3}},
+			},
+			expectedOutput: []string{"2 segments verified, 3 logs, last LSN 3"},
+		},
+		"out of order LSNs": {
+			segments: []segment{
+				{name: "wal_1000.log", lsns: []int64{2, 1}},
+				{name: "wal_2000.log", lsns: []int64{4, 3}},
+			},
+			expectedOutput: []string{"2 segments verified, 4 logs, last LSN 4"},
+		},
+		"duplicate LSN in segment": {
+			segments: []segment{
+				{name: "wal_1000.log", lsns: []int64{1, 2, 1}},
+			},
+			expectedOutput: []string{"wal_1000.log: duplicate LSN 1, already written in wal_1000.log"},
+			expectedErr:    errVerificationFailed,
+		},
+		"duplicate LSN across segments": {
+			segments: []segment{
+				{name: "wal_1000.log", lsns: []int64{1, 2}},
+				{name: "wal_2000.log", lsns: []int64{2, 3}},
+			},
+			expectedOutput: []string{"wal_2000.log: duplicate LSN 2, already written in wal_1000.log"},
+			expectedErr:    errVerificationFailed,
+		},
+		"corrupted segment": {
+			segments: []segment{
+				{name: "wal_1000.log", lsns: []int64{1}, tail: []byte{0x01, 0x02, 0x03}},
+				{name: "wal_2000.log", lsns: []int64{2}},
+			},
+			expectedOutput: []string{"wal_1000.log: corrupted at offset"},
+			expectedErr:    errVerificationFailed,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+			err := verify(&out, walDirectories{data: createSegments(t, test.segments...)})
+			assert.ErrorIs(t, err, test.expectedErr)
+			for _, expected := range test.expectedOutput {
+				assert.Contains(t, out.String(), expected)
+			}
+		})
+	}
+}
+
+func TestDump(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		segments func(t *testing.T) []segment
+		args     []string
+
+		expectedOutput    string
+		expectedErrOutput string
+		expectedErr       string
+	}{
+		"out of order LSNs": {
+			segments: func(*testing.T) []segment {
+				return []segment{
+					{name: "wal_1000.log", lsns: []int64{2, 1}},
+					{name: "wal_2000.log", lsns: []int64{3}},
+				}
+			},
+			expectedOutput: "1\t-\tSET\tkey\tvalue\n" +
+				"2\t-\tSET\tkey\tvalue\n" +
+				"3\t-\tSET\tkey\tvalue\n",
+		},
+		"corrupted segment": {
+			segments: func(t *testing.T) []segment {
+				return []segment{
+					{name: "wal_1000.log", lsns: []int64{1}, tail: []byte{0x01, 0x02, 0x03}},
+					{name: "wal_2000.log", lsns: []int64{2}},
+				}
+			},
+			expectedOutput: "1\t-\tSET\tkey\tvalue\n" +
+				"2\t-\tSET\tkey\tvalue\n",
+			expectedErrOutput: "wal_1000.log: corrupted at offset",
+		},
+		"torn tail of the last segment": {
+			segments: func(t *testing.T) []segment {
+				return []segment{
+					{name: "wal_1000.log", lsns: []int64{1}},
+					{name: "wal_2000.log", lsns: []int64{2}, tail: tornLog(t)},
+				}
+			},
+			args:              []string{"-format", "json", "-from", "2"},
+			expectedOutput:    `{"lsn":2,"command":"SET","key":"key","value":"value"}` + "\n",
+			expectedErrOutput: "wal_2000.log: corrupted at offset",
+		},
+		"unknown format": {
+			segments: func(*testing.T) []segment {
+				return nil
+			},
+			args:        []string{"-format", "xml"},
+			expectedErr: "unknown format: xml",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var out, errOut bytes.Buffer
+			err := dump(&out, &errOut, walDirectories{data: createSegments(t, test.segments(t)...)}, test.args)
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedOutput, out.String())
+			if test.expectedErrOutput == "" {
+				assert.Empty(t, errOut.String())
+			} else {
+				assert.Contains(t, errOut.String(), test.expectedErrOutput)
+			}
+		})
+	}
+}
+
+// compressSegments -- сжимает сегменты каталога, как архиватор retention
+func compressSegments(t *testing.T, directory string) {
+	t.Helper()
+
+	files, err := os.ReadDir(directory)
+	require.NoError(t, err)
+	for _, file := range files {
+		filename := filepath.Join(directory, file.Name())
+		data, err := os.ReadFile(filename)
+		require.NoError(t, err)
+
+		var buffer bytes.Buffer
+		writer := gzip.NewWriter(&buffer)
+		_, err = writer.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		require.NoError(t, os.WriteFile(filename+filesystem.CompressedSuffix, buffer.Bytes(), 0600))
+		require.NoError(t, os.Remove(filename))
+	}
+}
+
+func TestArchivedSegments(t *testing.T) {
+	t.Parallel()
+
+	archive := createSegments(t,
+		segment{name: "wal_1000.log", lsns: []int64{1}},
+		segment{name: "wal_2000.log", lsns: []int64{2}, tail: []byte{0x01, 0x02, 0x03}},
+	)
+	compressSegments(t, archive)
+	directories := walDirectories{
+		data:    createSegments(t, segment{name: "wal_3000.log", lsns: []int64{3}}),
+		archive: archive,
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, listSegments(&out, directories))
+	assert.Contains(t, out.String(), "archive/wal_1000.log.gz")
+	assert.Contains(t, out.String(), "wal_3000.log")
+
+	// поврежденный архив не должен проходить проверку
+	out.Reset()
+	err := verify(&out, directories)
+	assert.ErrorIs(t, err, errVerificationFailed)
+	assert.Contains(t, out.String(), "archive/wal_2000.log.gz: corrupted at offset")
+
+	out.Reset()
+	var errOut bytes.Buffer
+	require.NoError(t, dump(&out, &errOut, directories, nil))
+	assert.Equal(t, "1\t-\tSET\tkey\tvalue\n"+
+		"2\t-\tSET\tkey\tvalue\n"+
+		"3\t-\tSET\tkey\tvalue\n", out.String())
+
+	err = truncate(&out, directories, []string{"-segment", "archive/wal_2000.log.gz"})
+	assert.ErrorContains(t, err, "compressed segment can't be truncated")
+}
@@ -1,6 +1,10 @@
 package common
 
-import "context"
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
 
 type TxID string
 
@@ -11,3 +15,62 @@ func ContextWithTxID(parent context.Context, value int64) context.Context {
 func GetTxIDFromContext(ctx context.Context) int64 {
 	return ctx.Value(TxID("tx")).(int64)
 }
+
+// clientAddressKey -- ключ адреса клиента, недоступный другим пакетам
+type clientAddressKey struct{}
+
+// ContextWithClientAddress -- адрес клиента, от которого пришел запрос
+func ContextWithClientAddress(parent context.Context, address string) context.Context {
+	return context.WithValue(parent, clientAddressKey{}, address)
+}
+
+// GetClientAddressFromContext -- пустая строка, если адрес не задан
+func GetClientAddressFromContext(ctx context.Context) string {
+	address, _ := ctx.Value(clientAddressKey{}).(string)
+	return address
+}
+
+// connectionIDKey -- ключ идентификатора соединения, недоступный другим пакетам
+type connectionIDKey struct{}
+
+// ContextWithConnectionID -- идентификатор соединения, в котором пришел запрос
+func ContextWithConnectionID(parent context.Context, connectionID string) context.Context {
+	return context.WithValue(parent, connectionIDKey{}, connectionID)
+}
+
+// GetConnectionIDFromContext -- пустая строка, если идентификатор не задан
+func GetConnectionIDFromContext(ctx context.Context) string {
+	connectionID, _ := ctx.Value(connectionIDKey{}).(string)
+	return connectionID
+}
+
+// requestIDKey -- ключ идентификатора запроса, недоступный другим пакетам
+type requestIDKey struct{}
+
+// ContextWithRequestID -- идентификатор запроса, создается серверным слоем
+func ContextWithRequestID(parent context.Context, requestID string) context.Context {
+	return context.WithValue(parent, requestIDKey{}, requestID)
+}
+
+// GetRequestIDFromContext -- пустая строка, если идентификатор не задан
+func GetRequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// LogFields -- поля лога с идентификаторами запроса из контекста,
+// отсутствующие идентификаторы пропускаются
+func LogFields(ctx context.Context, fields ...zap.Field) []zap.Field {
+	result := make([]zap.Field, 0, len(fields)+3)
+	if requestID := GetRequestIDFromContext(ctx); requestID != "" {
+		result = append(result, zap.String("request_id", requestID))
+	}
+	if connectionID := GetConnectionIDFromContext(ctx); connectionID != "" {
+		result = append(result, zap.String("connection_id", connectionID))
+	}
+	if txID, ok := ctx.Value(TxID("tx")).(int64); ok {
+		result = append(result, zap.Int64("tx_id", txID))
+	}
+
+	return append(result, fields...)
+}
@@ -3,43 +3,38 @@ package configuration
 import (
 	"fmt"
 	"io"
+	"os"
 	"time"
 
-	"code.cloudfoundry.org/bytefmt"
-
 	"gopkg.in/yaml.v3"
 )
 
-const (
-	defaultMaxConnections = 100
-	defaultMaxMessageSize = 4 * bytefmt.KILOBYTE
-	defaultIdleTimeout    = 60 * time.Second
-	defaultHost           = "0.0.0.0"
-	defaultPort           = 8080
-
-	// Default logging values
-	defaultLogLevel  = "info"
-	defaultLogOutput = "stdout"
-
-	// Default engine type
-	defaultEngineType = "in_memory"
-)
-
 // Supported values constants
 var (
-	supportedLogLevels   = []string{"debug", "info", "warn", "error", "fatal"}
-	supportedEngineTypes = []string{"in_memory", "persistent"}
+	supportedLogLevels       = []string{"debug", "info", "warn", "error", "fatal"}
+	supportedEngineTypes     = []string{"in_memory"}
+	supportedServerModes     = []string{"goroutine", "netpoll"}
+	supportedCompressions    = []string{"none", "flate", "gzip"}
+	supportedTracingExporter = []string{"log"}
+	supportedListenPolicies  = []string{ListenErrorFail, ListenErrorSkip}
 )
 
 // ByteSize - custom тип для срабатывания UnMarshal
 type ByteSize int
 
+// FileMode - права на файл, в конфигурации задаются восьмеричной строкой ("0660")
+type FileMode os.FileMode
+
 // ServerConfig - базовый интерфейс для всех конфигураций серверов
 type ServerConfig interface {
 	// getType возвращает тип сервера
 	getType() string
 	// getName возвращает имя сервера
 	getName() string
+	// setDefaults заполняет незаданные поля значениями по умолчанию
+	setDefaults()
+	// validate возвращает все ошибки конфигурации, path - путь сервера в конфигурации
+	validate(path string) []error
 }
 
 // ServerConfigs - slice для хранения конфигураций серверов
@@ -51,6 +46,41 @@ type Config struct {
 	WAL     *WALConfig     `yaml:"wal"`
 	Servers ServerConfigs  `yaml:"servers"`
 	Logging *LoggingConfig `yaml:"logging"`
+	Users   []UserConfig   `yaml:"users"`
+	Admin   *AdminConfig   `yaml:"admin"`
+	Metrics *MetricsConfig `yaml:"metrics"`
+	SlowLog *SlowLogConfig `yaml:"slowlog"`
+	Tracing *TracingConfig `yaml:"tracing"`
+
+	// unknownEnv -- переменные KAVA_*, не подошедшие ни к одному полю
+	unknownEnv []string
+}
+
+// TracingConfig -- трассировка запросов, exporter log пишет спаны в лог
+// на уровне debug, без раздела спаны не создаются
+type TracingConfig struct {
+	Exporter string `yaml:"exporter"`
+}
+
+// SlowLogConfig -- журнал запросов, выполнявшихся дольше threshold, хранит
+// не больше max_length последних записей, log дублирует записи в лог
+type SlowLogConfig struct {
+	Threshold time.Duration `yaml:"threshold"`
+	MaxLength int           `yaml:"max_length"`
+	Log       bool          `yaml:"log"`
+}
+
+// AdminConfig -- раздел административных команд (INFO, DBSIZE, FLUSHALL),
+// без раздела команды отключены
+type AdminConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// MetricsConfig -- HTTP сервер метрик в формате Prometheus,
+// без раздела метрики не собираются
+type MetricsConfig struct {
+	Address string `yaml:"address"`
+	Path    string `yaml:"path"`
 }
 
 // EngineConfig -- раздел движка
@@ -70,6 +100,15 @@ type BaseServer struct {
 	Name string `yaml:"name"`
 }
 
+// Политики on_listen_error: что делать, если сервер не может открыть сокет
+const (
+	// ListenErrorFail -- процесс не запускается, при перезагрузке ошибка пишется в лог
+	ListenErrorFail = "fail"
+	// ListenErrorSkip -- сервер пропускается с предупреждением, при следующей
+	// перезагрузке конфигурации он запускается снова
+	ListenErrorSkip = "skip"
+)
+
 // TCPServerConfig - конфигурация TCP сервера
 type TCPServerConfig struct {
 	BaseServer     `yaml:",inline"`
@@ -78,6 +117,55 @@ type TCPServerConfig struct {
 	MaxConnections int           `yaml:"max_connections"`
 	MaxMessageSize ByteSize      `yaml:"max_message_size"`
 	IdleTimeout    time.Duration `yaml:"idle_timeout"`
+	DrainTimeout   time.Duration `yaml:"drain_timeout"`
+	QueryTimeout   time.Duration `yaml:"query_timeout"`
+	// MaxConnectionsWait -- сколько соединение ждет свободного слота
+	// при достижении max_connections, 0 - отказ сразу
+	MaxConnectionsWait time.Duration `yaml:"max_connections_wait"`
+	// Mode -- обработка соединений: goroutine (по умолчанию) - горутина на соединение,
+	// netpoll - чтение через epoll (только linux) и выполнение запросов в пуле из workers горутин
+	Mode    string `yaml:"mode"`
+	Workers int    `yaml:"workers"`
+	// OnListenError -- политика при ошибке открытия сокета, по умолчанию fail
+	OnListenError string `yaml:"on_listen_error"`
+
+	TLS *TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig -- настройки TLS сервера, с ca_file проверяются
+// сертификаты клиентов, require_client_cert делает их обязательными
+type TLSConfig struct {
+	CertFile          string `yaml:"cert_file"`
+	KeyFile           string `yaml:"key_file"`
+	CAFile            string `yaml:"ca_file"`
+	RequireClientCert bool   `yaml:"require_client_cert"`
+}
+
+// UserConfig -- пользователь TCP сервера, password_hash - SHA-256 пароля в hex.
+// В commands и keys "*" разрешает все, шаблон ключа с "*" на конце задает префикс
+type UserConfig struct {
+	Name         string   `yaml:"name"`
+	PasswordHash string   `yaml:"password_hash"`
+	Commands     []string `yaml:"commands"`
+	Keys         []string `yaml:"keys"`
+}
+
+// UnixServerConfig - конфигурация сервера на unix сокете, соединения
+// обрабатываются так же, как в TCP сервере
+type UnixServerConfig struct {
+	BaseServer     `yaml:",inline"`
+	Path           string        `yaml:"path"`
+	Mode           FileMode      `yaml:"mode"`
+	MaxConnections int           `yaml:"max_connections"`
+	MaxMessageSize ByteSize      `yaml:"max_message_size"`
+	IdleTimeout    time.Duration `yaml:"idle_timeout"`
+	DrainTimeout   time.Duration `yaml:"drain_timeout"`
+	QueryTimeout   time.Duration `yaml:"query_timeout"`
+	// MaxConnectionsWait -- сколько соединение ждет свободного слота
+	// при достижении max_connections, 0 - отказ сразу
+	MaxConnectionsWait time.Duration `yaml:"max_connections_wait"`
+	// OnListenError -- политика при ошибке открытия сокета, по умолчанию fail
+	OnListenError string `yaml:"on_listen_error"`
 }
 
 type WALConfig struct {
@@ -85,10 +173,37 @@ type WALConfig struct {
 	FlushingBatchTimeout time.Duration `yaml:"flushing_batch_timeout"`
 	MaxSegmentSize       ByteSize      `yaml:"max_segment_size"`
 	DataDirectory        string        `yaml:"data_directory"`
+	Compression          string        `yaml:"compression"`
+
+	Retention *WALRetentionConfig `yaml:"retention"`
+	Recovery  *WALRecoveryConfig  `yaml:"recovery"`
 }
 
-// Load -- загружает информацию из файла
-func Load(r io.Reader) (*Config, error) {
+// WALRetentionConfig -- политика хранения сегментов WAL, вышедшие из
+// политики сегменты переносятся в архив и восстанавливаются оттуда
+type WALRetentionConfig struct {
+	MaxTotalSize     ByteSize      `yaml:"max_total_size"`
+	MaxAge           time.Duration `yaml:"max_age"`
+	KeepSegments     int           `yaml:"keep_segments"`
+	ArchiveDirectory string        `yaml:"archive_directory"`
+	Compress         bool          `yaml:"compress"`
+	CheckInterval    time.Duration `yaml:"check_interval"`
+}
+
+// WALRecoveryConfig -- восстановление на момент времени: WAL применяется
+// до target_lsn или target_time, узел запускается только на чтение
+type WALRecoveryConfig struct {
+	TargetLSN  int64     `yaml:"target_lsn"`
+	TargetTime time.Time `yaml:"target_time"`
+}
+
+// Load -- загружает информацию из файла, переопределяет ее переменными
+// окружения KAVA_* и overrides, заполняет значения по умолчанию и проверяет результат
+func Load(r io.Reader, overrides ...Override) (*Config, error) {
+	return load(r, os.Environ(), overrides...)
+}
+
+func load(r io.Reader, environ []string, overrides ...Override) (*Config, error) {
 	data, err := io.ReadAll(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read configuration: %w", err)
@@ -99,5 +214,20 @@ func Load(r io.Reader) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse configuration: %w", err)
 	}
 
+	if err := config.ApplyEnv(environ); err != nil {
+		return nil, fmt.Errorf("failed to apply environment variables: %w", err)
+	}
+
+	for _, override := range overrides {
+		if err := override(&config); err != nil {
+			return nil, fmt.Errorf("failed to override configuration: %w", err)
+		}
+	}
+
+	config.SetDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
 	return &config, nil
 }
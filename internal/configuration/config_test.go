@@ -1,11 +1,13 @@
 package configuration
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const testCfgData = `engine:
@@ -19,22 +21,76 @@ servers:
     max_connections: 1
     max_message_size: "2KB"
     idle_timeout: 5m
+    drain_timeout: 10s
+    query_timeout: 3s
+    max_connections_wait: 1s
+    mode: goroutine
+    workers: 4
+    on_listen_error: skip
+    tls:
+      cert_file: "server.crt"
+      key_file: "server.key"
+      ca_file: "ca.crt"
+      require_client_cert: true
 
   - type: console
     name:  console-service
+    history_file: ".kava_history"
 
   - type: tcp
     name: hello-world
 
+  - type: unix
+    name: sidecar
+    path: /run/kava/kava.sock
+    mode: "0660"
+    query_timeout: 2s
+
 logging:
   level: "info"
   output: "output.log"
 
+users:
+  - name: admin
+    password_hash: "pbkdf2_sha256$10000$a86a01ff995df72d6ee284c902d4e611$ca6e1dc5400fecc2994d4606335f2fda330b2eb720d5cec8e0daaf0cf3837632"
+    commands: ["*"]
+    keys: ["*"]
+  - name: reader
+    password_hash: "pbkdf2_sha256$10000$528bc7c0bb5c51ee08ddee156ec4f832$9cbf699aa40d38b81ffe360c2506ea071af468d14247d8160252e8502a69802a"
+    commands: ["GET"]
+    keys: ["user:*"]
+
+admin:
+  enabled: true
+
+metrics:
+  address: "localhost:9180"
+  path: "/metrics"
+
+slowlog:
+  threshold: 10ms
+  max_length: 64
+  log: true
+
+tracing:
+  exporter: log
+
 wal:
   flushing_batch_length: 101
   flushing_batch_timeout: "7s"
   max_segment_size: "3KB"
   data_directory: "wal_dataz"
+  compression: "flate"
+  retention:
+    max_total_size: "1MB"
+    max_age: 24h
+    keep_segments: 3
+    archive_directory: "wal_archive"
+    compress: true
+    check_interval: 30s
+  recovery:
+    target_lsn: 42
+    target_time: 2025-03-01T14:03:00Z
 `
 
 func TestLoad(t *testing.T) {
@@ -45,7 +101,12 @@ func TestLoad(t *testing.T) {
 
 		expectedCfg Config
 	}{
-		"load empty config": {cfgData: ""},
+		"load empty config": {cfgData: "",
+			expectedCfg: Config{
+				Engine:  &EngineConfig{Type: "in_memory"},
+				Logging: &LoggingConfig{Level: "info", Output: "kava.log"},
+			},
+		},
 		"load config": {cfgData: testCfgData,
 			expectedCfg: Config{
 				Engine: &EngineConfig{
@@ -57,17 +118,31 @@ func TestLoad(t *testing.T) {
 							Type: "tcp",
 							Name: "main",
 						},
-						Port:           8087,
-						Host:           "localhost",
-						MaxConnections: 1,
-						MaxMessageSize: 2048,
-						IdleTimeout:    5 * time.Minute,
+						Port:               8087,
+						Host:               "localhost",
+						MaxConnections:     1,
+						MaxMessageSize:     2048,
+						IdleTimeout:        5 * time.Minute,
+						DrainTimeout:       10 * time.Second,
+						QueryTimeout:       3 * time.Second,
+						MaxConnectionsWait: time.Second,
+						Mode:               "goroutine",
+						Workers:            4,
+						OnListenError:      "skip",
+						TLS: &TLSConfig{
+							CertFile:          "server.crt",
+							KeyFile:           "server.key",
+							CAFile:            "ca.crt",
+							RequireClientCert: true,
+						},
 					},
 					&ConsoleConfig{
 						BaseServer: BaseServer{
 							Type: "console",
 							Name: "console-service",
 						},
+						HistoryFile: ".kava_history",
+						HistorySize: 1000,
 					},
 					&TCPServerConfig{
 						BaseServer: BaseServer{
@@ -79,14 +154,61 @@ func TestLoad(t *testing.T) {
 						MaxConnections: 100,
 						MaxMessageSize: 4096,
 						IdleTimeout:    1 * time.Minute,
+						DrainTimeout:   5 * time.Second,
+						OnListenError:  "fail",
+					},
+					&UnixServerConfig{
+						BaseServer: BaseServer{
+							Type: "unix",
+							Name: "sidecar",
+						},
+						Path:           "/run/kava/kava.sock",
+						Mode:           0660,
+						MaxConnections: 100,
+						MaxMessageSize: 4096,
+						IdleTimeout:    1 * time.Minute,
+						DrainTimeout:   5 * time.Second,
+						QueryTimeout:   2 * time.Second,
+						OnListenError:  "fail",
 					},
 				},
 				Logging: &LoggingConfig{Level: "info", Output: "output.log"},
+				Users: []UserConfig{
+					{
+						Name:         "admin",
+						PasswordHash: "pbkdf2_sha256$10000$a86a01ff995df72d6ee284c902d4e611$ca6e1dc5400fecc2994d4606335f2fda330b2eb720d5cec8e0daaf0cf3837632",
+						Commands:     []string{"*"},
+						Keys:         []string{"*"},
+					},
+					{
+						Name:         "reader",
+						PasswordHash: "pbkdf2_sha256$10000$528bc7c0bb5c51ee08ddee156ec4f832$9cbf699aa40d38b81ffe360c2506ea071af468d14247d8160252e8502a69802a",
+						Commands:     []string{"GET"},
+						Keys:         []string{"user:*"},
+					},
+				},
+				Admin:   &AdminConfig{Enabled: true},
+				Metrics: &MetricsConfig{Address: "localhost:9180", Path: "/metrics"},
+				SlowLog: &SlowLogConfig{Threshold: 10 * time.Millisecond, MaxLength: 64, Log: true},
+				Tracing: &TracingConfig{Exporter: "log"},
 				WAL: &WALConfig{
 					FlushingBatchLength:  101,
 					FlushingBatchTimeout: 7 * time.Second,
 					MaxSegmentSize:       3072,
 					DataDirectory:        "wal_dataz",
+					Compression:          "flate",
+					Retention: &WALRetentionConfig{
+						MaxTotalSize:     1 << 20,
+						MaxAge:           24 * time.Hour,
+						KeepSegments:     3,
+						ArchiveDirectory: "wal_archive",
+						Compress:         true,
+						CheckInterval:    30 * time.Second,
+					},
+					Recovery: &WALRecoveryConfig{
+						TargetLSN:  42,
+						TargetTime: time.Date(2025, time.March, 1, 14, 3, 0, 0, time.UTC),
+					},
 				},
 			},
 		},
@@ -97,9 +219,281 @@ func TestLoad(t *testing.T) {
 			t.Parallel()
 
 			reader := strings.NewReader(test.cfgData)
-			cfg, err := Load(reader)
+			cfg, err := load(reader, nil)
 			assert.NoError(t, err)
 			assert.Equal(t, test.expectedCfg, *cfg)
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestLoadInvalidUnixServer(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"without path": `servers:
+  - type: unix
+    name: sidecar
+`,
+		"invalid mode": `servers:
+  - type: unix
+    name: sidecar
+    path: kava.sock
+    mode: "rw-rw----"
+`,
+	}
+
+	for name, cfgData := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := Load(strings.NewReader(cfgData))
+			assert.Error(t, err)
+			assert.Nil(t, cfg)
+		})
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := load(strings.NewReader(`wal:
+  data_directory: "wal_data"
+  retention:
+    keep_segments: 2
+metrics: {}
+slowlog: {}
+tracing: {}
+`), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, &WALConfig{
+		FlushingBatchLength:  100,
+		FlushingBatchTimeout: 10 * time.Millisecond,
+		MaxSegmentSize:       10 << 20,
+		DataDirectory:        "wal_data",
+		Retention: &WALRetentionConfig{
+			KeepSegments:     2,
+			ArchiveDirectory: filepath.Join("wal_data", "archive"),
+			CheckInterval:    time.Minute,
+		},
+	}, cfg.WAL)
+	assert.Equal(t, &MetricsConfig{Address: "localhost:9180", Path: "/metrics"}, cfg.Metrics)
+	assert.Equal(t, &SlowLogConfig{MaxLength: 128}, cfg.SlowLog)
+	assert.Equal(t, &TracingConfig{Exporter: "log"}, cfg.Tracing)
+	assert.Nil(t, cfg.Admin)
+}
+
+func TestLoadInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := load(strings.NewReader(`engine:
+  type: "persistent"
+logging:
+  level: "trace"
+servers:
+  - type: tcp
+    name: main
+    port: 70000
+    mode: epoll
+    on_listen_error: retry
+  - type: tcp
+    name: main
+    max_connections: -1
+    tls:
+      key_file: server.key
+users:
+  - name: admin
+    password_hash: "secret"
+wal:
+  flushing_batch_length: -5
+  compression: "zstd"
+metrics:
+  path: "metrics"
+slowlog:
+  threshold: -1s
+tracing:
+  exporter: "jaeger"
+`), nil)
+	require.Error(t, err)
+
+	// ошибки перечисляются все сразу, а не до первой
+	for _, expected := range []string{
+		`engine.type: unsupported value "persistent"`,
+		`logging.level: unsupported value "trace", supported: debug, info, warn, error, fatal`,
+		`servers[0].port: must be in range 0-65535, got 70000`,
+		`servers[0].mode: unsupported value "epoll"`,
+		`servers[0].on_listen_error: unsupported value "retry", supported: fail, skip`,
+		`servers[1].name: "main" is already used by servers[0]`,
+		`servers[1].max_connections: must be positive, got -1`,
+		`servers[1].tls.cert_file: must be set`,
+		`users[0].password_hash: password hash is invalid`,
+		`wal.flushing_batch_length: must be positive, got -5`,
+		`wal.compression: unsupported value "zstd"`,
+		`metrics.path: must start with /, got "metrics"`,
+		`slowlog.threshold: must not be negative, got -1s`,
+		`tracing.exporter: unsupported value "jaeger"`,
+	} {
+		assert.ErrorContains(t, err, expected)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := load(strings.NewReader(`servers:
+  - type: tcp
+    name: main
+    port: 8087
+  - type: unix
+    name: sidecar
+    path: kava.sock
+users:
+  - name: reader
+    password_hash: "pbkdf2_sha256$10000$528bc7c0bb5c51ee08ddee156ec4f832$9cbf699aa40d38b81ffe360c2506ea071af468d14247d8160252e8502a69802a"
+    commands: ["GET"]
+`), []string{
+		"KAVA_LOGGING_LEVEL=debug",
+		"KAVA_SERVERS_0_PORT=9090",
+		"KAVA_SERVERS_0_MAX_MESSAGE_SIZE=8KB",
+		"KAVA_SERVERS_1_MODE=0600",
+		"KAVA_SERVERS_1_IDLE_TIMEOUT=30s",
+		"KAVA_USERS_0_COMMANDS=GET, SET",
+		"KAVA_WAL_FLUSHING_BATCH_TIMEOUT=5ms",
+		"KAVA_WAL_RETENTION_MAX_AGE=1h",
+		"KAVA_ADMIN_ENABLED=true",
+		"KAVA_UNKNOWN=value",
+		"KAVA_METRICS_ADRESS=localhost:9000",
+		"KAVA_SERVERS_2_PORT=9091",
+		"PATH=/usr/bin",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "debug", cfg.Logging.Level)
+
+	tcpServer := cfg.Servers[0].(*TCPServerConfig)
+	assert.Equal(t, 9090, tcpServer.Port)
+	assert.Equal(t, ByteSize(8192), tcpServer.MaxMessageSize)
+
+	unixServer := cfg.Servers[1].(*UnixServerConfig)
+	assert.Equal(t, FileMode(0600), unixServer.Mode)
+	assert.Equal(t, 30*time.Second, unixServer.IdleTimeout)
+
+	assert.Equal(t, []string{"GET", "SET"}, cfg.Users[0].Commands)
+
+	// разделы, которых нет в файле, создаются переменными окружения
+	require.NotNil(t, cfg.WAL)
+	assert.Equal(t, 5*time.Millisecond, cfg.WAL.FlushingBatchTimeout)
+	assert.Equal(t, 10<<20, int(cfg.WAL.MaxSegmentSize))
+	assert.Equal(t, time.Hour, cfg.WAL.Retention.MaxAge)
+	assert.Equal(t, &AdminConfig{Enabled: true}, cfg.Admin)
+
+	// переменная с опечаткой не включает раздел и попадает в пропущенные
+	assert.Nil(t, cfg.Metrics)
+	assert.Equal(t, []string{"KAVA_METRICS_ADRESS", "KAVA_SERVERS_2_PORT", "KAVA_UNKNOWN"}, cfg.UnknownEnv())
+}
+
+func TestApplyEnvInvalidValue(t *testing.T) {
+	t.Parallel()
+
+	_, err := load(strings.NewReader(""), []string{"KAVA_WAL_FLUSHING_BATCH_TIMEOUT=soon"})
+	assert.ErrorContains(t, err, "KAVA_WAL_FLUSHING_BATCH_TIMEOUT")
+}
+
+func TestLoadFromEnvironment(t *testing.T) {
+	t.Setenv("KAVA_LOGGING_OUTPUT", "stderr")
+
+	cfg, err := Load(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Equal(t, "stderr", cfg.Logging.Output)
+}
+
+func TestLoadOverrides(t *testing.T) {
+	t.Parallel()
+
+	cfgData := `logging:
+  level: info
+wal:
+  data_directory: "wal_data"
+  retention:
+    keep_segments: 2
+servers:
+  - type: tcp
+    name: main
+    host: localhost
+    port: 8087
+  - type: unix
+    name: sidecar
+    path: kava.sock
+  - type: console
+`
+
+	t.Run("override fields", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := load(strings.NewReader(cfgData), []string{"KAVA_LOGGING_LEVEL=warn"},
+			WithLoggingLevel("debug"),
+			WithDataDirectory("/var/lib/kava"),
+			WithListenAddress("main", "0.0.0.0:9090"),
+			WithListenAddress("sidecar", "unix:///run/kava.sock"),
+		)
+		require.NoError(t, err)
+
+		// флаги важнее переменных окружения
+		assert.Equal(t, "debug", cfg.Logging.Level)
+		assert.Equal(t, "/var/lib/kava", cfg.WAL.DataDirectory)
+		// значение по умолчанию вычисляется от переопределенного каталога
+		assert.Equal(t, filepath.Join("/var/lib/kava", "archive"), cfg.WAL.Retention.ArchiveDirectory)
+
+		tcpServer := cfg.Servers[0].(*TCPServerConfig)
+		assert.Equal(t, "0.0.0.0", tcpServer.Host)
+		assert.Equal(t, 9090, tcpServer.Port)
+		assert.Equal(t, "/run/kava.sock", cfg.Servers[1].(*UnixServerConfig).Path)
+	})
+
+	t.Run("override without server name", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := load(strings.NewReader(`servers:
+  - type: tcp
+    port: 8087
+`), nil, WithListenAddress("", "localhost:9000"), WithDataDirectory("data"))
+		require.NoError(t, err)
+		assert.Equal(t, "localhost", cfg.Servers[0].(*TCPServerConfig).Host)
+		assert.Equal(t, 9000, cfg.Servers[0].(*TCPServerConfig).Port)
+		require.NotNil(t, cfg.WAL)
+		assert.Equal(t, "data", cfg.WAL.DataDirectory)
+	})
+
+	t.Run("invalid overrides", func(t *testing.T) {
+		t.Parallel()
+
+		tests := map[string]struct {
+			override Override
+			err      string
+		}{
+			"ambiguous server": {
+				override: WithListenAddress("", "localhost:9000"),
+				err:      "server name is required",
+			},
+			"unknown server": {
+				override: WithListenAddress("backup", "localhost:9000"),
+				err:      `tcp or unix server "backup" not found`,
+			},
+			"invalid tcp address": {
+				override: WithListenAddress("main", "localhost"),
+				err:      `invalid tcp address "localhost"`,
+			},
+			"invalid level": {
+				override: WithLoggingLevel("trace"),
+				err:      `logging.level: unsupported value "trace"`,
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := load(strings.NewReader(cfgData), nil, test.override)
+				assert.ErrorContains(t, err, test.err)
+			})
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package configuration
+
+import (
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/bytefmt"
+)
+
+const (
+	defaultMaxConnections = 100
+	defaultMaxMessageSize = 4 * bytefmt.KILOBYTE
+	defaultIdleTimeout    = 60 * time.Second
+	defaultDrainTimeout   = 5 * time.Second
+	defaultHistorySize    = 1000
+	defaultHost           = "0.0.0.0"
+	defaultPort           = 8080
+	defaultOnListenError  = ListenErrorFail
+
+	// Default logging values
+	defaultLogLevel  = "info"
+	defaultLogOutput = "kava.log"
+
+	// Default engine type
+	defaultEngineType = "in_memory"
+
+	// Default WAL values
+	defaultFlushingBatchLength  = 100
+	defaultFlushingBatchTimeout = 10 * time.Millisecond
+	defaultMaxSegmentSize       = 10 * bytefmt.MEGABYTE
+	defaultWALDataDirectory     = "./data/spider/wal"
+	defaultArchiveSubdirectory  = "archive"
+	defaultArchiveCheckInterval = time.Minute
+
+	// Default metrics values
+	defaultMetricsAddress = "localhost:9180"
+	defaultMetricsPath    = "/metrics"
+
+	defaultSlowLogMaxLength = 128
+	defaultTracingExporter  = "log"
+)
+
+// DefaultLogLevel -- уровень логирования без раздела logging
+func DefaultLogLevel() string {
+	return defaultLogLevel
+}
+
+// DefaultLogOutput -- файл лога без раздела logging
+func DefaultLogOutput() string {
+	return defaultLogOutput
+}
+
+// SetDefaults -- заполняет незаданные поля значениями по умолчанию. Разделы
+// engine и logging создаются всегда, остальные необязательные разделы
+// (wal, admin, metrics, slowlog, tracing) без них остаются выключенными
+func (c *Config) SetDefaults() {
+	if c.Engine == nil {
+		c.Engine = &EngineConfig{}
+	}
+	if c.Engine.Type == "" {
+		c.Engine.Type = defaultEngineType
+	}
+
+	if c.Logging == nil {
+		c.Logging = &LoggingConfig{}
+	}
+	if c.Logging.Level == "" {
+		c.Logging.Level = defaultLogLevel
+	}
+	if c.Logging.Output == "" {
+		c.Logging.Output = defaultLogOutput
+	}
+
+	for _, server := range c.Servers {
+		server.setDefaults()
+	}
+
+	if c.WAL != nil {
+		c.WAL.setDefaults()
+	}
+
+	if c.Metrics != nil {
+		if c.Metrics.Address == "" {
+			c.Metrics.Address = defaultMetricsAddress
+		}
+		if c.Metrics.Path == "" {
+			c.Metrics.Path = defaultMetricsPath
+		}
+	}
+
+	if c.SlowLog != nil && c.SlowLog.MaxLength == 0 {
+		c.SlowLog.MaxLength = defaultSlowLogMaxLength
+	}
+
+	if c.Tracing != nil && c.Tracing.Exporter == "" {
+		c.Tracing.Exporter = defaultTracingExporter
+	}
+}
+
+func (c *ConsoleConfig) setDefaults() {
+	if c.HistorySize == 0 {
+		c.HistorySize = defaultHistorySize
+	}
+}
+
+func (t *TCPServerConfig) setDefaults() {
+	if t.Port == 0 {
+		t.Port = defaultPort
+	}
+	if t.Host == "" {
+		t.Host = defaultHost
+	}
+	if t.OnListenError == "" {
+		t.OnListenError = defaultOnListenError
+	}
+	setConnectionDefaults(&t.MaxConnections, &t.MaxMessageSize, &t.IdleTimeout, &t.DrainTimeout)
+}
+
+// setDefaults -- значения по умолчанию совпадают с TCP сервером
+func (u *UnixServerConfig) setDefaults() {
+	if u.OnListenError == "" {
+		u.OnListenError = defaultOnListenError
+	}
+	setConnectionDefaults(&u.MaxConnections, &u.MaxMessageSize, &u.IdleTimeout, &u.DrainTimeout)
+}
+
+func setConnectionDefaults(
+	maxConnections *int,
+	maxMessageSize *ByteSize,
+	idleTimeout *time.Duration,
+	drainTimeout *time.Duration,
+) {
+	if *maxConnections == 0 {
+		*maxConnections = defaultMaxConnections
+	}
+	if *maxMessageSize == 0 {
+		*maxMessageSize = defaultMaxMessageSize
+	}
+	if *idleTimeout == 0 {
+		*idleTimeout = defaultIdleTimeout
+	}
+	if *drainTimeout == 0 {
+		*drainTimeout = defaultDrainTimeout
+	}
+}
+
+func (w *WALConfig) setDefaults() {
+	if w.FlushingBatchLength == 0 {
+		w.FlushingBatchLength = defaultFlushingBatchLength
+	}
+	if w.FlushingBatchTimeout == 0 {
+		w.FlushingBatchTimeout = defaultFlushingBatchTimeout
+	}
+	if w.MaxSegmentSize == 0 {
+		w.MaxSegmentSize = defaultMaxSegmentSize
+	}
+	if w.DataDirectory == "" {
+		w.DataDirectory = defaultWALDataDirectory
+	}
+
+	if w.Retention != nil {
+		if w.Retention.ArchiveDirectory == "" {
+			w.Retention.ArchiveDirectory = filepath.Join(w.DataDirectory, defaultArchiveSubdirectory)
+		}
+		if w.Retention.CheckInterval == 0 {
+			w.Retention.CheckInterval = defaultArchiveCheckInterval
+		}
+	}
+}
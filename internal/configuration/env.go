@@ -0,0 +1,179 @@
+package configuration
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix -- префикс переменных окружения, которые переопределяют конфигурацию
+const envPrefix = "KAVA"
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	stringsType = reflect.TypeOf([]string(nil))
+)
+
+// ApplyEnv -- переопределяет поля конфигурации переменными окружения из environ
+// (в формате os.Environ). Имя переменной - yaml имена полей в верхнем регистре
+// через "_" с префиксом KAVA, элементы списков адресуются индексом:
+// KAVA_LOGGING_LEVEL, KAVA_WAL_RETENTION_MAX_AGE, KAVA_SERVERS_0_PORT.
+// Значения разбираются как в YAML, списки строк задаются через запятую.
+// Переменные, которые не подошли ни к одному полю, возвращает UnknownEnv
+func (c *Config) ApplyEnv(environ []string) error {
+	env := make(map[string]string)
+	for _, variable := range environ {
+		name, value, found := strings.Cut(variable, "=")
+		if found && strings.HasPrefix(name, envPrefix+"_") {
+			env[name] = value
+		}
+	}
+
+	c.unknownEnv = nil
+	if len(env) == 0 {
+		return nil
+	}
+
+	used := make(map[string]bool)
+	if err := applyEnv(reflect.ValueOf(c).Elem(), envPrefix, env, used); err != nil {
+		return err
+	}
+
+	for name := range env {
+		if !used[name] {
+			c.unknownEnv = append(c.unknownEnv, name)
+		}
+	}
+	sort.Strings(c.unknownEnv)
+
+	return nil
+}
+
+// UnknownEnv -- пропущенные переменные KAVA_*, например, с опечаткой в имени
+// или с индексом за пределами списка
+func (c *Config) UnknownEnv() []string {
+	return c.unknownEnv
+}
+
+// applyEnv -- used отмечает переменные, примененные к полям
+func applyEnv(value reflect.Value, name string, env map[string]string, used map[string]bool) error {
+	if isEnvScalar(value.Type()) {
+		raw, found := env[name]
+		if !found {
+			return nil
+		}
+
+		used[name] = true
+		if err := setEnvValue(value, raw); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer:
+		if !value.IsNil() {
+			return applyEnv(value.Elem(), name, env, used)
+		}
+
+		// раздел, которого нет в YAML, создается, если хотя бы одна
+		// переменная подошла к его полям
+		if !hasEnvPrefix(env, name+"_") {
+			return nil
+		}
+
+		section := reflect.New(value.Type().Elem())
+		applied := len(used)
+		if err := applyEnv(section.Elem(), name, env, used); err != nil {
+			return err
+		}
+		if len(used) != applied {
+			value.Set(section)
+		}
+	case reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return applyEnv(value.Elem(), name, env, used)
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			if err := applyEnv(value.Index(i), fmt.Sprintf("%s_%d", name, i), env, used); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			tagName, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			fieldName := name
+			if options != "inline" {
+				if tagName == "" || tagName == "-" {
+					continue
+				}
+				fieldName = name + "_" + strings.ToUpper(tagName)
+			}
+
+			if err := applyEnv(value.Field(i), fieldName, env, used); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// isEnvScalar -- значение задается одной переменной окружения
+func isEnvScalar(t reflect.Type) bool {
+	if t == timeType || t == stringsType {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+func setEnvValue(value reflect.Value, raw string) error {
+	switch {
+	case value.Type() == stringsType:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+		return nil
+	case value.Kind() == reflect.String:
+		// строка без разбора YAML, иначе "yes" или "0660" изменили бы тип
+		value.SetString(raw)
+		return nil
+	}
+
+	// длительности, размеры и права разбираются так же, как в файле
+	return yaml.Unmarshal([]byte(raw), value.Addr().Interface())
+}
+
+func hasEnvPrefix(env map[string]string, prefix string) bool {
+	for name := range env {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -5,9 +5,12 @@ func (b BaseServer) getType() string {
 	return b.Type
 }
 
-// ConsoleConfig - конфигурация консольного сервера
+// ConsoleConfig - конфигурация консольного сервера, история запросов
+// сохраняется в history_file, если он задан
 type ConsoleConfig struct {
-	BaseServer `yaml:",inline"`
+	BaseServer  `yaml:",inline"`
+	HistoryFile string `yaml:"history_file"`
+	HistorySize int    `yaml:"history_size"`
 }
 
 func (c *ConsoleConfig) getType() string {
@@ -24,4 +27,12 @@ func (t *TCPServerConfig) getType() string {
 
 func (t *TCPServerConfig) getName() string {
 	return t.Name
+}
+
+func (u *UnixServerConfig) getType() string {
+	return u.Type
+}
+
+func (u *UnixServerConfig) getName() string {
+	return u.Name
 }
\ No newline at end of file
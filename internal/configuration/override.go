@@ -0,0 +1,102 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Override -- изменение конфигурации поверх файла и переменных окружения,
+// например, из флагов командной строки. Применяется до значений по умолчанию,
+// поэтому зависящие от поля значения (каталог архива WAL) вычисляются от нового
+type Override func(*Config) error
+
+// WithLoggingLevel -- переопределяет logging.level
+func WithLoggingLevel(level string) Override {
+	return func(c *Config) error {
+		if c.Logging == nil {
+			c.Logging = &LoggingConfig{}
+		}
+
+		c.Logging.Level = level
+		return nil
+	}
+}
+
+// WithDataDirectory -- переопределяет wal.data_directory, включает WAL,
+// если раздела нет в конфигурации
+func WithDataDirectory(directory string) Override {
+	return func(c *Config) error {
+		if c.WAL == nil {
+			c.WAL = &WALConfig{}
+		}
+
+		c.WAL.DataDirectory = directory
+		return nil
+	}
+}
+
+// WithListenAddress -- переопределяет адрес сервера с именем name: host:port для
+// tcp, путь сокета (можно с префиксом unix://) для unix. Без имени адрес
+// применяется к единственному tcp или unix серверу конфигурации
+func WithListenAddress(name, address string) Override {
+	return func(c *Config) error {
+		server, err := findListener(c.Servers, name)
+		if err != nil {
+			return err
+		}
+
+		switch server := server.(type) {
+		case *TCPServerConfig:
+			host, portValue, err := net.SplitHostPort(address)
+			if err != nil {
+				return fmt.Errorf("invalid tcp address %q: %w", address, err)
+			}
+
+			port, err := strconv.Atoi(portValue)
+			if err != nil {
+				return fmt.Errorf("invalid tcp port %q", portValue)
+			}
+
+			server.Host = host
+			server.Port = port
+		case *UnixServerConfig:
+			path := strings.TrimPrefix(address, "unix://")
+			if path == "" {
+				return fmt.Errorf("invalid unix address %q", address)
+			}
+
+			server.Path = path
+		}
+
+		return nil
+	}
+}
+
+func findListener(servers ServerConfigs, name string) (ServerConfig, error) {
+	var found []ServerConfig
+	for _, server := range servers {
+		switch server.(type) {
+		case *TCPServerConfig, *UnixServerConfig:
+		default:
+			continue
+		}
+
+		if name == "" || server.getName() == name {
+			found = append(found, server)
+		}
+	}
+
+	switch {
+	case len(found) == 1:
+		return found[0], nil
+	case name != "" && len(found) == 0:
+		return nil, fmt.Errorf("tcp or unix server %q not found", name)
+	case len(found) == 0:
+		return nil, errors.New("no tcp or unix server to override address")
+	}
+
+	return nil, errors.New("several servers configured, server name is required to override address")
+}
@@ -2,6 +2,7 @@ package configuration
 
 import (
 	"fmt"
+	"strconv"
 
 	"code.cloudfoundry.org/bytefmt"
 	"gopkg.in/yaml.v3"
@@ -32,6 +33,17 @@ func (m *ByteSize) UnmarshalYAML(unmarshal func(any) error) error {
 	return nil
 }
 
+// UnmarshalYAML - права разбираются как восьмеричное число
+func (m *FileMode) UnmarshalYAML(value *yaml.Node) error {
+	mode, err := strconv.ParseUint(value.Value, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid file mode %q: %w", value.Value, err)
+	}
+
+	*m = FileMode(mode)
+	return nil
+}
+
 // UnmarshalYAML - кастомная десериализация для slice серверов
 func (s *ServerConfigs) UnmarshalYAML(value *yaml.Node) error {
 	// Проверяем, что получили sequence (массив) в YAML
@@ -68,21 +80,12 @@ func (s *ServerConfigs) UnmarshalYAML(value *yaml.Node) error {
 			if err := item.Decode(&s); err != nil {
 				return fmt.Errorf("failed to decode tcp server: %w", err)
 			}
-			// Устанавливаем значения по умолчанию для TCP сервера
-			if s.Port == 0 {
-				s.Port = defaultPort
-			}
-			if s.Host == "" {
-				s.Host = defaultHost
-			}
-			if s.MaxConnections == 0 {
-				s.MaxConnections = defaultMaxConnections
-			}
-			if s.MaxMessageSize == 0 {
-				s.MaxMessageSize = defaultMaxMessageSize
-			}
-			if s.IdleTimeout == 0 {
-				s.IdleTimeout = defaultIdleTimeout
+			server = &s
+
+		case "unix":
+			var s UnixServerConfig
+			if err := item.Decode(&s); err != nil {
+				return fmt.Errorf("failed to decode unix server: %w", err)
 			}
 			server = &s
 
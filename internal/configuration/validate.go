@@ -0,0 +1,187 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"kava/internal/password"
+)
+
+// Validate -- проверяет конфигурацию после заполнения значений по умолчанию
+// и возвращает сразу все найденные ошибки
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Engine != nil {
+		errs = append(errs, checkSupported("engine.type", c.Engine.Type, supportedEngineTypes)...)
+	}
+
+	if c.Logging != nil {
+		errs = append(errs, checkSupported("logging.level", c.Logging.Level, supportedLogLevels)...)
+	}
+
+	names := make(map[string]string, len(c.Servers))
+	for i, server := range c.Servers {
+		path := fmt.Sprintf("servers[%d]", i)
+		if name := server.getName(); name != "" {
+			if previous, exist := names[name]; exist {
+				errs = append(errs, fmt.Errorf("%s.name: %q is already used by %s", path, name, previous))
+			}
+			names[name] = path
+		}
+
+		errs = append(errs, server.validate(path)...)
+	}
+
+	users := make(map[string]struct{}, len(c.Users))
+	for i, user := range c.Users {
+		path := fmt.Sprintf("users[%d]", i)
+		if user.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name: must be set", path))
+		} else if _, exist := users[user.Name]; exist {
+			errs = append(errs, fmt.Errorf("%s.name: duplicate user %q", path, user.Name))
+		}
+		users[user.Name] = struct{}{}
+
+		if _, err := password.Parse(user.PasswordHash); err != nil {
+			errs = append(errs, fmt.Errorf("%s.password_hash: %w", path, err))
+		}
+	}
+
+	if c.WAL != nil {
+		errs = append(errs, c.WAL.validate("wal")...)
+	}
+
+	if c.Metrics != nil && !strings.HasPrefix(c.Metrics.Path, "/") {
+		errs = append(errs, fmt.Errorf("metrics.path: must start with /, got %q", c.Metrics.Path))
+	}
+
+	if c.SlowLog != nil {
+		errs = append(errs, checkNotNegative("slowlog.threshold", c.SlowLog.Threshold)...)
+		errs = append(errs, checkPositive("slowlog.max_length", c.SlowLog.MaxLength)...)
+	}
+
+	if c.Tracing != nil {
+		errs = append(errs, checkSupported("tracing.exporter", c.Tracing.Exporter, supportedTracingExporter)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *ConsoleConfig) validate(path string) []error {
+	return checkNotNegative(path+".history_size", c.HistorySize)
+}
+
+func (t *TCPServerConfig) validate(path string) []error {
+	var errs []error
+	if t.Port < 0 || t.Port > 65535 {
+		errs = append(errs, fmt.Errorf("%s.port: must be in range 0-65535, got %d", path, t.Port))
+	}
+
+	if t.Mode != "" {
+		errs = append(errs, checkSupported(path+".mode", t.Mode, supportedServerModes)...)
+	}
+	errs = append(errs, checkNotNegative(path+".workers", t.Workers)...)
+	errs = append(errs, checkSupported(path+".on_listen_error", t.OnListenError, supportedListenPolicies)...)
+
+	if t.TLS != nil {
+		if t.TLS.CertFile == "" {
+			errs = append(errs, fmt.Errorf("%s.tls.cert_file: must be set", path))
+		}
+		if t.TLS.KeyFile == "" {
+			errs = append(errs, fmt.Errorf("%s.tls.key_file: must be set", path))
+		}
+		if t.TLS.RequireClientCert && t.TLS.CAFile == "" {
+			errs = append(errs, fmt.Errorf("%s.tls.ca_file: must be set with require_client_cert", path))
+		}
+		if t.Mode == "netpoll" {
+			errs = append(errs, fmt.Errorf("%s.tls: is not supported in netpoll mode", path))
+		}
+	}
+
+	return append(errs, validateConnections(
+		path, t.MaxConnections, t.MaxMessageSize, t.IdleTimeout, t.DrainTimeout, t.QueryTimeout, t.MaxConnectionsWait,
+	)...)
+}
+
+func (u *UnixServerConfig) validate(path string) []error {
+	var errs []error
+	if u.Path == "" {
+		errs = append(errs, fmt.Errorf("%s.path: is required for unix server", path))
+	}
+	errs = append(errs, checkSupported(path+".on_listen_error", u.OnListenError, supportedListenPolicies)...)
+
+	return append(errs, validateConnections(
+		path, u.MaxConnections, u.MaxMessageSize, u.IdleTimeout, u.DrainTimeout, u.QueryTimeout, u.MaxConnectionsWait,
+	)...)
+}
+
+func validateConnections(
+	path string,
+	maxConnections int,
+	maxMessageSize ByteSize,
+	idleTimeout, drainTimeout, queryTimeout, maxConnectionsWait time.Duration,
+) []error {
+	var errs []error
+	errs = append(errs, checkPositive(path+".max_connections", maxConnections)...)
+	errs = append(errs, checkPositive(path+".max_message_size", maxMessageSize)...)
+	errs = append(errs, checkNotNegative(path+".idle_timeout", idleTimeout)...)
+	errs = append(errs, checkNotNegative(path+".drain_timeout", drainTimeout)...)
+	errs = append(errs, checkNotNegative(path+".query_timeout", queryTimeout)...)
+	errs = append(errs, checkNotNegative(path+".max_connections_wait", maxConnectionsWait)...)
+	return errs
+}
+
+func (w *WALConfig) validate(path string) []error {
+	var errs []error
+	errs = append(errs, checkPositive(path+".flushing_batch_length", w.FlushingBatchLength)...)
+	errs = append(errs, checkPositive(path+".flushing_batch_timeout", w.FlushingBatchTimeout)...)
+	// сегмент нулевого размера переполнен сразу, и каждый батч открывал бы новый файл
+	errs = append(errs, checkPositive(path+".max_segment_size", w.MaxSegmentSize)...)
+	if w.Compression != "" {
+		errs = append(errs, checkSupported(path+".compression", w.Compression, supportedCompressions)...)
+	}
+
+	if w.Retention != nil {
+		errs = append(errs, checkNotNegative(path+".retention.max_total_size", w.Retention.MaxTotalSize)...)
+		errs = append(errs, checkNotNegative(path+".retention.max_age", w.Retention.MaxAge)...)
+		errs = append(errs, checkNotNegative(path+".retention.keep_segments", w.Retention.KeepSegments)...)
+		errs = append(errs, checkPositive(path+".retention.check_interval", w.Retention.CheckInterval)...)
+	}
+
+	if w.Recovery != nil {
+		errs = append(errs, checkNotNegative(path+".recovery.target_lsn", w.Recovery.TargetLSN)...)
+	}
+
+	return errs
+}
+
+func checkSupported(path, value string, supported []string) []error {
+	if slices.Contains(supported, value) {
+		return nil
+	}
+
+	return []error{fmt.Errorf("%s: unsupported value %q, supported: %s", path, value, strings.Join(supported, ", "))}
+}
+
+type number interface {
+	~int | ~int64
+}
+
+func checkPositive[T number](path string, value T) []error {
+	if value > 0 {
+		return nil
+	}
+
+	return []error{fmt.Errorf("%s: must be positive, got %v", path, value)}
+}
+
+func checkNotNegative[T number](path string, value T) []error {
+	if value >= 0 {
+		return nil
+	}
+
+	return []error{fmt.Errorf("%s: must not be negative, got %v", path, value)}
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"kava/internal/configuration"
+	"kava/internal/password"
+)
+
+const anyPattern = "*"
+
+var (
+	// ErrInvalidCredentials -- неизвестный пользователь или неверный пароль
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrPermissionDenied -- пользователю не разрешена команда или ключ
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// User -- аутентифицированный пользователь с его правами
+type User struct {
+	name         string
+	passwordHash *password.Hash
+	allCommands  bool
+	commands     map[string]struct{}
+	allKeys      bool
+	keys         []string
+}
+
+// Name -- getter
+func (u *User) Name() string {
+	return u.name
+}
+
+// Authorize -- проверяет, что пользователю разрешена команда над ключами,
+// команды без ключей проверяются только по имени
+func (u *User) Authorize(command string, keys ...string) error {
+	if !u.allowsCommand(command) {
+		return ErrPermissionDenied
+	}
+
+	for _, key := range keys {
+		if !u.allowsKey(key) {
+			return ErrPermissionDenied
+		}
+	}
+
+	return nil
+}
+
+// AuthorizeAllKeys -- команда над всеми ключами, например FLUSHALL,
+// разрешена только пользователю с шаблоном ключей "*"
+func (u *User) AuthorizeAllKeys(command string) error {
+	if !u.allowsCommand(command) || !u.allKeys {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+func (u *User) allowsCommand(command string) bool {
+	_, ok := u.commands[strings.ToUpper(command)]
+	return ok || u.allCommands
+}
+
+func (u *User) allowsKey(key string) bool {
+	for _, pattern := range u.keys {
+		if matchKey(pattern, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchKey -- шаблон с "*" на конце задает префикс, иначе ключ должен совпадать целиком
+func matchKey(pattern, key string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, anyPattern); ok {
+		return strings.HasPrefix(key, prefix)
+	}
+
+	return pattern == key
+}
+
+// Authenticator -- проверяет пароли пользователей из конфигурации
+type Authenticator struct {
+	users map[string]*User
+	// unknownUserHash -- проверяется для неизвестного пользователя, чтобы время
+	// ответа не выдавало, есть ли такой пользователь
+	unknownUserHash *password.Hash
+}
+
+// NewAuthenticator -- конструктор, пароли задаются хэшем PBKDF2 с солью
+// в формате pbkdf2_sha256$<iterations>$<salt hex>$<hash hex>
+func NewAuthenticator(users []configuration.UserConfig) (*Authenticator, error) {
+	if len(users) == 0 {
+		return nil, errors.New("users are not configured")
+	}
+
+	authenticator := &Authenticator{
+		users: make(map[string]*User, len(users)),
+	}
+
+	for _, cfg := range users {
+		if cfg.Name == "" {
+			return nil, errors.New("user name is empty")
+		}
+		if _, exist := authenticator.users[cfg.Name]; exist {
+			return nil, fmt.Errorf("user %s is duplicated", cfg.Name)
+		}
+
+		passwordHash, err := password.Parse(cfg.PasswordHash)
+		if err != nil {
+			return nil, fmt.Errorf("password hash of user %s is invalid: %w", cfg.Name, err)
+		}
+		if authenticator.unknownUserHash == nil {
+			authenticator.unknownUserHash = passwordHash
+		}
+
+		user := &User{
+			name:         cfg.Name,
+			passwordHash: passwordHash,
+			commands:     make(map[string]struct{}, len(cfg.Commands)),
+			allKeys:      slices.Contains(cfg.Keys, anyPattern),
+			keys:         cfg.Keys,
+		}
+		for _, command := range cfg.Commands {
+			if command == anyPattern {
+				user.allCommands = true
+				continue
+			}
+			user.commands[strings.ToUpper(command)] = struct{}{}
+		}
+
+		authenticator.users[cfg.Name] = user
+	}
+
+	return authenticator, nil
+}
+
+// Authenticate -- возвращает пользователя, если пароль совпал с хэшем
+func (a *Authenticator) Authenticate(name, secret string) (*User, error) {
+	user, exist := a.users[name]
+	if !exist {
+		a.unknownUserHash.Match(secret)
+		return nil, ErrInvalidCredentials
+	}
+
+	if !user.passwordHash.Match(secret) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// HashPassword -- хэш пароля со случайной солью в виде, ожидаемом в password_hash
+func HashPassword(secret string) (string, error) {
+	return password.Generate(secret, password.DefaultIterations)
+}
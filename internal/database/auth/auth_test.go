@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kava/internal/configuration"
+	"kava/internal/password"
+)
+
+// hashPassword -- хэш с минимальным числом итераций, чтобы тесты были быстрыми
+func hashPassword(t *testing.T, secret string) string {
+	t.Helper()
+
+	hash, err := password.Generate(secret, password.MinIterations)
+	require.NoError(t, err)
+	return hash
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		users []configuration.UserConfig
+
+		expectedErr bool
+	}{
+		"without users": {
+			expectedErr: true,
+		},
+		"empty user name": {
+			users:       []configuration.UserConfig{{PasswordHash: hashPassword(t, "secret")}},
+			expectedErr: true,
+		},
+		"duplicated user": {
+			users: []configuration.UserConfig{
+				{Name: "admin", PasswordHash: hashPassword(t, "secret")},
+				{Name: "admin", PasswordHash: hashPassword(t, "other")},
+			},
+			expectedErr: true,
+		},
+		"invalid password hash": {
+			users:       []configuration.UserConfig{{Name: "admin", PasswordHash: "secret"}},
+			expectedErr: true,
+		},
+		"unsalted password hash": {
+			users: []configuration.UserConfig{
+				{Name: "admin", PasswordHash: "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"},
+			},
+			expectedErr: true,
+		},
+		"valid users": {
+			users: []configuration.UserConfig{
+				{Name: "admin", PasswordHash: hashPassword(t, "secret"), Commands: []string{"*"}, Keys: []string{"*"}},
+				{Name: "reader", PasswordHash: hashPassword(t, "reader"), Commands: []string{"GET"}},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			authenticator, err := NewAuthenticator(test.users)
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Nil(t, authenticator)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, authenticator)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	authenticator, err := NewAuthenticator([]configuration.UserConfig{
+		{Name: "admin", PasswordHash: hashPassword(t, "secret")},
+	})
+	require.NoError(t, err)
+
+	user, err := authenticator.Authenticate("admin", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", user.Name())
+
+	_, err = authenticator.Authenticate("admin", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = authenticator.Authenticate("unknown", "secret")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestAuthorize(t *testing.T) {
+	t.Parallel()
+
+	authenticator, err := NewAuthenticator([]configuration.UserConfig{
+		{Name: "admin", PasswordHash: hashPassword(t, "admin"), Commands: []string{"*"}, Keys: []string{"*"}},
+		{Name: "reader", PasswordHash: hashPassword(t, "reader"), Commands: []string{"get"}, Keys: []string{"user:*", "config"}},
+		{Name: "flusher", PasswordHash: hashPassword(t, "flusher"), Commands: []string{"FLUSHALL"}, Keys: []string{"user:*"}},
+		{Name: "nobody", PasswordHash: hashPassword(t, "nobody")},
+	})
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		user    string
+		command string
+		keys    []string
+		allKeys bool
+
+		expectedErr error
+	}{
+		"admin any command":          {user: "admin", command: "DEL", keys: []string{"anything"}},
+		"admin all keys":             {user: "admin", command: "FLUSHALL", allKeys: true},
+		"reader allowed prefix":      {user: "reader", command: "GET", keys: []string{"user:42"}},
+		"reader allowed exact key":   {user: "reader", command: "GET", keys: []string{"config"}},
+		"reader without keys":        {user: "reader", command: "GET"},
+		"reader exact key mismatch":  {user: "reader", command: "GET", keys: []string{"config2"}, expectedErr: ErrPermissionDenied},
+		"reader other prefix":        {user: "reader", command: "GET", keys: []string{"admin:1"}, expectedErr: ErrPermissionDenied},
+		"reader one of keys denied":  {user: "reader", command: "GET", keys: []string{"user:1", "admin:1"}, expectedErr: ErrPermissionDenied},
+		"reader forbidden command":   {user: "reader", command: "DEL", keys: []string{"user:42"}, expectedErr: ErrPermissionDenied},
+		"prefix keys deny all keys":  {user: "flusher", command: "FLUSHALL", allKeys: true, expectedErr: ErrPermissionDenied},
+		"nobody without permissions": {user: "nobody", command: "GET", keys: []string{"key"}, expectedErr: ErrPermissionDenied},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			user, err := authenticator.Authenticate(test.user, test.user)
+			require.NoError(t, err)
+
+			if test.allKeys {
+				err = user.AuthorizeAllKeys(test.command)
+			} else {
+				err = user.Authorize(test.command, test.keys...)
+			}
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
@@ -1,55 +1,202 @@
 package client
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net"
+	"strings"
 	"time"
 )
 
+const (
+	unixScheme = "unix://"
+	// aliveCheckTimeout -- ожидание при проверке соединения, за которое
+	// приходит уже отправленный сервером разрыв соединения
+	aliveCheckTimeout = time.Millisecond
+)
+
+// ErrMultilineRequest -- запросы разделяются переводом строки, запрос с ним
+// стал бы несколькими, и все следующие ответы сопоставились бы не тем запросам
+var ErrMultilineRequest = errors.New("request must not contain line breaks")
 
-// TCPClient -- клиент
+
+// TCPClient -- клиент, запросы и ответы разделяются переводом строки
 type TCPClient struct {
 	connection  net.Conn
-	//idleTimeout time.Duration
+	reader      *bufio.Reader
+	idleTimeout time.Duration
 	bufferSize  int
 }
 
-// NewTCPClient - создание клиента
+// NewTCPClient - создание клиента, адрес unix:///path/kava.sock подключает к unix сокету
 func NewTCPClient(address string, bufferSize int, idleTimeout time.Duration) (*TCPClient, error) {
-	connection, err := net.Dial("tcp", address)
+	network, address := splitAddress(address)
+	connection, err := net.Dial(network, address)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial: %w", err)
 	}
 
+	return newClient(connection, bufferSize, idleTimeout)
+}
+
+// NewTLSClient - создание клиента поверх TLS
+func NewTLSClient(address string, bufferSize int, idleTimeout time.Duration, tlsConfig *tls.Config) (*TCPClient, error) {
+	if tlsConfig == nil {
+		return nil, errors.New("tls config is invalid")
+	}
+
+	network, address := splitAddress(address)
+	dialer := &net.Dialer{Timeout: idleTimeout}
+	connection, err := tls.DialWithDialer(dialer, network, address, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+
+	return newClient(connection, bufferSize, idleTimeout)
+}
+
+// splitAddress -- возвращает сеть и адрес для net.Dial
+func splitAddress(address string) (string, string) {
+	if path, ok := strings.CutPrefix(address, unixScheme); ok {
+		return "unix", path
+	}
+
+	return "tcp", address
+}
+
+func newClient(connection net.Conn, bufferSize int, idleTimeout time.Duration) (*TCPClient, error) {
 	client := &TCPClient{
-		connection: connection,
-		bufferSize: bufferSize,
+		connection:  connection,
+		reader:      bufio.NewReaderSize(connection, bufferSize),
+		idleTimeout: idleTimeout,
+		bufferSize:  bufferSize,
 	}
 
-	if err := connection.SetDeadline(time.Now().Add(idleTimeout)); err != nil {
-		return nil, fmt.Errorf("failed to set deadline for connection: %w", err)
+	if err := client.extendDeadline(); err != nil {
+		_ = connection.Close()
+		return nil, err
 	}
-	
+
 	return client, nil
 }
 
-// Send - отправка запроса
+// Send - отправка запроса, перевод строки добавляется к запросу при отсутствии
+// и отрезается от ответа
 func (c *TCPClient) Send(request []byte) ([]byte, error) {
+	request, err := terminate(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.extendDeadline(); err != nil {
+		return nil, err
+	}
+
 	if _, err := c.connection.Write(request); err != nil {
 		return nil, err
 	}
 
-	response := make([]byte, c.bufferSize)
-	count, err := c.connection.Read(response)
-	if err != nil && err != io.EOF {
+	return c.readResponse()
+}
+
+// SendBatch - отправка нескольких запросов одной записью без ожидания ответов,
+// ответы возвращаются в порядке запросов
+func (c *TCPClient) SendBatch(requests [][]byte) ([][]byte, error) {
+	var batch []byte
+	for _, request := range requests {
+		request, err := terminate(request)
+		if err != nil {
+			return nil, err
+		}
+		batch = append(batch, request...)
+	}
+
+	if err := c.extendDeadline(); err != nil {
+		return nil, err
+	}
+
+	// ответы читаются параллельно с записью, иначе большая пачка может
+	// заполнить буферы сокета с обеих сторон
+	written := make(chan error, 1)
+	go func() {
+		_, err := c.connection.Write(batch)
+		written <- err
+	}()
+
+	responses := make([][]byte, 0, len(requests))
+	for range requests {
+		response, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+
+	if err := <-written; err != nil {
 		return nil, err
-	} else if count == c.bufferSize {
+	}
+
+	return responses, nil
+}
+
+// extendDeadline -- idle timeout отсчитывается от начала каждого запроса,
+// а не от создания клиента, иначе долгая сессия обрывается без простоя
+func (c *TCPClient) extendDeadline() error {
+	if err := c.connection.SetDeadline(time.Now().Add(c.idleTimeout)); err != nil {
+		return fmt.Errorf("failed to set deadline for connection: %w", err)
+	}
+
+	return nil
+}
+
+func (c *TCPClient) readResponse() ([]byte, error) {
+	line, err := c.reader.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) || len(line) > c.bufferSize {
 		return nil, errors.New("small buffer size")
+	} else if err != nil && (err != io.EOF || len(line) == 0) {
+		return nil, err
+	}
+
+	response := make([]byte, len(line))
+	copy(response, line)
+	return bytes.TrimRight(response, "\r\n"), nil
+}
+
+// terminate -- добавляет перевод строки, запрос может заканчиваться
+// им, но не может содержать переводы строк внутри
+func terminate(request []byte) ([]byte, error) {
+	if bytes.ContainsAny(bytes.TrimSuffix(request, []byte("\n")), "\r\n") {
+		return nil, ErrMultilineRequest
+	}
+
+	if bytes.HasSuffix(request, []byte("\n")) {
+		return request, nil
+	}
+
+	terminated := make([]byte, 0, len(request)+1)
+	return append(append(terminated, request...), '\n'), nil
+}
+
+// Alive - проверяет без запроса, что сервер не закрыл соединение, например,
+// по idle timeout. Данных от сервера до запроса быть не должно, поэтому и они
+// означают, что соединение использовать нельзя
+func (c *TCPClient) Alive() bool {
+	if c.reader.Buffered() > 0 {
+		return false
+	}
+
+	// прошедший дедлайн не дает прочитать даже уже полученный разрыв
+	if err := c.connection.SetReadDeadline(time.Now().Add(aliveCheckTimeout)); err != nil {
+		return false
 	}
 
-	return response[:count], nil
+	_, err := c.reader.Peek(1)
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
 
 // Close - закрытие клиента
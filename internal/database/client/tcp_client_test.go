@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bufio"
 	"net"
 	"testing"
 	"time"
@@ -171,4 +172,127 @@ func TestTimeout(t *testing.T) {
             t.Error("Expected timeout error, got nil")
         }
     })
-}
\ No newline at end of file
+}
+func TestIdleTimeoutPerRequest(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			if _, err := conn.Write(line); err != nil {
+				return
+			}
+		}
+	}()
+
+	client, err := NewTCPClient(listener.Addr().String(), 1024, 200*time.Millisecond)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// сессия длиннее idle timeout не обрывается, пока между запросами нет простоя
+	for i := 0; i < 4; i++ {
+		time.Sleep(100 * time.Millisecond)
+		response, err := client.Send([]byte("PING"))
+		require.NoError(t, err)
+		require.Equal(t, "PING", string(response))
+	}
+}
+
+func TestAlive(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- conn
+	}()
+
+	client, err := NewTCPClient(listener.Addr().String(), 1024, time.Second)
+	require.NoError(t, err)
+	defer client.Close()
+
+	conn := <-accepted
+	require.True(t, client.Alive())
+
+	// ответ без запроса означает, что поток ответов разошелся с запросами
+	_, err = conn.Write([]byte("[ok]\n"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return !client.Alive() }, time.Second, 10*time.Millisecond)
+
+	client, err = NewTCPClient(listener.Addr().String(), 1024, time.Second)
+	require.NoError(t, err)
+	defer client.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	// сервер закрыл соединение по idle timeout
+	require.NoError(t, (<-accepted).Close())
+	require.Eventually(t, func() bool { return !client.Alive() }, time.Second, 10*time.Millisecond)
+}
+
+func TestSendMultilineRequest(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			received <- line
+			if _, err := conn.Write([]byte("[ok]\n")); err != nil {
+				return
+			}
+		}
+	}()
+
+	client, err := NewTCPClient(listener.Addr().String(), 1024, time.Second)
+	require.NoError(t, err)
+	defer client.Close()
+
+	for _, request := range []string{"SET a b\nDEL c", "SET a b\rDEL c", "GET a\r\n"} {
+		_, err = client.Send([]byte(request))
+		require.ErrorIs(t, err, ErrMultilineRequest)
+	}
+
+	_, err = client.SendBatch([][]byte{[]byte("GET a"), []byte("GET b\nGET c")})
+	require.ErrorIs(t, err, ErrMultilineRequest)
+
+	// отклоненные запросы не отправлены, и ответы не сдвинулись
+	response, err := client.Send([]byte("GET a\n"))
+	require.NoError(t, err)
+	require.Equal(t, "[ok]", string(response))
+	require.Equal(t, "GET a\n", <-received)
+}
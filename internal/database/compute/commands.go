@@ -1,28 +1,142 @@
 package compute
 
+import (
+	"sort"
+	"strings"
+)
 
 const (
 	UnknownCommandID = iota
 	SetCommandID
 	GetCommandID
 	DelCommandID
+	PingCommandID
+	EchoCommandID
+	DBSizeCommandID
+	InfoCommandID
+	FlushAllCommandID
+	SlowLogCommandID
 )
 
 const (
-	setCommand = "SET"
-	getCommand = "GET"
-	delCommand = "DEL"
+	setCommand      = "SET"
+	getCommand      = "GET"
+	delCommand      = "DEL"
+	pingCommand     = "PING"
+	echoCommand     = "ECHO"
+	dbSizeCommand   = "DBSIZE"
+	infoCommand     = "INFO"
+	flushAllCommand = "FLUSHALL"
+	slowLogCommand  = "SLOWLOG"
 )
 
 var commandTextToID = map[string]int{
-	setCommand: SetCommandID,
-	getCommand: GetCommandID,
-	delCommand: DelCommandID,
+	setCommand:      SetCommandID,
+	getCommand:      GetCommandID,
+	delCommand:      DelCommandID,
+	pingCommand:     PingCommandID,
+	echoCommand:     EchoCommandID,
+	dbSizeCommand:   DBSizeCommandID,
+	infoCommand:     InfoCommandID,
+	flushAllCommand: FlushAllCommandID,
+	slowLogCommand:  SlowLogCommandID,
+}
+
+// keyArgument -- имя аргумента-ключа в описании команды
+const keyArgument = "key"
+
+// CommandInfo -- описание команды для справки, административные команды
+// выполняются, только если они включены в конфигурации
+type CommandInfo struct {
+	Name              string
+	Arguments         []string
+	OptionalArguments []string
+	Description       string
+	Admin             bool
+	// AllKeys -- команда затрагивает все ключи
+	AllKeys bool
+	// ReadOnly -- команда не меняет данные, ее можно повторить после обрыва соединения
+	ReadOnly bool
+}
+
+// Keys -- аргументы запроса, которые команда использует как ключи
+func (c CommandInfo) Keys(arguments []string) []string {
+	var keys []string
+	for idx, name := range c.Arguments {
+		if name == keyArgument && idx < len(arguments) {
+			keys = append(keys, arguments[idx])
+		}
+	}
+
+	return keys
+}
+
+// Usage -- синтаксис команды, например "SET key value"
+func (c CommandInfo) Usage() string {
+	tokens := append([]string{c.Name}, c.Arguments...)
+	for _, argument := range c.OptionalArguments {
+		tokens = append(tokens, "["+argument+"]")
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+// acceptsArguments -- подходит ли число аргументов команде
+func (c CommandInfo) acceptsArguments(count int) bool {
+	return count >= len(c.Arguments) && count <= len(c.Arguments)+len(c.OptionalArguments)
 }
 
+var commandsInfo = map[int]CommandInfo{
+	SetCommandID: {Name: setCommand, Arguments: []string{keyArgument, "value"}, Description: "set the value of the key"},
+	GetCommandID: {Name: getCommand, Arguments: []string{keyArgument}, Description: "get the value of the key", ReadOnly: true},
+	DelCommandID: {Name: delCommand, Arguments: []string{keyArgument}, Description: "delete the key"},
 
-var commandArgumentsCount = map[int]int{
-	SetCommandID: 2,
-	GetCommandID: 1,
-	DelCommandID: 1,
-}
\ No newline at end of file
+	PingCommandID: {Name: pingCommand, Description: "check the connection", ReadOnly: true},
+	EchoCommandID: {Name: echoCommand, Arguments: []string{"message"}, Description: "return the message", ReadOnly: true},
+
+	DBSizeCommandID:   {Name: dbSizeCommand, Description: "return the number of keys", Admin: true, ReadOnly: true},
+	InfoCommandID:     {Name: infoCommand, Description: "return server information and statistics", Admin: true, ReadOnly: true},
+	FlushAllCommandID: {Name: flushAllCommand, Description: "delete all keys", Admin: true, AllKeys: true},
+	SlowLogCommandID: {
+		Name:              slowLogCommand,
+		Arguments:         []string{"GET|LEN|RESET"},
+		OptionalArguments: []string{"count"},
+		Description:       "show or reset the slow query log",
+		Admin:             true,
+	},
+}
+
+// Commands -- описания всех команд, отсортированные по имени
+func Commands() []CommandInfo {
+	commands := make([]CommandInfo, 0, len(commandsInfo))
+	for _, info := range commandsInfo {
+		commands = append(commands, info)
+	}
+
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+	return commands
+}
+
+// LookupCommand -- описание команды по имени без учета регистра
+func LookupCommand(name string) (CommandInfo, bool) {
+	info, exist := commandsInfo[commandTextToID[strings.ToUpper(name)]]
+	return info, exist
+}
+
+// IsAdminCommand -- является ли команда административной
+func IsAdminCommand(commandID int) bool {
+	return commandsInfo[commandID].Admin
+}
+
+// CommandName -- возвращает текстовое имя команды по идентификатору
+func CommandName(commandID int) string {
+	for text, id := range commandTextToID {
+		if id == commandID {
+			return text
+		}
+	}
+
+	return "UNKNOWN"
+}
@@ -13,4 +13,74 @@ func TestCommand(t *testing.T) {
 	require.Equal(t, SetCommandID, commandTextToID["SET"])
 	require.Equal(t, GetCommandID, commandTextToID["GET"])
 	require.Equal(t, DelCommandID, commandTextToID["DEL"])
+	require.Equal(t, PingCommandID, commandTextToID["PING"])
+	require.Equal(t, EchoCommandID, commandTextToID["ECHO"])
+	require.Equal(t, DBSizeCommandID, commandTextToID["DBSIZE"])
+	require.Equal(t, InfoCommandID, commandTextToID["INFO"])
+	require.Equal(t, FlushAllCommandID, commandTextToID["FLUSHALL"])
+	require.Equal(t, SlowLogCommandID, commandTextToID["SLOWLOG"])
+}
+
+func TestCommandName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "SET", CommandName(SetCommandID))
+	require.Equal(t, "GET", CommandName(GetCommandID))
+	require.Equal(t, "DEL", CommandName(DelCommandID))
+	require.Equal(t, "UNKNOWN", CommandName(UnknownCommandID))
+}
+
+func TestCommands(t *testing.T) {
+	t.Parallel()
+
+	commands := Commands()
+	require.Len(t, commands, len(commandTextToID))
+	require.Equal(t, "DBSIZE", commands[0].Name)
+	require.Equal(t, "DEL", commands[1].Name)
+	require.Equal(t, "SET", commands[len(commands)-2].Name)
+	require.Equal(t, "SET key value", commands[len(commands)-2].Usage())
+	require.Equal(t, "SLOWLOG GET|LEN|RESET [count]", commands[len(commands)-1].Usage())
+}
+
+func TestIsAdminCommand(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, IsAdminCommand(InfoCommandID))
+	require.True(t, IsAdminCommand(DBSizeCommandID))
+	require.True(t, IsAdminCommand(FlushAllCommandID))
+	require.False(t, IsAdminCommand(PingCommandID))
+	require.False(t, IsAdminCommand(GetCommandID))
+	require.False(t, IsAdminCommand(UnknownCommandID))
+}
+
+func TestLookupCommand(t *testing.T) {
+	t.Parallel()
+
+	info, exist := LookupCommand("get")
+	require.True(t, exist)
+	require.Equal(t, "GET key", info.Usage())
+	require.NotEmpty(t, info.Description)
+
+	_, exist = LookupCommand("UNKNOWN")
+	require.False(t, exist)
+}
+
+func TestCommandInfoKeys(t *testing.T) {
+	t.Parallel()
+
+	keys := func(command string, arguments ...string) []string {
+		info, exist := LookupCommand(command)
+		require.True(t, exist)
+		return info.Keys(arguments)
+	}
+
+	require.Equal(t, []string{"key"}, keys("SET", "key", "value"))
+	require.Equal(t, []string{"key"}, keys("GET", "key"))
+	require.Equal(t, []string{"key"}, keys("DEL", "key"))
+	require.Nil(t, keys("DEL"))
+	require.Nil(t, keys("ECHO", "message"))
+	require.Nil(t, keys("SLOWLOG", "GET", "10"))
+
+	info, _ := LookupCommand("FLUSHALL")
+	require.True(t, info.AllKeys)
 }
@@ -41,13 +41,15 @@ func (d *Compute) Parse(queryStr string) (Query, error) {
 		d.logger.Debug("command not found", zap.String("query", queryStr))
 		return Query{}, errInvalidCommand
 	}
-	if commandArgumentsCount[commandID] != len(tokens)-1 {
+	if !commandsInfo[commandID].acceptsArguments(len(tokens) - 1) {
 		d.logger.Debug("invalid number of arguments for the query", zap.String("query", queryStr))
 		return Query{}, errInvalidArguments
 	}
 	query := Query{
 		commandID: commandID,
-		key:       tokens[1],
+	}
+	if len(tokens) > 1 {
+		query.key = tokens[1]
 	}
 	if len(tokens) == 3 {
 		query.value = tokens[2]
@@ -71,9 +71,17 @@ func TestParse(t *testing.T) {
 			expectedErr: errInvalidCommand,
 		},
 		"invalid command": {
-			queryStr: "INFO key",
+			queryStr: "KEYS key",
 			expectedErr: errInvalidCommand,
 		},
+		"INFO with argument": {
+			queryStr: "INFO key",
+			expectedErr: errInvalidArguments,
+		},
+		"ECHO without message": {
+			queryStr: "ECHO",
+			expectedErr: errInvalidArguments,
+		},
 		"GET without key": {
 			queryStr: "GET",
 			expectedErr: errInvalidArguments,
@@ -118,6 +126,30 @@ func TestParse(t *testing.T) {
 			queryStr: "DEL key:suffix",
 			expectedQuery: NewQuery(DelCommandID, "key:suffix", ""),
 		},
+		"PING query": {
+			queryStr: "PING",
+			expectedQuery: NewQuery(PingCommandID, "", ""),
+		},
+		"ECHO query": {
+			queryStr: "ECHO hello",
+			expectedQuery: NewQuery(EchoCommandID, "hello", ""),
+		},
+		"SLOWLOG query": {
+			queryStr: "SLOWLOG GET",
+			expectedQuery: NewQuery(SlowLogCommandID, "GET", ""),
+		},
+		"SLOWLOG query with optional argument": {
+			queryStr: "SLOWLOG GET 10",
+			expectedQuery: NewQuery(SlowLogCommandID, "GET", "10"),
+		},
+		"SLOWLOG with extra argument": {
+			queryStr: "SLOWLOG GET 10 20",
+			expectedErr: errInvalidArguments,
+		},
+		"FLUSHALL query": {
+			queryStr: "FLUSHALL",
+			expectedQuery: NewQuery(FlushAllCommandID, "", ""),
+		},
 	}
 	compute, err := NewCompute(zap.NewNop())
 	require.NoError(t, err)
@@ -4,12 +4,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"kava/internal/common"
 	"kava/internal/database/compute"
+	"kava/internal/database/filesystem"
+	"kava/internal/database/slowlog"
 	"kava/internal/database/storage"
+	"kava/internal/metrics"
+	"kava/internal/tracing"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
 )
 
+const (
+	adminDisabledResponse   = "[error] admin commands are disabled"
+	slowLogDisabledResponse = "[error] slow log is disabled"
+)
+
 type computeLayer interface {
 	Parse(string) (compute.Query, error)
 }
@@ -18,6 +32,10 @@ type storageLayer interface {
 	Set(context.Context, string, string) error
 	Get(context.Context, string) (string, error)
 	Del(context.Context, string) error
+	FlushAll(context.Context) error
+	Size() int
+	MemoryUsage() int64
+	LastLSN() int64
 }
 
 // Database -- состав по слоям
@@ -25,10 +43,68 @@ type Database struct {
 	computeLayer computeLayer
 	storageLayer storageLayer
 	logger       *zap.Logger
+
+	started       time.Time
+	adminCommands bool
+	walDirectory  string
+	connections   *atomic.Int64
+	metrics       queryMetrics
+	slowLog       *slowlog.SlowLog
+	tracer        tracing.Tracer
+}
+
+// DatabaseOption -- необязательная настройка базы
+type DatabaseOption func(*Database)
+
+// WithAdminCommands -- разрешает административные команды INFO, DBSIZE, FLUSHALL
+func WithAdminCommands(enabled bool) DatabaseOption {
+	return func(d *Database) {
+		d.adminCommands = enabled
+	}
+}
+
+// WithWALDirectory -- каталог сегментов WAL для статистики INFO
+func WithWALDirectory(directory string) DatabaseOption {
+	return func(d *Database) {
+		d.walDirectory = directory
+	}
+}
+
+// WithConnectionsCounter -- счетчик открытых соединений, который ведут серверы
+func WithConnectionsCounter(counter *atomic.Int64) DatabaseOption {
+	return func(d *Database) {
+		d.connections = counter
+	}
+}
+
+// WithMetrics -- считает запросы и их длительность по командам
+func WithMetrics(registry *metrics.Registry) DatabaseOption {
+	return func(d *Database) {
+		d.metrics = newQueryMetrics(registry)
+	}
+}
+
+// WithSlowLog -- записывает запросы дольше порога в журнал медленных запросов
+func WithSlowLog(slowLog *slowlog.SlowLog) DatabaseOption {
+	return func(d *Database) {
+		d.slowLog = slowLog
+	}
+}
+
+// WithTracer -- создает спаны запроса, разбора, ожидания WAL и применения к движку
+func WithTracer(tracer tracing.Tracer) DatabaseOption {
+	return func(d *Database) {
+		d.tracer = tracer
+	}
 }
 
 // NewDatabase -- конструктор Database
-func NewDatabase(computeLayer computeLayer, storageLayer storageLayer, logger *zap.Logger) (*Database, error) {
+func NewDatabase(
+	computeLayer computeLayer,
+	storageLayer storageLayer,
+	logger *zap.Logger,
+	options ...DatabaseOption,
+) (*Database, error) {
 	if computeLayer == nil {
 		return nil, errors.New("compute layer is invalid")
 	}
@@ -41,20 +117,79 @@ func NewDatabase(computeLayer computeLayer, storageLayer storageLayer, logger *z
 		return nil, errors.New("logger is invalid")
 	}
 
-	return &Database{
+	database := &Database{
 		computeLayer: computeLayer,
 		storageLayer: storageLayer,
 		logger:       logger,
-	}, nil
+		started:      time.Now(),
+	}
+
+	for _, option := range options {
+		option(database)
+	}
+
+	return database, nil
 }
 
-// HandleQuery -- выполняет запрос от клиента
+// HandleQuery -- выполняет запрос от клиента, идентификаторы запроса и
+// соединения берутся из контекста, который создал серверный слой
 func (d *Database) HandleQuery(ctx context.Context, queryStr string) string {
-	d.logger.Debug("handling query", zap.String("query", queryStr))
+	d.logger.Debug("handling query", common.LogFields(ctx, zap.String("query", queryStr))...)
+	if err := ctx.Err(); err != nil {
+		return fmt.Sprintf("[error] %s", err.Error())
+	}
+
+	if d.tracer != nil {
+		ctx = tracing.ContextWithTracer(ctx, d.tracer)
+	}
+
+	started := time.Now()
+	ctx, span := tracing.StartSpan(ctx, "query")
+	if requestID := common.GetRequestIDFromContext(ctx); requestID != "" {
+		span.SetAttribute("request_id", requestID)
+	}
+
+	_, parseSpan := tracing.StartSpan(ctx, "parse")
 	query, err := d.computeLayer.Parse(queryStr)
+	parseSpan.End(err)
 	if err != nil {
-		return fmt.Sprintf("[error] %s", err.Error())
+		response := fmt.Sprintf("[error] %s", err.Error())
+		d.observe(ctx, span, query, response, started)
+		return response
+	}
+
+	response := d.executeQuery(ctx, query)
+	d.observe(ctx, span, query, response, started)
+	return response
+}
+
+// observe -- учитывает выполненный запрос в метриках, журнале медленных запросов
+// и завершает спан запроса
+func (d *Database) observe(ctx context.Context, span tracing.Span, query compute.Query, response string, started time.Time) {
+	duration := time.Since(started)
+	span.SetAttribute("command", compute.CommandName(query.CommandID()))
+	var err error
+	if message, failed := strings.CutPrefix(response, "[error] "); failed {
+		err = errors.New(message)
+	}
+	span.End(err)
+
+	d.metrics.observe(query.CommandID(), response, duration)
+	d.slowLog.Record(slowlog.Entry{
+		Timestamp: started,
+		Duration:  duration,
+		Command:   compute.CommandName(query.CommandID()),
+		Key:       query.GetKey(),
+		Client:    common.GetClientAddressFromContext(ctx),
+		RequestID: common.GetRequestIDFromContext(ctx),
+	})
+}
+
+func (d *Database) executeQuery(ctx context.Context, query compute.Query) string {
+	if compute.IsAdminCommand(query.CommandID()) && !d.adminCommands {
+		return adminDisabledResponse
 	}
+
 	switch query.CommandID() {
 	case compute.DelCommandID:
 		return d.handleDelQuery(ctx, query)
@@ -62,10 +197,22 @@ func (d *Database) HandleQuery(ctx context.Context, queryStr string) string {
 		return d.handleGetQuery(ctx, query)
 	case compute.SetCommandID:
 		return d.handleSetQuery(ctx, query)
+	case compute.PingCommandID:
+		return "[ok] PONG"
+	case compute.EchoCommandID:
+		return fmt.Sprintf("[ok] %s", query.GetKey())
+	case compute.DBSizeCommandID:
+		return fmt.Sprintf("[ok] %d", d.storageLayer.Size())
+	case compute.InfoCommandID:
+		return d.handleInfoQuery(ctx)
+	case compute.FlushAllCommandID:
+		return d.handleFlushAllQuery(ctx)
+	case compute.SlowLogCommandID:
+		return d.handleSlowLogQuery(query)
 	}
 	d.logger.Error(
 		"compute layer is incorrect",
-		zap.Int("command_id", query.CommandID()),
+		common.LogFields(ctx, zap.Int("command_id", query.CommandID()))...,
 	)
 
 	return "[error] internal error"
@@ -96,3 +243,93 @@ func (d *Database) handleDelQuery(ctx context.Context, query compute.Query) stri
 
 	return "[ok]"
 }
+
+func (d *Database) handleFlushAllQuery(ctx context.Context) string {
+	if err := d.storageLayer.FlushAll(ctx); err != nil {
+		return fmt.Sprintf("[error] %s", err.Error())
+	}
+
+	d.logger.Warn("all keys were deleted by FLUSHALL", common.LogFields(ctx)...)
+	return "[ok]"
+}
+
+// handleInfoQuery -- ответ должен уместиться в одну строку протокола,
+// поэтому поля разделяются пробелами в виде name:value
+func (d *Database) handleInfoQuery(ctx context.Context) string {
+	var segments int
+	var walSize int64
+	if d.walDirectory != "" {
+		var err error
+		segments, walSize, err = filesystem.SegmentsStat(d.walDirectory)
+		if err != nil {
+			d.logger.Warn("failed to collect WAL statistics", common.LogFields(ctx, zap.Error(err))...)
+		}
+	}
+
+	var connections int64
+	if d.connections != nil {
+		connections = d.connections.Load()
+	}
+
+	return fmt.Sprintf(
+		"[ok] uptime_seconds:%d keys:%d used_memory:%d wal_segments:%d wal_size:%d last_lsn:%d connections:%d",
+		int64(time.Since(d.started).Seconds()),
+		d.storageLayer.Size(),
+		d.storageLayer.MemoryUsage(),
+		segments,
+		walSize,
+		d.storageLayer.LastLSN(),
+		connections,
+	)
+}
+
+// handleSlowLogQuery -- записи выводятся одной строкой от новых к старым,
+// поля записи в виде name:value, записи разделяются "; "
+func (d *Database) handleSlowLogQuery(query compute.Query) string {
+	if d.slowLog == nil {
+		return slowLogDisabledResponse
+	}
+
+	switch strings.ToUpper(query.GetKey()) {
+	case "GET":
+		var count int
+		if query.GetValue() != "" {
+			var err error
+			if count, err = strconv.Atoi(query.GetValue()); err != nil || count <= 0 {
+				return "[error] invalid arguments"
+			}
+		}
+
+		entries := d.slowLog.Get(count)
+		if len(entries) == 0 {
+			return "[ok]"
+		}
+
+		formatted := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			formatted = append(formatted, fmt.Sprintf(
+				"id:%d timestamp:%d duration_us:%d command:%s key:%s client:%s",
+				entry.ID,
+				entry.Timestamp.Unix(),
+				entry.Duration.Microseconds(),
+				entry.Command,
+				entry.Key,
+				entry.Client,
+			))
+		}
+		return "[ok] " + strings.Join(formatted, "; ")
+	case "LEN":
+		if query.GetValue() != "" {
+			return "[error] invalid arguments"
+		}
+		return fmt.Sprintf("[ok] %d", d.slowLog.Len())
+	case "RESET":
+		if query.GetValue() != "" {
+			return "[error] invalid arguments"
+		}
+		d.slowLog.Reset()
+		return "[ok]"
+	}
+
+	return "[error] invalid arguments"
+}
@@ -94,6 +94,20 @@ func (mr *MockstorageLayerMockRecorder) Del(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Del", reflect.TypeOf((*MockstorageLayer)(nil).Del), arg0, arg1)
 }
 
+// FlushAll mocks base method.
+func (m *MockstorageLayer) FlushAll(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlushAll", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FlushAll indicates an expected call of FlushAll.
+func (mr *MockstorageLayerMockRecorder) FlushAll(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushAll", reflect.TypeOf((*MockstorageLayer)(nil).FlushAll), arg0)
+}
+
 // Get mocks base method.
 func (m *MockstorageLayer) Get(arg0 context.Context, arg1 string) (string, error) {
 	m.ctrl.T.Helper()
@@ -109,6 +123,34 @@ func (mr *MockstorageLayerMockRecorder) Get(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockstorageLayer)(nil).Get), arg0, arg1)
 }
 
+// LastLSN mocks base method.
+func (m *MockstorageLayer) LastLSN() int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastLSN")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// LastLSN indicates an expected call of LastLSN.
+func (mr *MockstorageLayerMockRecorder) LastLSN() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastLSN", reflect.TypeOf((*MockstorageLayer)(nil).LastLSN))
+}
+
+// MemoryUsage mocks base method.
+func (m *MockstorageLayer) MemoryUsage() int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MemoryUsage")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// MemoryUsage indicates an expected call of MemoryUsage.
+func (mr *MockstorageLayerMockRecorder) MemoryUsage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MemoryUsage", reflect.TypeOf((*MockstorageLayer)(nil).MemoryUsage))
+}
+
 // Set mocks base method.
 func (m *MockstorageLayer) Set(arg0 context.Context, arg1, arg2 string) error {
 	m.ctrl.T.Helper()
@@ -122,3 +164,17 @@ func (mr *MockstorageLayerMockRecorder) Set(arg0, arg1, arg2 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockstorageLayer)(nil).Set), arg0, arg1, arg2)
 }
+
+// Size mocks base method.
+func (m *MockstorageLayer) Size() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Size")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// Size indicates an expected call of Size.
+func (mr *MockstorageLayerMockRecorder) Size() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Size", reflect.TypeOf((*MockstorageLayer)(nil).Size))
+}
@@ -3,16 +3,24 @@ package database
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 
+	"kava/internal/common"
 	"kava/internal/database/compute"
+	"kava/internal/database/slowlog"
 	"kava/internal/database/storage"
+	"kava/internal/metrics"
+	"kava/internal/tracing"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 // mockgen -source=database.go -destination=database_mock.go -package=database
@@ -251,4 +259,272 @@ func TestHandleQuery(t *testing.T) {
 			assert.Equal(t, test.expectedResponse, response)
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestHandleQueryWithExpiredContext(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	database, err := NewDatabase(NewMockcomputeLayer(ctrl), NewMockstorageLayer(ctrl), zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	response := database.HandleQuery(ctx, "GET key")
+	assert.Equal(t, "[error] context deadline exceeded", response)
+}
+
+func TestHandleAdminQuery(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+
+	tests := map[string]struct {
+		query        compute.Query
+		adminEnabled bool
+		storageLayer func() storageLayer
+
+		expectedResponse string
+	}{
+		"handle ping query": {
+			query:            compute.NewQuery(compute.PingCommandID, "", ""),
+			storageLayer:     func() storageLayer { return NewMockstorageLayer(ctrl) },
+			expectedResponse: "[ok] PONG",
+		},
+		"handle echo query": {
+			query:            compute.NewQuery(compute.EchoCommandID, "hello", ""),
+			storageLayer:     func() storageLayer { return NewMockstorageLayer(ctrl) },
+			expectedResponse: "[ok] hello",
+		},
+		"handle dbsize query with disabled admin commands": {
+			query:            compute.NewQuery(compute.DBSizeCommandID, "", ""),
+			storageLayer:     func() storageLayer { return NewMockstorageLayer(ctrl) },
+			expectedResponse: "[error] admin commands are disabled",
+		},
+		"handle dbsize query": {
+			query:        compute.NewQuery(compute.DBSizeCommandID, "", ""),
+			adminEnabled: true,
+			storageLayer: func() storageLayer {
+				storageLayer := NewMockstorageLayer(ctrl)
+				storageLayer.EXPECT().
+					Size().
+					Return(3)
+				return storageLayer
+			},
+			expectedResponse: "[ok] 3",
+		},
+		"handle flushall query with error from storage": {
+			query:        compute.NewQuery(compute.FlushAllCommandID, "", ""),
+			adminEnabled: true,
+			storageLayer: func() storageLayer {
+				storageLayer := NewMockstorageLayer(ctrl)
+				storageLayer.EXPECT().
+					FlushAll(gomock.Any()).
+					Return(storage.ErrorReadOnly)
+				return storageLayer
+			},
+			expectedResponse: "[error] storage is read-only",
+		},
+		"handle flushall query": {
+			query:        compute.NewQuery(compute.FlushAllCommandID, "", ""),
+			adminEnabled: true,
+			storageLayer: func() storageLayer {
+				storageLayer := NewMockstorageLayer(ctrl)
+				storageLayer.EXPECT().
+					FlushAll(gomock.Any()).
+					Return(nil)
+				return storageLayer
+			},
+			expectedResponse: "[ok]",
+		},
+		"handle info query": {
+			query:        compute.NewQuery(compute.InfoCommandID, "", ""),
+			adminEnabled: true,
+			storageLayer: func() storageLayer {
+				storageLayer := NewMockstorageLayer(ctrl)
+				storageLayer.EXPECT().Size().Return(2)
+				storageLayer.EXPECT().MemoryUsage().Return(int64(150))
+				storageLayer.EXPECT().LastLSN().Return(int64(17))
+				return storageLayer
+			},
+			expectedResponse: "[ok] uptime_seconds:0 keys:2 used_memory:150 wal_segments:4 wal_size:0 last_lsn:17 connections:5",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			computeLayer := NewMockcomputeLayer(ctrl)
+			computeLayer.EXPECT().
+				Parse("QUERY").
+				Return(test.query, nil)
+
+			// пустые сегменты, чтобы размер WAL не зависел от окружения
+			directory := t.TempDir()
+			for _, name := range []string{"wal_0.log", "wal_1.log", "wal_2.log", "wal_3.log"} {
+				file, err := os.Create(filepath.Join(directory, name))
+				require.NoError(t, err)
+				require.NoError(t, file.Close())
+			}
+
+			connections := new(atomic.Int64)
+			connections.Store(5)
+
+			database, err := NewDatabase(
+				computeLayer,
+				test.storageLayer(),
+				zap.NewNop(),
+				WithAdminCommands(test.adminEnabled),
+				WithWALDirectory(directory),
+				WithConnectionsCounter(connections),
+			)
+			require.NoError(t, err)
+
+			response := database.HandleQuery(context.Background(), "QUERY")
+			assert.Equal(t, test.expectedResponse, response)
+		})
+	}
+}
+
+func TestHandleQueryMetrics(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	computeLayer := NewMockcomputeLayer(ctrl)
+	computeLayer.EXPECT().
+		Parse("GET key").
+		Return(compute.NewQuery(compute.GetCommandID, "key", ""), nil).
+		Times(2)
+	computeLayer.EXPECT().
+		Parse("TRUNCATE").
+		Return(compute.Query{}, errors.New("compute error"))
+
+	storageLayer := NewMockstorageLayer(ctrl)
+	gomock.InOrder(
+		storageLayer.EXPECT().Get(gomock.Any(), "key").Return("value", nil),
+		storageLayer.EXPECT().Get(gomock.Any(), "key").Return("", storage.ErrorNotExist),
+	)
+
+	registry := metrics.NewRegistry()
+	database, err := NewDatabase(computeLayer, storageLayer, zap.NewNop(), WithMetrics(registry))
+	require.NoError(t, err)
+
+	database.HandleQuery(context.Background(), "GET key")
+	database.HandleQuery(context.Background(), "GET key")
+	database.HandleQuery(context.Background(), "TRUNCATE")
+
+	queries := registry.NewCounterVec("kava_queries_total", "", "command", "status")
+	assert.Equal(t, 1.0, queries.WithLabelValues("GET", "ok").Value())
+	assert.Equal(t, 1.0, queries.WithLabelValues("GET", "error").Value())
+	assert.Equal(t, 1.0, queries.WithLabelValues("UNKNOWN", "error").Value())
+
+	duration := registry.NewHistogramVec("kava_query_duration_seconds", "", nil, "command")
+	assert.Equal(t, uint64(2), duration.WithLabelValues("GET").Count())
+}
+
+func TestHandleQueryTracing(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	computeLayer := NewMockcomputeLayer(ctrl)
+	computeLayer.EXPECT().
+		Parse("GET key").
+		Return(compute.NewQuery(compute.GetCommandID, "key", ""), nil)
+	computeLayer.EXPECT().
+		Parse("TRUNCATE").
+		Return(compute.Query{}, errors.New("compute error"))
+
+	storageLayer := NewMockstorageLayer(ctrl)
+	storageLayer.EXPECT().
+		Get(gomock.Any(), "key").
+		DoAndReturn(func(ctx context.Context, _ string) (string, error) {
+			// нижние слои получают идентификаторы запроса и трейсер из контекста
+			assert.Equal(t, "request", common.GetRequestIDFromContext(ctx))
+			assert.Equal(t, "connection", common.GetConnectionIDFromContext(ctx))
+			return "value", nil
+		})
+
+	core, logs := observer.New(zap.DebugLevel)
+	tracer := tracing.NewLogTracer(zap.New(core))
+	database, err := NewDatabase(computeLayer, storageLayer, zap.NewNop(), WithTracer(tracer))
+	require.NoError(t, err)
+
+	ctx := common.ContextWithConnectionID(context.Background(), "connection")
+	ctx = common.ContextWithRequestID(ctx, "request")
+	assert.Equal(t, "[ok] value", database.HandleQuery(ctx, "GET key"))
+	assert.Equal(t, "[error] compute error", database.HandleQuery(ctx, "TRUNCATE"))
+
+	spans := logs.FilterMessage("span finished").All()
+	require.Len(t, spans, 4)
+
+	assert.Equal(t, "parse", spans[0].ContextMap()["span"])
+	assert.Equal(t, "request", spans[0].ContextMap()["request_id"])
+
+	query := spans[1].ContextMap()
+	assert.Equal(t, "query", query["span"])
+	assert.Equal(t, "GET", query["command"])
+	assert.Equal(t, "request", query["request_id"])
+	assert.Equal(t, "connection", query["connection_id"])
+	assert.NotContains(t, query, "error")
+
+	assert.Equal(t, "compute error", spans[2].ContextMap()["error"])
+	assert.Equal(t, "UNKNOWN", spans[3].ContextMap()["command"])
+	assert.Equal(t, "compute error", spans[3].ContextMap()["error"])
+}
+
+func TestHandleSlowLogQuery(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	computeLayer, err := compute.NewCompute(zap.NewNop())
+	require.NoError(t, err)
+
+	storageLayer := NewMockstorageLayer(ctrl)
+	storageLayer.EXPECT().
+		Get(gomock.Any(), "key").
+		Return("value", nil).
+		Times(2)
+
+	slowLog, err := slowlog.NewSlowLog(0, 10, nil)
+	require.NoError(t, err)
+
+	database, err := NewDatabase(computeLayer, storageLayer, zap.NewNop(), WithAdminCommands(true), WithSlowLog(slowLog))
+	require.NoError(t, err)
+
+	ctx := common.ContextWithClientAddress(context.Background(), "127.0.0.1:5000")
+	database.HandleQuery(ctx, "GET key")
+	database.HandleQuery(ctx, "GET key")
+
+	entries := slowLog.Get(0)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "GET", entries[0].Command)
+	assert.Equal(t, "key", entries[0].Key)
+	assert.Equal(t, "127.0.0.1:5000", entries[0].Client)
+
+	response := database.HandleQuery(ctx, "SLOWLOG GET 1")
+	assert.Regexp(t, `^\[ok\] id:2 timestamp:\d+ duration_us:\d+ command:GET key:key client:127.0.0.1:5000$`, response)
+
+	// SLOWLOG GET тоже попадает в журнал
+	assert.Equal(t, "[ok] 3", database.HandleQuery(ctx, "SLOWLOG LEN"))
+	assert.Equal(t, "[error] invalid arguments", database.HandleQuery(ctx, "SLOWLOG GET zero"))
+	assert.Equal(t, "[error] invalid arguments", database.HandleQuery(ctx, "SLOWLOG RESET 1"))
+	assert.Equal(t, "[error] invalid arguments", database.HandleQuery(ctx, "SLOWLOG CLEAR"))
+
+	assert.Equal(t, "[ok]", database.HandleQuery(ctx, "SLOWLOG RESET"))
+	assert.Equal(t, 1, slowLog.Len())
+}
+
+func TestHandleSlowLogQueryWithoutSlowLog(t *testing.T) {
+	t.Parallel()
+
+	computeLayer, err := compute.NewCompute(zap.NewNop())
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	database, err := NewDatabase(computeLayer, NewMockstorageLayer(ctrl), zap.NewNop(), WithAdminCommands(true))
+	require.NoError(t, err)
+
+	assert.Equal(t, "[error] slow log is disabled", database.HandleQuery(context.Background(), "SLOWLOG GET"))
+}
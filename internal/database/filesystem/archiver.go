@@ -0,0 +1,183 @@
+package filesystem
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// CompressedSuffix -- расширение сжатых архиватором сегментов
+const CompressedSuffix = ".gz"
+
+type RetentionPolicy struct {
+	// MaxTotalSize limits the total size of segments in the directory
+	MaxTotalSize int64
+	// MaxAge limits the time since the last write to a segment
+	MaxAge time.Duration
+	// KeepSegments is the count of the newest segments which are never retired
+	KeepSegments int
+}
+
+type SegmentsArchiver struct {
+	directory        string
+	archiveDirectory string
+	compress         bool
+
+	policy        RetentionPolicy
+	checkInterval time.Duration
+	logger        *zap.Logger
+}
+
+func NewSegmentsArchiver(
+	directory string,
+	archiveDirectory string,
+	compress bool,
+	policy RetentionPolicy,
+	checkInterval time.Duration,
+	logger *zap.Logger,
+) (*SegmentsArchiver, error) {
+	if archiveDirectory == "" {
+		return nil, errors.New("archive directory is invalid")
+	}
+	if checkInterval <= 0 {
+		return nil, errors.New("check interval is invalid")
+	}
+	if logger == nil {
+		return nil, errors.New("logger is invalid")
+	}
+
+	// the last segment is always active, it can't be retired
+	policy.KeepSegments = max(policy.KeepSegments, 1)
+
+	return &SegmentsArchiver{
+		directory:        directory,
+		archiveDirectory: archiveDirectory,
+		compress:         compress,
+		policy:           policy,
+		checkInterval:    checkInterval,
+		logger:           logger,
+	}, nil
+}
+
+func (a *SegmentsArchiver) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(a.checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := a.Archive(); err != nil {
+					a.logger.Warn("failed to archive WAL segments", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// Archive moves all segments retired by the retention policy to the archive
+// directory, recovery reads them from there (see WithArchiveDirectory)
+func (a *SegmentsArchiver) Archive() error {
+	segments, err := a.retiredSegments()
+	if err != nil {
+		return err
+	}
+
+	if len(segments) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(a.archiveDirectory, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	for _, segmentName := range segments {
+		if err := a.archiveSegment(segmentName); err != nil {
+			return fmt.Errorf("failed to archive segment %s: %w", segmentName, err)
+		}
+
+		a.logger.Info("WAL segment archived", zap.String("segment", segmentName))
+	}
+
+	return nil
+}
+
+func (a *SegmentsArchiver) retiredSegments() ([]string, error) {
+	segments, err := SegmentNames(a.directory)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]os.FileInfo, 0, len(segments))
+	var totalSize int64
+	for _, segmentName := range segments {
+		info, err := os.Stat(fmt.Sprintf("%s/%s", a.directory, segmentName))
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, info)
+		totalSize += info.Size()
+	}
+
+	var retired []string
+	for idx := 0; idx < len(segments)-a.policy.KeepSegments; idx++ {
+		tooBig := a.policy.MaxTotalSize > 0 && totalSize > a.policy.MaxTotalSize
+		tooOld := a.policy.MaxAge > 0 && time.Since(infos[idx].ModTime()) > a.policy.MaxAge
+		if !tooBig && !tooOld {
+			continue
+		}
+
+		retired = append(retired, segments[idx])
+		totalSize -= infos[idx].Size()
+	}
+
+	return retired, nil
+}
+
+func (a *SegmentsArchiver) archiveSegment(segmentName string) error {
+	source := fmt.Sprintf("%s/%s", a.directory, segmentName)
+	destination := fmt.Sprintf("%s/%s", a.archiveDirectory, segmentName)
+	if !a.compress {
+		return os.Rename(source, destination)
+	}
+
+	if err := compressFile(source, destination+CompressedSuffix); err != nil {
+		return err
+	}
+
+	return os.Remove(source)
+}
+
+func compressFile(source, destination string) error {
+	input, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	output, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	writer := gzip.NewWriter(output)
+	if _, err := io.Copy(writer, input); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return output.Sync()
+}
@@ -0,0 +1,154 @@
+package filesystem
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewSegmentsArchiver(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		archiveDirectory string
+		checkInterval    time.Duration
+		logger           *zap.Logger
+
+		expectedErr    string
+		expectedNilObj bool
+	}{
+		"create archiver without archive directory": {
+			checkInterval:  time.Second,
+			logger:         zap.NewNop(),
+			expectedErr:    "archive directory is invalid",
+			expectedNilObj: true,
+		},
+		"create archiver without check interval": {
+			archiveDirectory: "archive",
+			logger:           zap.NewNop(),
+			expectedErr:      "check interval is invalid",
+			expectedNilObj:   true,
+		},
+		"create archiver without logger": {
+			archiveDirectory: "archive",
+			checkInterval:    time.Second,
+			expectedErr:      "logger is invalid",
+			expectedNilObj:   true,
+		},
+		"create archiver": {
+			archiveDirectory: "archive",
+			checkInterval:    time.Second,
+			logger:           zap.NewNop(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			archiver, err := NewSegmentsArchiver("data", test.archiveDirectory, false, RetentionPolicy{}, test.checkInterval, test.logger)
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if test.expectedNilObj {
+				assert.Nil(t, archiver)
+			} else {
+				assert.NotNil(t, archiver)
+			}
+		})
+	}
+}
+
+func createSegments(t *testing.T, directory string, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		err := os.WriteFile(directory+"/"+name, []byte("aaaaa"), 0644)
+		require.NoError(t, err)
+	}
+}
+
+func TestSegmentsArchiverBySize(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	archiveDirectory := directory + "/archive"
+	createSegments(t, directory, "wal_1000.log", "wal_2000.log", "wal_3000.log", "wal_4000.log")
+
+	policy := RetentionPolicy{MaxTotalSize: 12}
+	archiver, err := NewSegmentsArchiver(directory, archiveDirectory, false, policy, time.Second, zap.NewNop())
+	require.NoError(t, err)
+
+	err = archiver.Archive()
+	require.NoError(t, err)
+
+	segments, err := SegmentNames(directory)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wal_3000.log", "wal_4000.log"}, segments)
+
+	archived, err := SegmentNames(archiveDirectory)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wal_1000.log", "wal_2000.log"}, archived)
+}
+
+func TestSegmentsArchiverByAge(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	archiveDirectory := directory + "/archive"
+	createSegments(t, directory, "wal_1000.log", "wal_2000.log", "wal_3000.log")
+
+	old := time.Now().Add(-time.Hour)
+	for _, name := range []string{"wal_1000.log", "wal_2000.log", "wal_3000.log"} {
+		err := os.Chtimes(directory+"/"+name, old, old)
+		require.NoError(t, err)
+	}
+
+	policy := RetentionPolicy{MaxAge: time.Minute, KeepSegments: 2}
+	archiver, err := NewSegmentsArchiver(directory, archiveDirectory, true, policy, time.Second, zap.NewNop())
+	require.NoError(t, err)
+
+	err = archiver.Archive()
+	require.NoError(t, err)
+
+	segments, err := SegmentNames(directory)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wal_2000.log", "wal_3000.log"}, segments)
+
+	file, err := os.Open(archiveDirectory + "/wal_1000.log.gz")
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaa", string(data))
+}
+
+func TestSegmentsArchiverKeepsLastSegment(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	createSegments(t, directory, "wal_1000.log")
+
+	policy := RetentionPolicy{MaxTotalSize: 1}
+	archiver, err := NewSegmentsArchiver(directory, directory+"/archive", false, policy, time.Second, zap.NewNop())
+	require.NoError(t, err)
+
+	err = archiver.Archive()
+	require.NoError(t, err)
+
+	segments, err := SegmentNames(directory)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wal_1000.log"}, segments)
+}
@@ -14,23 +14,40 @@ type Segment struct {
 
 	segmentSize    int
 	maxSegmentSize int
+
+	validateTail func([]byte) int
+	observeSync  func(time.Duration)
 }
 
-func NewSegment(directory string, maxSegmentSize int) *Segment {
+// NewSegment creates a segment writer; validateTail returns the length of
+// the valid prefix of the last segment data and may be nil
+func NewSegment(directory string, maxSegmentSize int, validateTail func([]byte) int) *Segment {
 	return &Segment{
 		directory:      directory,
 		maxSegmentSize: maxSegmentSize,
+		validateTail:   validateTail,
 	}
 }
 
+// ObserveSync sets a callback that receives the duration of every fsync
+func (s *Segment) ObserveSync(observe func(time.Duration)) {
+	s.observeSync = observe
+}
+
 func (s *Segment) Write(data []byte) error {
+	if s.file == nil {
+		if err := s.openLastSegment(); err != nil {
+			return fmt.Errorf("failed to open last segment file: %w", err)
+		}
+	}
+
 	if s.file == nil || s.segmentSize >= s.maxSegmentSize {
 		if err := s.rotateSegment(); err != nil {
 			return fmt.Errorf("failed to rotate segment file: %w", err)
 		}
 	}
 
-	writtenBytes, err := WriteFile(s.file, data)
+	writtenBytes, err := WriteFile(s.file, data, s.observeSync)
 	if err != nil {
 		return fmt.Errorf("failed to write data to segment file: %w", err)
 	}
@@ -39,6 +56,54 @@ func (s *Segment) Write(data []byte) error {
 	return nil
 }
 
+func (s *Segment) Close() error {
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *Segment) openLastSegment() error {
+	segmentName, err := SegmentLast(s.directory)
+	if err != nil || segmentName == "" {
+		return err
+	}
+
+	filename := fmt.Sprintf("%s/%s", s.directory, segmentName)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	validSize := len(data)
+	if s.validateTail != nil {
+		validSize = s.validateTail(data)
+	}
+
+	if validSize < len(data) {
+		// torn write of the previous run, the tail can't be replayed anyway
+		if err := os.Truncate(filename, int64(validSize)); err != nil {
+			return err
+		}
+	}
+
+	if validSize >= s.maxSegmentSize {
+		return nil
+	}
+
+	file, err := AppendFile(filename)
+	if err != nil {
+		return err
+	}
+
+	s.file = file
+	s.segmentSize = validSize
+	return nil
+}
+
 func (s *Segment) rotateSegment() error {
 	segmentName := fmt.Sprintf("%s/wal_%d.log", s.directory, now().UnixMilli())
 	file, err := CreateFile(segmentName)
@@ -46,6 +111,10 @@ func (s *Segment) rotateSegment() error {
 		return err
 	}
 
+	if s.file != nil {
+		_ = s.file.Close()
+	}
+
 	s.file = file
 	s.segmentSize = 0
 	return nil
@@ -1,25 +1,99 @@
 package filesystem
 
 import (
+	"compress/gzip"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 )
 
 type SegmentsDirectory struct {
-	directory string
+	directory        string
+	archiveDirectory string
 }
 
-func NewSegmentsDirectory(directory string) *SegmentsDirectory {
-	return &SegmentsDirectory{
+// SegmentsDirectoryOption -- необязательная настройка чтения сегментов
+type SegmentsDirectoryOption func(*SegmentsDirectory)
+
+// WithArchiveDirectory -- сегменты, перенесенные архиватором, читаются
+// вместе с сегментами каталога, сжатые сегменты распаковываются
+func WithArchiveDirectory(archiveDirectory string) SegmentsDirectoryOption {
+	return func(d *SegmentsDirectory) {
+		d.archiveDirectory = archiveDirectory
+	}
+}
+
+func NewSegmentsDirectory(directory string, options ...SegmentsDirectoryOption) *SegmentsDirectory {
+	d := &SegmentsDirectory{
 		directory: directory,
 	}
+
+	for _, option := range options {
+		option(d)
+	}
+
+	return d
 }
 
 func (d *SegmentsDirectory) ForEach(action func([]byte) error) error {
+	return d.Walk(func(_ string, data []byte) error {
+		return action(data)
+	})
+}
+
+// Walk -- как ForEach, но передает и путь сегмента в каталоге или в архиве,
+// данные сжатого сегмента передаются распакованными
+func (d *SegmentsDirectory) Walk(action func(path string, data []byte) error) error {
+	segments, err := d.segments()
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range segments {
+		data, err := readSegmentFile(filename)
+		if err != nil {
+			return err
+		}
+
+		if err := action(filename, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// segments -- пути сегментов архива и каталога в порядке имен. Сегмент
+// каталога важнее копии в архиве: архиватор удаляет его только после
+// записи копии, и копия может быть неполной
+func (d *SegmentsDirectory) segments() ([]string, error) {
+	paths := make(map[string]string)
+	if d.archiveDirectory != "" {
+		files, err := os.ReadDir(d.archiveDirectory)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to scan archive directory: %w", err)
+		}
+
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+
+			name := strings.TrimSuffix(file.Name(), CompressedSuffix)
+			paths[name] = filepath.Join(d.archiveDirectory, file.Name())
+		}
+	}
+
 	files, err := os.ReadDir(d.directory)
 	if err != nil {
 		// TODO: need to create a directory if it is missing
-		return fmt.Errorf("failed to scan directory with segments: %w", err)
+		return nil, fmt.Errorf("failed to scan directory with segments: %w", err)
 	}
 
 	for _, file := range files {
@@ -27,16 +101,38 @@ func (d *SegmentsDirectory) ForEach(action func([]byte) error) error {
 			continue
 		}
 
-		filename := fmt.Sprintf("%s/%s", d.directory, file.Name())
-		data, err := os.ReadFile(filename)
-		if err != nil {
-			return err
-		}
+		paths[file.Name()] = filepath.Join(d.directory, file.Name())
+	}
 
-		if err := action(data); err != nil {
-			return err
-		}
+	segments := make([]string, 0, len(paths))
+	for _, name := range slices.Sorted(maps.Keys(paths)) {
+		segments = append(segments, paths[name])
 	}
 
-	return nil
-}
\ No newline at end of file
+	return segments, nil
+}
+
+func readSegmentFile(filename string) ([]byte, error) {
+	if !strings.HasSuffix(filename, CompressedSuffix) {
+		return os.ReadFile(filename)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress segment %s: %w", filename, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress segment %s: %w", filename, err)
+	}
+
+	return data, nil
+}
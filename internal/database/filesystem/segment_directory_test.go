@@ -2,6 +2,8 @@ package filesystem
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,7 +16,7 @@ func TestSegmentsDirectoryForEach(t *testing.T) {
 	segmentsCount := 0
 	expectedSegmentsCount := 3
 
-	directory := NewSegmentsDirectory("test_data")
+	directory := NewSegmentsDirectory(newTestSegments(t))
 	err := directory.ForEach(func(data []byte) error {
 		assert.True(t, len(data) != 0)
 		segmentsCount++
@@ -28,10 +30,55 @@ func TestSegmentsDirectoryForEach(t *testing.T) {
 func TestSegmentsDirectoryForEachWithBreak(t *testing.T) {
 	t.Parallel()
 
-	directory := NewSegmentsDirectory("test_data")
+	directory := NewSegmentsDirectory(newTestSegments(t))
 	err := directory.ForEach(func([]byte) error {
 		return errors.New("error")
 	})
 
 	assert.Error(t, err, "error")
 }
+
+func TestSegmentsDirectoryForEachWithArchive(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	archiveDirectory := filepath.Join(directory, "archive")
+	require.NoError(t, os.Mkdir(archiveDirectory, 0700))
+
+	write := func(filename, data string) {
+		require.NoError(t, os.WriteFile(filename, []byte(data), 0600))
+	}
+
+	write(filepath.Join(archiveDirectory, "wal_1000.log"), "archived")
+	write(filepath.Join(directory, "wal_2000.log"), "compressed")
+	require.NoError(t, compressFile(filepath.Join(directory, "wal_2000.log"), filepath.Join(archiveDirectory, "wal_2000.log.gz")))
+	require.NoError(t, os.Remove(filepath.Join(directory, "wal_2000.log")))
+	// архиватор остановился до удаления сегмента, копия в архиве неполная
+	write(filepath.Join(archiveDirectory, "wal_3000.log.gz"), "broken")
+	write(filepath.Join(directory, "wal_3000.log"), "active")
+
+	var segments []string
+	directoryReader := NewSegmentsDirectory(directory, WithArchiveDirectory(archiveDirectory))
+	err := directoryReader.ForEach(func(data []byte) error {
+		segments = append(segments, string(data))
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"archived", "compressed", "active"}, segments)
+}
+
+func TestSegmentsDirectoryForEachWithMissingArchive(t *testing.T) {
+	t.Parallel()
+
+	directory := newTestSegments(t)
+	segmentsCount := 0
+	directoryReader := NewSegmentsDirectory(directory, WithArchiveDirectory(filepath.Join(directory, "missing")))
+	err := directoryReader.ForEach(func([]byte) error {
+		segmentsCount++
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, segmentsCount)
+}
@@ -22,7 +22,7 @@ func TestSegmentWrite(t *testing.T) {
 	}()
 
 	const maxSegmentSize = 10
-	segment := NewSegment(testWALDirectory, maxSegmentSize)
+	segment := NewSegment(testWALDirectory, maxSegmentSize, nil)
 
 	now = func() time.Time {
 		return time.Unix(1, 0)
@@ -47,4 +47,58 @@ func TestSegmentWrite(t *testing.T) {
 	stat, err = os.Stat(testWALDirectory + "/wal_2000.log")
 	require.NoError(t, err)
 	assert.Equal(t, int64(5), stat.Size())
-}
\ No newline at end of file
+}
+
+func TestSegmentWriteToLastSegment(t *testing.T) {
+	t.Parallel()
+
+	const testWALDirectory = "temp_test_data_last"
+	err := os.Mkdir(testWALDirectory, os.ModePerm)
+	require.NoError(t, err)
+
+	defer func() {
+		err := os.RemoveAll(testWALDirectory)
+		require.NoError(t, err)
+	}()
+
+	err = os.WriteFile(testWALDirectory+"/wal_500.log", []byte("aaaaa"), 0644)
+	require.NoError(t, err)
+
+	const maxSegmentSize = 10
+	segment := NewSegment(testWALDirectory, maxSegmentSize, nil)
+
+	err = segment.Write([]byte("bbbbb"))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(testWALDirectory + "/wal_500.log")
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaabbbbb", string(data))
+}
+
+func TestSegmentWriteToLastSegmentWithBrokenTail(t *testing.T) {
+	t.Parallel()
+
+	const testWALDirectory = "temp_test_data_tail"
+	err := os.Mkdir(testWALDirectory, os.ModePerm)
+	require.NoError(t, err)
+
+	defer func() {
+		err := os.RemoveAll(testWALDirectory)
+		require.NoError(t, err)
+	}()
+
+	err = os.WriteFile(testWALDirectory+"/wal_500.log", []byte("aaaaaxx"), 0644)
+	require.NoError(t, err)
+
+	const maxSegmentSize = 10
+	segment := NewSegment(testWALDirectory, maxSegmentSize, func(data []byte) int {
+		return 5
+	})
+
+	err = segment.Write([]byte("bbbbb"))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(testWALDirectory + "/wal_500.log")
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaabbbbb", string(data))
+}
@@ -3,6 +3,7 @@ package filesystem
 import (
 	"fmt"
 	"os"
+	"time"
 )
 
 func SegmentNext(directory string, segmentName string) (string, error) {
@@ -48,6 +49,50 @@ func SegmentLast(directory string) (string, error) {
 	return filename, nil
 }
 
+func SegmentNames(directory string) ([]string, error) {
+	files, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan WAL directory: %w", err)
+	}
+
+	filenames := make([]string, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		filenames = append(filenames, file.Name())
+	}
+
+	return filenames, nil
+}
+
+// SegmentsStat -- количество сегментов в каталоге и их суммарный размер
+func SegmentsStat(directory string) (int, int64, error) {
+	files, err := os.ReadDir(directory)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to scan WAL directory: %w", err)
+	}
+
+	var count int
+	var size int64
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			return 0, 0, err
+		}
+
+		count++
+		size += info.Size()
+	}
+
+	return count, size, nil
+}
+
 func CreateFile(filename string) (*os.File, error) {
 	flags := os.O_CREATE | os.O_WRONLY
 	file, err := os.OpenFile(filename, flags, 0644)
@@ -58,13 +103,25 @@ func CreateFile(filename string) (*os.File, error) {
 	return file, err
 }
 
-func WriteFile(file *os.File, data []byte) (int, error) {
+func AppendFile(filename string) (*os.File, error) {
+	flags := os.O_APPEND | os.O_WRONLY
+	return os.OpenFile(filename, flags, 0644)
+}
+
+// WriteFile writes data and fsyncs the file, observeSync may be nil
+// and receives the duration of the fsync alone
+func WriteFile(file *os.File, data []byte, observeSync func(time.Duration)) (int, error) {
 	writtenBytes, err := file.Write(data)
 	if err != nil {
 		return 0, err
 	}
 
-	if err = file.Sync(); err != nil {
+	syncStarted := time.Now()
+	err = file.Sync()
+	if observeSync != nil {
+		observeSync(time.Since(syncStarted))
+	}
+	if err != nil {
 		return 0, err
 	}
 
@@ -1,27 +1,43 @@
 package filesystem
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 )
 
+// newTestSegments -- каталог с сегментами wal_1000.log, wal_2000.log и wal_3000.log
+func newTestSegments(t *testing.T) string {
+	t.Helper()
+
+	directory := t.TempDir()
+	for _, name := range []string{"wal_1000.log", "wal_2000.log", "wal_3000.log"} {
+		require.NoError(t, os.WriteFile(filepath.Join(directory, name), []byte(name), 0600))
+	}
+
+	return directory
+}
+
 func TestSegmentUpperBound(t *testing.T) {
 	t.Parallel()
 
-	filename, err := SegmentNext("test_data", "wal_0.log")
+	directory := newTestSegments(t)
+
+	filename, err := SegmentNext(directory, "wal_0.log")
 	require.NoError(t, err)
 	require.Equal(t, "wal_1000.log", filename)
 
-	filename, err = SegmentNext("test_data", "wal_1000.log")
+	filename, err = SegmentNext(directory, "wal_1000.log")
 	require.NoError(t, err)
 	require.Equal(t, "wal_2000.log", filename)
 
-	filename, err = SegmentNext("test_data", "wal_2000.log")
+	filename, err = SegmentNext(directory, "wal_2000.log")
 	require.NoError(t, err)
 	require.Equal(t, "", filename)
 
-	filename, err = SegmentNext("test_data", "wal_3000.log")
+	filename, err = SegmentNext(directory, "wal_3000.log")
 	require.NoError(t, err)
 	require.Equal(t, "", filename)
 }
@@ -29,7 +45,26 @@ func TestSegmentUpperBound(t *testing.T) {
 func TestSegmentLast(t *testing.T) {
 	t.Parallel()
 
-	filename, err := SegmentLast("test_data")
+	directory := newTestSegments(t)
+
+	filename, err := SegmentLast(directory)
 	require.NoError(t, err)
 	require.Equal(t, "wal_3000.log", filename)
-}
\ No newline at end of file
+}
+
+func TestSegmentsStat(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "wal_1000.log"), []byte("abc"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "wal_2000.log"), []byte("defgh"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(directory, "archive"), 0700))
+
+	count, size, err := SegmentsStat(directory)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.Equal(t, int64(8), size)
+
+	_, _, err = SegmentsStat(filepath.Join(directory, "missing"))
+	require.Error(t, err)
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"strings"
+	"time"
+
+	"kava/internal/database/compute"
+	"kava/internal/metrics"
+)
+
+// queryMetrics -- метрики запросов, без реестра обновления ничего не делают
+type queryMetrics struct {
+	queries  *metrics.CounterVec
+	duration *metrics.HistogramVec
+}
+
+func newQueryMetrics(registry *metrics.Registry) queryMetrics {
+	return queryMetrics{
+		queries: registry.NewCounterVec(
+			"kava_queries_total",
+			"Number of handled queries by command and status.",
+			"command", "status",
+		),
+		duration: registry.NewHistogramVec(
+			"kava_query_duration_seconds",
+			"Query handling latency by command.",
+			metrics.DefaultDurationBuckets,
+			"command",
+		),
+	}
+}
+
+// observe -- запрос, который не удалось разобрать, учитывается как UNKNOWN
+func (m queryMetrics) observe(commandID int, response string, duration time.Duration) {
+	command := compute.CommandName(commandID)
+	status := "ok"
+	if strings.HasPrefix(response, "[error]") {
+		status = "error"
+	}
+
+	m.queries.WithLabelValues(command, status).Inc()
+	m.duration.WithLabelValues(command).Observe(duration.Seconds())
+}
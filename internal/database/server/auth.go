@@ -0,0 +1,89 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"kava/internal/database/auth"
+	"kava/internal/database/compute"
+)
+
+const authCommand = "AUTH"
+
+const (
+	authenticationRequiredResponse = "[error] authentication required"
+	authenticationDisabledResponse = "[error] authentication is disabled"
+)
+
+// authorize -- обрабатывает AUTH и проверяет права на запрос, handled означает,
+// что запрос не должен доходить до базы и response нужно вернуть клиенту
+func (s *TCPServer) authorize(session *session, query string) (response string, handled bool) {
+	tokens := strings.Fields(query)
+	if len(tokens) != 0 && strings.EqualFold(tokens[0], authCommand) {
+		return s.authenticate(session, tokens[1:]), true
+	}
+
+	if s.authenticator == nil {
+		return "", false
+	}
+
+	if session.user == nil {
+		return authenticationRequiredResponse, true
+	}
+
+	// пустой запрос отклонит compute слой
+	if len(tokens) == 0 {
+		return "", false
+	}
+
+	if err := authorizeQuery(session.user, tokens); err != nil {
+		session.logger.Warn(
+			"query rejected",
+			zap.String("user", session.user.Name()),
+			zap.String("command", tokens[0]),
+			zap.Error(err),
+		)
+		return fmt.Sprintf("[error] %s", err.Error()), true
+	}
+
+	return "", false
+}
+
+func (s *TCPServer) authenticate(session *session, arguments []string) string {
+	if s.authenticator == nil {
+		return authenticationDisabledResponse
+	}
+
+	if len(arguments) != 2 {
+		return "[error] invalid arguments"
+	}
+
+	user, err := s.authenticator.Authenticate(arguments[0], arguments[1])
+	if err != nil {
+		session.logger.Warn(
+			"authentication failed",
+			zap.String("user", arguments[0]),
+		)
+		return fmt.Sprintf("[error] %s", err.Error())
+	}
+
+	session.user = user
+	return "[ok]"
+}
+
+// authorizeQuery -- права на ключи проверяются только для аргументов,
+// которые команда использует как ключи
+func authorizeQuery(user *auth.User, tokens []string) error {
+	info, known := compute.LookupCommand(tokens[0])
+	switch {
+	case !known:
+		// неизвестную команду отклонит compute слой
+		return user.Authorize(tokens[0])
+	case info.AllKeys:
+		return user.AuthorizeAllKeys(tokens[0])
+	default:
+		return user.Authorize(tokens[0], info.Keys(tokens[1:])...)
+	}
+}
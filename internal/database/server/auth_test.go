@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"kava/internal/configuration"
+	"kava/internal/database/auth"
+	"kava/internal/password"
+)
+
+func hashPassword(t *testing.T, secret string) string {
+	t.Helper()
+
+	hash, err := password.Generate(secret, password.MinIterations)
+	require.NoError(t, err)
+	return hash
+}
+
+func TestTCPServer_Authentication(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator([]configuration.UserConfig{
+		{Name: "reader", PasswordHash: hashPassword(t, "secret"), Commands: []string{"GET"}, Keys: []string{"user:*"}},
+	})
+	require.NoError(t, err)
+
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "GET user:1").Return("[ok] value")
+
+	cfg := &configuration.TCPServerConfig{
+		Host:           "localhost",
+		Port:           0,
+		MaxConnections: 10,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Second * 30,
+	}
+
+	server, err := NewTCPServer(cfg, mockDB, zap.NewNop(), WithAuthenticator(authenticator))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	send := func(query string) string {
+		_, err := conn.Write([]byte(query))
+		require.NoError(t, err)
+
+		response := make([]byte, 1024)
+		n, err := conn.Read(response)
+		require.NoError(t, err)
+		return string(response[:n])
+	}
+
+	assert.Equal(t, "[error] authentication required\n", send("GET user:1\n"))
+	assert.Equal(t, "[error] invalid credentials\n", send("AUTH reader wrong\n"))
+	assert.Equal(t, "[error] invalid arguments\n", send("AUTH reader\n"))
+	assert.Equal(t, "[ok]\n", send("AUTH reader secret\n"))
+	assert.Equal(t, "[ok] value\n", send("GET user:1\n"))
+	assert.Equal(t, "[error] permission denied\n", send("GET admin:1\n"))
+	assert.Equal(t, "[error] permission denied\n", send("DEL user:1\n"))
+
+	mockDB.AssertNumberOfCalls(t, "HandleQuery", 1)
+}
+
+func TestTCPServer_AuthenticationDisabled(t *testing.T) {
+	mockDB := new(MockDatabase)
+
+	cfg := &configuration.TCPServerConfig{
+		Host:           "localhost",
+		Port:           0,
+		MaxConnections: 10,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Second * 30,
+	}
+
+	server, err := NewTCPServer(cfg, mockDB, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("AUTH user password\n"))
+	require.NoError(t, err)
+
+	response := make([]byte, 1024)
+	n, err := conn.Read(response)
+	require.NoError(t, err)
+	assert.Equal(t, "[error] authentication is disabled\n", string(response[:n]))
+	mockDB.AssertNotCalled(t, "HandleQuery", mock.Anything, mock.Anything)
+}
+
+func TestTCPServer_AuthorizationKeyArguments(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator([]configuration.UserConfig{
+		{Name: "tenant", PasswordHash: hashPassword(t, "secret"), Commands: []string{"*"}, Keys: []string{"user:*"}},
+		{Name: "admin", PasswordHash: hashPassword(t, "secret"), Commands: []string{"*"}, Keys: []string{"*"}},
+	})
+	require.NoError(t, err)
+
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, mock.Anything).Return("[ok]")
+
+	server, err := NewTCPServer(&configuration.TCPServerConfig{
+		Host:           "localhost",
+		MaxConnections: 10,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Second * 30,
+	}, mockDB, zap.NewNop(), WithAuthenticator(authenticator))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+
+	tests := map[string]struct {
+		user  string
+		query string
+
+		expectedResponse string
+	}{
+		"echo message is not a key":       {user: "tenant", query: "ECHO hello", expectedResponse: "[ok]\n"},
+		"slowlog subcommand is not a key": {user: "tenant", query: "SLOWLOG LEN", expectedResponse: "[ok]\n"},
+		"value is not a key":              {user: "tenant", query: "SET user:1 admin:1", expectedResponse: "[ok]\n"},
+		"key out of patterns":             {user: "tenant", query: "SET admin:1 value", expectedResponse: "[error] permission denied\n"},
+		"flushall needs all keys":         {user: "tenant", query: "FLUSHALL", expectedResponse: "[error] permission denied\n"},
+		"flushall with all keys":          {user: "admin", query: "FLUSHALL", expectedResponse: "[ok]\n"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", server.Addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+
+			reader := bufio.NewReader(conn)
+			send := func(query string) string {
+				_, err := conn.Write([]byte(query + "\n"))
+				require.NoError(t, err)
+
+				response, err := reader.ReadString('\n')
+				require.NoError(t, err)
+				return response
+			}
+
+			require.Equal(t, "[ok]\n", send("AUTH "+test.user+" secret"))
+			assert.Equal(t, test.expectedResponse, send(test.query))
+		})
+	}
+}
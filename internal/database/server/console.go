@@ -5,46 +5,348 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"kava/internal/database/compute"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"go.uber.org/zap"
 )
 
-// Сonsole -- читает запросы из in, пишет ответы в out
+const (
+	consolePrompt             = "kava> "
+	consoleContinuationPrompt = "  ... "
+	consoleClientAddress      = "console"
+
+	// maxConsoleReadErrors -- после стольких ошибок чтения подряд консоль завершается
+	maxConsoleReadErrors = 3
+
+	helpCommand = "HELP"
+)
+
+// metaCommands -- команды консоли, которые не проходят через язык запросов
+var metaCommands = map[string]string{
+	".help":    "show this help",
+	".history": "show command history",
+	".stats":   "show console statistics",
+	".quit":    "exit the console",
+	".exit":    "exit the console",
+}
+
+// Сonsole -- читает запросы из in, пишет ответы в out. Если in - терминал,
+// работает как REPL с приглашением, историей и дополнением команд
 type Сonsole struct {
-	in     io.Reader
-	out    io.Writer
-	db     Database
-	logger *zap.Logger
+	in      io.Reader
+	out     io.Writer
+	db      Database
+	logger  *zap.Logger
+	session *session
+
+	historyFile string
+	historySize int
+	history     *history
+	stats       consoleStats
+}
+
+// consoleStats -- статистика запросов за время работы консоли
+type consoleStats struct {
+	started  time.Time
+	queries  int
+	errors   int
+	duration time.Duration
+}
+
+// ConsoleOption -- необязательная настройка консоли
+type ConsoleOption func(*Сonsole)
+
+// WithHistory -- хранит не больше size последних запросов и сохраняет их в файл,
+// если он задан. Без этой настройки история не ведется
+func WithHistory(filename string, size int) ConsoleOption {
+	return func(c *Сonsole) {
+		c.historyFile = filename
+		c.historySize = size
+	}
 }
 
 // NewConsole -- конструктор консоли
-func NewConsole(in io.Reader, out io.Writer, db Database, logger *zap.Logger) (*Сonsole, error) {
-	return &Сonsole{
-		in:     in,
-		out:    out,
-		db:     db,
-		logger: logger,
-	}, nil
+func NewConsole(in io.Reader, out io.Writer, db Database, logger *zap.Logger, options ...ConsoleOption) (*Сonsole, error) {
+	console := &Сonsole{
+		in:      in,
+		out:     out,
+		db:      db,
+		logger:  logger,
+		session: newSession(consoleClientAddress, logger),
+	}
+
+	for _, option := range options {
+		option(console)
+	}
+
+	return console, nil
 }
 
-// Start -- запускает консоль
-func (c *Сonsole) Start(ctx context.Context) {
+// Start -- запускает консоль, завершается по отмене контекста, концу ввода или .quit.
+// Возвращает ошибку, если ввод не читается
+func (c *Сonsole) Start(ctx context.Context) error {
+	history, err := newHistory(c.historyFile, c.historySize)
+	if err != nil {
+		c.logger.Warn("failed to open console history", zap.String("file", c.historyFile), zap.Error(err))
+	}
+	c.history = history
+
+	term := newTerminal(c.in)
+	done := make(chan error, 1)
 	go func() {
-		reader := bufio.NewReader(c.in)
-		var res string
-		for {
-			query, err := reader.ReadString('\n')
-			if err == io.EOF {
-				break
+		done <- c.run(ctx, bufio.NewReader(c.in), term)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// чтение может остаться заблокированным: терминал закрывается сразу, чтобы
+		// читатель не вернул посимвольный режим, остальное закроет сам run
+		term.close()
+		return nil
+	}
+}
+
+// run -- история ведется только в интерактивном режиме. Терминал и история
+// закрываются здесь, после последнего чтения
+func (c *Сonsole) run(ctx context.Context, reader *bufio.Reader, term *terminal) error {
+	defer func() {
+		term.close()
+		if err := c.history.close(); err != nil {
+			c.logger.Warn("failed to close console history", zap.Error(err))
+		}
+	}()
+
+	interactive := term != nil
+	editor := &lineEditor{
+		reader:   reader,
+		out:      c.out,
+		history:  c.history,
+		complete: complete,
+	}
+
+	c.stats.started = time.Now()
+	if interactive {
+		fmt.Fprintln(c.out, "KaVa console, type .help for help")
+	}
+
+	readErrors := 0
+	for {
+		query, err := c.readQuery(reader, editor, term)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == io.EOF {
+			if strings.TrimSpace(query) != "" {
+				c.execute(ctx, query)
+			}
+			return nil
+		}
+		if err != nil {
+			c.logger.Error("failed to read query", zap.Error(err))
+			if readErrors++; readErrors == maxConsoleReadErrors {
+				return fmt.Errorf("failed to read query: %w", err)
 			}
-			if err != nil {
-				c.logger.Error("failed to read query", zap.Error(err))
+			continue
+		}
+		readErrors = 0
+
+		if interactive {
+			if strings.TrimSpace(query) == "" {
 				continue
 			}
-			res = c.db.HandleQuery(ctx, query)
+			if err := c.history.add(strings.TrimSpace(query)); err != nil {
+				c.logger.Warn("failed to save console history", zap.Error(err))
+			}
+		}
 
-			fmt.Fprintln(c.out, res)
+		if quit := c.execute(ctx, query); quit {
+			return nil
 		}
-	}()
-	<-ctx.Done()
+	}
+}
+
+// readQuery -- строка, оканчивающаяся на \, продолжается на следующей
+func (c *Сonsole) readQuery(reader *bufio.Reader, editor *lineEditor, term *terminal) (string, error) {
+	var parts []string
+	prompt := consolePrompt
+	for {
+		line, err := c.readLine(reader, editor, term, prompt)
+		line = strings.TrimRight(line, "\r\n")
+		if err != nil {
+			return strings.Join(append(parts, line), " "), err
+		}
+
+		if continued, ok := strings.CutSuffix(line, "\\"); ok {
+			parts = append(parts, strings.TrimRight(continued, " \t"))
+			prompt = consoleContinuationPrompt
+			continue
+		}
+
+		return strings.Join(append(parts, line), " "), nil
+	}
+}
+
+func (c *Сonsole) readLine(reader *bufio.Reader, editor *lineEditor, term *terminal, prompt string) (string, error) {
+	if term == nil {
+		return reader.ReadString('\n')
+	}
+
+	if err := term.makeRaw(); err != nil {
+		return "", err
+	}
+	defer term.restore()
+
+	return editor.readLine(prompt)
+}
+
+// execute -- выполняет запрос или команду консоли, true означает выход
+func (c *Сonsole) execute(ctx context.Context, query string) bool {
+	trimmed := strings.TrimSpace(query)
+	if strings.HasPrefix(trimmed, ".") {
+		return c.executeMeta(trimmed)
+	}
+
+	if tokens := strings.Fields(trimmed); len(tokens) != 0 && strings.EqualFold(tokens[0], helpCommand) {
+		c.printHelp(tokens[1:])
+		return false
+	}
+
+	started := time.Now()
+	response := c.db.HandleQuery(c.session.requestContext(ctx), query)
+	c.stats.record(time.Since(started), response)
+
+	fmt.Fprintln(c.out, response)
+	return false
+}
+
+func (c *Сonsole) executeMeta(command string) bool {
+	switch command {
+	case ".quit", ".exit":
+		return true
+	case ".help":
+		c.printHelp(nil)
+	case ".stats":
+		c.printStats()
+	case ".history":
+		for i, entry := range c.history.list() {
+			fmt.Fprintf(c.out, "%4d  %s\n", i+1, entry)
+		}
+	default:
+		fmt.Fprintf(c.out, "[error] unknown meta command: %s\n", command)
+	}
+
+	return false
+}
+
+// printHelp -- справка по командам из таблицы compute слоя
+func (c *Сonsole) printHelp(arguments []string) {
+	if len(arguments) != 0 {
+		info, exist := compute.LookupCommand(arguments[0])
+		if !exist {
+			fmt.Fprintf(c.out, "[error] unknown command: %s\n", arguments[0])
+			return
+		}
+
+		fmt.Fprintf(c.out, "%s -- %s\n", info.Usage(), info.Description)
+		return
+	}
+
+	writer := tabwriter.NewWriter(c.out, 0, 4, 4, ' ', 0)
+	fmt.Fprintln(writer, "Commands:")
+	for _, info := range compute.Commands() {
+		fmt.Fprintf(writer, "  %s\t%s\n", info.Usage(), info.Description)
+	}
+	fmt.Fprintf(writer, "  %s [command]\t%s\n", helpCommand, "show help for commands")
+
+	fmt.Fprintln(writer, "Meta commands:")
+	for _, command := range sortedMetaCommands() {
+		fmt.Fprintf(writer, "  %s\t%s\n", command, metaCommands[command])
+	}
+	_ = writer.Flush()
+
+	fmt.Fprintln(c.out, "Lines ending with \\ continue on the next line.")
+}
+
+func (c *Сonsole) printStats() {
+	var average time.Duration
+	if c.stats.queries != 0 {
+		average = c.stats.duration / time.Duration(c.stats.queries)
+	}
+
+	fmt.Fprintf(c.out, "uptime: %s\n", time.Since(c.stats.started).Round(time.Second))
+	fmt.Fprintf(c.out, "queries: %d\n", c.stats.queries)
+	fmt.Fprintf(c.out, "errors: %d\n", c.stats.errors)
+	fmt.Fprintf(c.out, "average latency: %s\n", average)
+}
+
+func (s *consoleStats) record(duration time.Duration, response string) {
+	s.queries++
+	s.duration += duration
+	if strings.HasPrefix(response, "[error]") {
+		s.errors++
+	}
+}
+
+// complete -- варианты дополнения строки: имя команды или мета-команды
+// в начале строки, имя команды после HELP
+func complete(line string) []string {
+	tokens := strings.Fields(line)
+	if strings.HasSuffix(line, " ") {
+		tokens = append(tokens, "")
+	}
+
+	var prefix, word string
+	var names []string
+	switch {
+	case len(tokens) == 0:
+		names = append(commandNames(), helpCommand)
+	case len(tokens) == 1 && strings.HasPrefix(tokens[0], "."):
+		word, names = tokens[0], sortedMetaCommands()
+	case len(tokens) == 1:
+		word, names = tokens[0], append(commandNames(), helpCommand)
+	case len(tokens) == 2 && strings.EqualFold(tokens[0], helpCommand):
+		prefix, word, names = strings.ToUpper(tokens[0])+" ", tokens[1], commandNames()
+	default:
+		return nil
+	}
+
+	var candidates []string
+	for _, name := range names {
+		if len(name) >= len(word) && strings.EqualFold(name[:len(word)], word) {
+			candidate := prefix + name
+			if !strings.HasPrefix(name, ".") {
+				candidate += " "
+			}
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	sort.Strings(candidates)
+	return candidates
+}
+
+func commandNames() []string {
+	commands := compute.Commands()
+	names := make([]string, 0, len(commands))
+	for _, info := range commands {
+		names = append(names, info.Name)
+	}
+
+	return names
+}
+
+func sortedMetaCommands() []string {
+	commands := make([]string, 0, len(metaCommands))
+	for command := range metaCommands {
+		commands = append(commands, command)
+	}
+
+	sort.Strings(commands)
+	return commands
 }
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+const (
+	keyCtrlD     = 4
+	keyBackspace = 8
+	keyTab       = 9
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// lineEditor -- ввод строки в посимвольном режиме терминала: редактирование,
+// история по стрелкам вверх/вниз и дополнение по Tab
+type lineEditor struct {
+	reader   *bufio.Reader
+	out      io.Writer
+	history  *history
+	complete func(line string) []string
+}
+
+// readLine -- читает строку до Enter, Ctrl-D на пустой строке возвращает io.EOF
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	entries := e.history.list()
+	position := len(entries)
+	current := ""
+
+	var line []rune
+	fmt.Fprint(e.out, prompt)
+	for {
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			return string(line), err
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\n")
+			return string(line), nil
+		case keyCtrlD:
+			if len(line) == 0 {
+				fmt.Fprint(e.out, "\n")
+				return "", io.EOF
+			}
+		case keyBackspace, keyDelete:
+			if len(line) != 0 {
+				line = line[:len(line)-1]
+				fmt.Fprint(e.out, "\b \b")
+			}
+		case keyCtrlU:
+			line = line[:0]
+			e.redraw(prompt, line)
+		case keyTab:
+			line = e.completeLine(prompt, line)
+		case keyEscape:
+			switch e.readEscape() {
+			case 'A':
+				if position > 0 {
+					if position == len(entries) {
+						current = string(line)
+					}
+					position--
+					line = []rune(entries[position])
+					e.redraw(prompt, line)
+				}
+			case 'B':
+				if position < len(entries) {
+					position++
+					if position == len(entries) {
+						line = []rune(current)
+					} else {
+						line = []rune(entries[position])
+					}
+					e.redraw(prompt, line)
+				}
+			}
+		default:
+			if unicode.IsPrint(r) {
+				line = append(line, r)
+				fmt.Fprint(e.out, string(r))
+			}
+		}
+	}
+}
+
+// readEscape -- разбирает escape-последовательность и возвращает ее последний символ,
+// нужны только стрелки ('A' - вверх, 'B' - вниз), остальные пропускаются
+func (e *lineEditor) readEscape() rune {
+	r, _, err := e.reader.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return 0
+	}
+
+	for {
+		r, _, err = e.reader.ReadRune()
+		if err != nil {
+			return 0
+		}
+		// параметры последовательности, например "3~" у Delete
+		if unicode.IsDigit(r) || r == ';' {
+			continue
+		}
+		return r
+	}
+}
+
+func (e *lineEditor) completeLine(prompt string, line []rune) []rune {
+	candidates := e.complete(string(line))
+	switch len(candidates) {
+	case 0:
+		return line
+	case 1:
+		line = []rune(candidates[0])
+	default:
+		prefix := commonPrefix(candidates)
+		if len([]rune(prefix)) > len(line) {
+			line = []rune(prefix)
+			break
+		}
+
+		fmt.Fprintf(e.out, "\n%s\n", strings.Join(candidates, "  "))
+	}
+
+	e.redraw(prompt, line)
+	return line
+}
+
+// redraw -- перерисовывает строку ввода целиком
+func (e *lineEditor) redraw(prompt string, line []rune) {
+	fmt.Fprintf(e.out, "\r\x1b[K%s%s", prompt, string(line))
+}
+
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	return prefix
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// history -- история запросов консоли, хранит не больше size последних записей
+// и дописывает новые в файл, если он задан
+type history struct {
+	mutex   sync.Mutex
+	size    int
+	entries []string
+	file    *os.File
+}
+
+func newHistory(filename string, size int) (*history, error) {
+	h := &history{size: size}
+	if filename == "" {
+		return h, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return h, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+
+	// файл переписывается, только когда записей больше лимита, иначе дописывается
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if len(h.entries) > size {
+		h.entries = h.entries[len(h.entries)-size:]
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(filename, flags, 0600)
+	if err != nil {
+		return h, err
+	}
+
+	if flags&os.O_TRUNC != 0 {
+		if _, err := file.WriteString(strings.Join(h.entries, "\n") + "\n"); err != nil {
+			_ = file.Close()
+			return h, err
+		}
+	}
+
+	h.file = file
+	return h, nil
+}
+
+// add -- запоминает запрос, пустые строки и повтор предыдущего пропускаются
+func (h *history) add(entry string) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if entry == "" || (len(h.entries) != 0 && h.entries[len(h.entries)-1] == entry) {
+		return nil
+	}
+
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > h.size {
+		h.entries = h.entries[len(h.entries)-h.size:]
+	}
+
+	if h.file == nil {
+		return nil
+	}
+
+	_, err := h.file.WriteString(entry + "\n")
+	return err
+}
+
+func (h *history) list() []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return append([]string(nil), h.entries...)
+}
+
+func (h *history) close() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.file == nil {
+		return nil
+	}
+
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
@@ -1,16 +1,21 @@
 package server
 
 import (
+	"bufio"
 	"context"
 	"errors"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 	"go.uber.org/zap/zaptest/observer"
@@ -193,3 +198,165 @@ func TestConsole_Start_ReadError(t *testing.T) {
 		t.Errorf("unexpected error message: %v", logEntry.Message)
 	}
 }
+
+func runConsole(t *testing.T, input string, db *MockDatabase, options ...ConsoleOption) string {
+	t.Helper()
+
+	out := &strings.Builder{}
+	console, err := NewConsole(strings.NewReader(input), out, db, zap.NewNop(), options...)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, console.Start(ctx))
+	return out.String()
+}
+
+func TestConsole_Help(t *testing.T) {
+	db := new(MockDatabase)
+	output := runConsole(t, "HELP\nhelp set\nHELP FOO\n.help\n", db)
+
+	assert.Contains(t, output, "Commands:")
+	assert.Contains(t, output, "GET key")
+	assert.Contains(t, output, "SET key value -- set the value of the key\n")
+	assert.Contains(t, output, "[error] unknown command: FOO\n")
+	assert.Contains(t, output, ".stats")
+	db.AssertNotCalled(t, "HandleQuery", mock.Anything, mock.Anything)
+}
+
+func TestConsole_MetaCommands(t *testing.T) {
+	db := new(MockDatabase)
+	db.On("HandleQuery", mock.Anything, "GET key").Return("[ok] value")
+	db.On("HandleQuery", mock.Anything, "GET missing").Return("[error] not found")
+
+	output := runConsole(t, "GET key\nGET missing\n.stats\n.unknown\n.quit\nGET key\n", db)
+
+	assert.Contains(t, output, "queries: 2\n")
+	assert.Contains(t, output, "errors: 1\n")
+	assert.Contains(t, output, "[error] unknown meta command: .unknown\n")
+	// запросы после .quit не выполняются
+	db.AssertNumberOfCalls(t, "HandleQuery", 2)
+}
+
+func TestConsole_MultiLineQuery(t *testing.T) {
+	db := new(MockDatabase)
+	db.On("HandleQuery", mock.Anything, "SET key value").Return("[ok]")
+
+	output := runConsole(t, "SET \\\nkey \\\nvalue\n", db)
+	assert.Equal(t, "[ok]\n", output)
+}
+
+func TestConsole_History(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history")
+	require.NoError(t, os.WriteFile(filename, []byte("GET a\nGET b\nGET c\n"), 0600))
+
+	h, err := newHistory(filename, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GET b", "GET c"}, h.list())
+
+	require.NoError(t, h.add("GET c"))
+	require.NoError(t, h.add("SET d 1"))
+	require.NoError(t, h.add(""))
+	assert.Equal(t, []string{"GET c", "SET d 1"}, h.list())
+	require.NoError(t, h.close())
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "GET b\nGET c\nSET d 1\n", string(data))
+
+	h, err = newHistory(filename, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GET c", "SET d 1"}, h.list())
+	require.NoError(t, h.close())
+}
+
+func TestConsole_Complete(t *testing.T) {
+	tests := map[string][]string{
+		"":        {"DBSIZE ", "DEL ", "ECHO ", "FLUSHALL ", "GET ", "HELP ", "INFO ", "PING ", "SET ", "SLOWLOG "},
+		"s":       {"SET ", "SLOWLOG "},
+		"se":      {"SET "},
+		"GE":      {"GET "},
+		"h":       {"HELP "},
+		".st":     {".stats"},
+		".":       {".exit", ".help", ".history", ".quit", ".stats"},
+		"help d":  {"HELP DBSIZE ", "HELP DEL "},
+		"help f":  {"HELP FLUSHALL "},
+		"HELP ":   {"HELP DBSIZE ", "HELP DEL ", "HELP ECHO ", "HELP FLUSHALL ", "HELP GET ", "HELP INFO ", "HELP PING ", "HELP SET ", "HELP SLOWLOG "},
+		"GET key": nil,
+		"x":       nil,
+	}
+
+	for line, expected := range tests {
+		assert.Equal(t, expected, complete(line), "line %q", line)
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	h, err := newHistory("", 10)
+	require.NoError(t, err)
+	require.NoError(t, h.add("GET first"))
+	require.NoError(t, h.add("GET second"))
+
+	readLine := func(input string) (string, error) {
+		editor := &lineEditor{
+			reader:   bufio.NewReader(strings.NewReader(input)),
+			out:      io.Discard,
+			history:  h,
+			complete: complete,
+		}
+		return editor.readLine(consolePrompt)
+	}
+
+	tests := map[string]struct {
+		input    string
+		expected string
+	}{
+		"plain input":          {input: "GET key\r", expected: "GET key"},
+		"backspace":            {input: "GET keyy\x7f\r", expected: "GET key"},
+		"clear line":           {input: "DEL key\x15GET key\r", expected: "GET key"},
+		"tab completion":       {input: "se\tkey value\r", expected: "SET key value"},
+		"history up":           {input: "\x1b[A\r", expected: "GET second"},
+		"history up twice":     {input: "\x1b[A\x1b[A\r", expected: "GET first"},
+		"history up and down":  {input: "GET\x1b[A\x1b[B\r", expected: "GET"},
+		"ignored arrow keys":   {input: "GET\x1b[D\x1b[3~ key\r", expected: "GET key"},
+		"non printable ignore": {input: "GET\x01 key\r", expected: "GET key"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			line, err := readLine(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, line)
+		})
+	}
+
+	_, err = readLine("\x04")
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+// errorReader -- ввод, который всегда возвращает ошибку
+type errorReader struct {
+	err   error
+	reads atomic.Int32
+}
+
+func (r *errorReader) Read([]byte) (int, error) {
+	r.reads.Add(1)
+	return 0, r.err
+}
+
+func TestConsole_Start_PersistentReadError(t *testing.T) {
+	readErr := errors.New("read error")
+	in := &errorReader{err: readErr}
+
+	console, err := NewConsole(in, &strings.Builder{}, new(MockDatabase), zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = console.Start(ctx)
+	assert.ErrorIs(t, err, readErr)
+	assert.Equal(t, int32(maxConsoleReadErrors), in.reads.Load())
+}
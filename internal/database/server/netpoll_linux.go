@@ -0,0 +1,507 @@
+//go:build linux
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// readEvents -- ожидание запросов, writeEvents -- ожидание места в буфере
+	// сокета для ответов, которые не удалось отправить сразу
+	readEvents      = syscall.EPOLLIN | syscall.EPOLLRDHUP | syscall.EPOLLONESHOT
+	writeEvents     = syscall.EPOLLOUT | syscall.EPOLLONESHOT
+	pollWaitTimeout = 100 // миллисекунды, чтобы цикл замечал остановку
+	pollMaxEvents   = 256
+	// disabledIdleCheckInterval -- как часто проверяется, не включили ли idle_timeout
+	disabledIdleCheckInterval = time.Second
+)
+
+// pollConnection -- соединение, зарегистрированное в epoll. Событие EPOLLONESHOT
+// отдает соединение ровно одному воркеру, после обработки оно снова взводится
+type pollConnection struct {
+	connection net.Conn
+	raw        syscall.RawConn
+	fd         int
+	session    *session
+	pending    []byte
+	// output -- ответы, не принятые сокетом, пока они не отправлены,
+	// новые запросы не читаются. Меняется только воркером
+	output []byte
+	// closing -- закрыть соединение после отправки output
+	closing    bool
+	lastActive atomic.Int64
+
+	mutex  sync.Mutex
+	busy   bool
+	closed bool
+}
+
+// netpoll -- чтение соединений через epoll и выполнение запросов в пуле воркеров
+type netpoll struct {
+	server  *TCPServer
+	epfd    int
+	wait    func(epfd int, events []syscall.EpollEvent, msec int) (int, error)
+	workers int
+	tasks   chan *pollConnection
+
+	mutex       sync.Mutex
+	connections map[int]*pollConnection
+
+	ctx    context.Context
+	done   chan struct{}
+	errors chan error
+	wg     sync.WaitGroup
+}
+
+func newNetpoll(server *TCPServer, workers int) (*netpoll, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create epoll: %w", err)
+	}
+
+	return &netpoll{
+		server:      server,
+		epfd:        epfd,
+		wait:        syscall.EpollWait,
+		workers:     workers,
+		tasks:       make(chan *pollConnection, workers),
+		connections: make(map[int]*pollConnection),
+		done:        make(chan struct{}),
+		errors:      make(chan error, 1),
+	}, nil
+}
+
+func (p *netpoll) start(ctx context.Context) {
+	p.ctx = ctx
+
+	p.wg.Add(p.workers)
+	for i := 0; i < p.workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			buffer := make([]byte, p.server.bufferSize)
+			for connection := range p.tasks {
+				p.process(connection, buffer)
+			}
+		}()
+	}
+
+	go p.loop()
+	go p.closeExpired()
+}
+
+func (p *netpoll) add(connection net.Conn) error {
+	syscallConn, ok := connection.(syscall.Conn)
+	if !ok {
+		return errors.New("connection does not expose file descriptor")
+	}
+
+	raw, err := syscallConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var fd int
+	if err := raw.Control(func(descriptor uintptr) { fd = int(descriptor) }); err != nil {
+		return err
+	}
+
+	c := &pollConnection{
+		connection: connection,
+		raw:        raw,
+		fd:         fd,
+		session:    newSession(connection.RemoteAddr().String(), p.server.logger),
+	}
+	c.lastActive.Store(time.Now().UnixNano())
+
+	p.mutex.Lock()
+	p.connections[fd] = c
+	p.mutex.Unlock()
+
+	event := syscall.EpollEvent{Events: readEvents, Fd: int32(fd)}
+	if err := syscall.EpollCtl(p.epfd, syscall.EPOLL_CTL_ADD, fd, &event); err != nil {
+		p.mutex.Lock()
+		delete(p.connections, fd)
+		p.mutex.Unlock()
+		return fmt.Errorf("failed to register connection: %w", err)
+	}
+
+	return nil
+}
+
+// failed -- ошибка цикла epoll, после нее соединения не обслуживаются,
+// и сервер должен остановиться
+func (p *netpoll) failed() <-chan error {
+	return p.errors
+}
+
+// loop -- ждет готовые к чтению или записи соединения и передает их воркерам
+func (p *netpoll) loop() {
+	defer close(p.tasks)
+
+	events := make([]syscall.EpollEvent, pollMaxEvents)
+	for {
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+
+		count, err := p.wait(p.epfd, events, pollWaitTimeout)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		} else if err != nil {
+			p.errors <- fmt.Errorf("failed to wait epoll events: %w", err)
+			return
+		}
+
+		for _, event := range events[:count] {
+			p.mutex.Lock()
+			c := p.connections[int(event.Fd)]
+			p.mutex.Unlock()
+
+			// событие могло остаться от уже закрытого соединения
+			if c == nil || !c.acquire() {
+				continue
+			}
+
+			select {
+			case p.tasks <- c:
+			case <-p.done:
+				return
+			}
+		}
+	}
+}
+
+// acquire -- отмечает соединение как обрабатываемое воркером
+func (c *pollConnection) acquire() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.busy || c.closed {
+		return false
+	}
+
+	c.busy = true
+	return true
+}
+
+// process -- отправляет отложенные ответы, затем читает доступные данные
+// и выполняет все полные запросы. Воркер не блокируется на записи: ответы,
+// которые не принял сокет, отправляются по EPOLLOUT
+func (p *netpoll) process(c *pollConnection, buffer []byte) {
+	s := p.server
+
+	if len(c.output) != 0 {
+		if err := c.flush(); err != nil {
+			c.session.logger.Warn("failed to write data", zap.Error(err))
+			p.closeConnection(c)
+			return
+		}
+
+		if len(c.output) != 0 {
+			p.rearm(c, writeEvents)
+			return
+		}
+
+		if c.closing || s.stopped.Load() {
+			p.closeConnection(c)
+			return
+		}
+	}
+
+	count, eof, err := c.read(buffer)
+	if errors.Is(err, syscall.EAGAIN) {
+		p.rearm(c, readEvents)
+		return
+	} else if err != nil {
+		c.session.logger.Warn("failed to read data", zap.Error(err))
+		p.closeConnection(c)
+		return
+	}
+
+	c.lastActive.Store(time.Now().UnixNano())
+	c.pending = append(c.pending, buffer[:count]...)
+
+	var responses []byte
+	stop := false
+	for {
+		index := bytes.IndexByte(c.pending, '\n')
+		if index < 0 {
+			break
+		}
+
+		line := c.pending[:index+1]
+		if len(line) > int(s.bufferSize) {
+			responses, stop = p.messageTooLarge(c, responses), true
+			break
+		}
+
+		c.pending = c.pending[index+1:]
+		response, timedOut := p.execute(c, string(line))
+		responses = append(responses, response+"\n"...)
+		if timedOut {
+			stop = true
+			break
+		}
+	}
+
+	if !stop && len(c.pending) >= int(s.bufferSize) {
+		responses, stop = p.messageTooLarge(c, responses), true
+	}
+
+	// последний запрос без перевода строки перед закрытием записи клиентом
+	if eof && !stop && len(c.pending) != 0 {
+		response, _ := p.execute(c, string(c.pending))
+		responses = append(responses, response+"\n"...)
+	}
+
+	// буфер не держится за простаивающим соединением
+	if len(c.pending) == 0 {
+		c.pending = nil
+	}
+
+	closeAfter := eof || stop
+
+	c.output = responses
+	if err := c.flush(); err != nil {
+		c.session.logger.Warn("failed to write data", zap.Error(err))
+		p.closeConnection(c)
+		return
+	}
+
+	// клиент читает медленнее, чем отправляет запросы: остаток ответов
+	// ждет EPOLLOUT, а медленный клиент закроется по idle_timeout
+	if len(c.output) != 0 {
+		c.closing = closeAfter
+		p.rearm(c, writeEvents)
+		return
+	}
+
+	if closeAfter || s.stopped.Load() {
+		p.closeConnection(c)
+		return
+	}
+
+	p.rearm(c, readEvents)
+}
+
+func (p *netpoll) execute(c *pollConnection, line string) (string, bool) {
+	query := trimQuery(line)
+	response, timedOut := p.server.executeQuery(p.ctx, c.session, query)
+	if timedOut {
+		c.session.logger.Warn(
+			"closing connection: query timeout exceeded",
+			zap.Duration("query_timeout", p.server.limits.Load().queryTimeout),
+		)
+	}
+
+	return response, timedOut
+}
+
+func (p *netpoll) messageTooLarge(c *pollConnection, responses []byte) []byte {
+	c.session.logger.Warn(
+		"closing connection: message is too large",
+		zap.Int("max_message_size", int(p.server.bufferSize)),
+	)
+
+	return append(responses, messageTooLargeResponse+"\n"...)
+}
+
+// read -- неблокирующее чтение, EAGAIN означает, что данных пока нет
+func (c *pollConnection) read(buffer []byte) (int, bool, error) {
+	var count int
+	var readErr error
+	err := c.raw.Read(func(fd uintptr) bool {
+		count, readErr = syscall.Read(int(fd), buffer)
+		return true
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	if readErr != nil {
+		return 0, false, readErr
+	}
+
+	return count, count == 0, nil
+}
+
+// flush -- неблокирующая запись output, при заполненном буфере сокета
+// остаток сохраняется до EPOLLOUT
+func (c *pollConnection) flush() error {
+	for len(c.output) != 0 {
+		var count int
+		var writeErr error
+		err := c.raw.Write(func(fd uintptr) bool {
+			count, writeErr = syscall.Write(int(fd), c.output)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case errors.Is(writeErr, syscall.EAGAIN):
+			return nil
+		case errors.Is(writeErr, syscall.EINTR):
+			continue
+		case writeErr != nil:
+			return writeErr
+		}
+
+		c.output = c.output[count:]
+	}
+
+	c.output = nil
+	return nil
+}
+
+// rearm -- возвращает соединение в epoll после обработки, events -- ожидаемые
+// события: запросы или место для отложенных ответов
+func (p *netpoll) rearm(c *pollConnection, events uint32) {
+	c.mutex.Lock()
+	c.busy = false
+	closed := c.closed
+	c.mutex.Unlock()
+
+	if closed {
+		return
+	}
+
+	// drain мог пропустить соединение, пока оно было занято,
+	// отложенные ответы отправляются до закрытия
+	if p.server.stopped.Load() && len(c.output) == 0 {
+		p.closeConnection(c)
+		return
+	}
+
+	event := syscall.EpollEvent{Events: events, Fd: int32(c.fd)}
+	if err := syscall.EpollCtl(p.epfd, syscall.EPOLL_CTL_MOD, c.fd, &event); err != nil {
+		c.session.logger.Warn("failed to rearm connection", zap.Error(err))
+		p.closeConnection(c)
+	}
+}
+
+func (p *netpoll) closeConnection(c *pollConnection) {
+	c.mutex.Lock()
+	closed := c.closed
+	c.closed = true
+	c.mutex.Unlock()
+
+	if !closed {
+		p.release(c)
+	}
+}
+
+// release -- освобождает ресурсы соединения, уже отмеченного закрытым
+func (p *netpoll) release(c *pollConnection) {
+	// дескриптор удаляется из epoll до закрытия, пока его номер не может быть переиспользован
+	_ = syscall.EpollCtl(p.epfd, syscall.EPOLL_CTL_DEL, c.fd, nil)
+	p.mutex.Lock()
+	delete(p.connections, c.fd)
+	p.mutex.Unlock()
+
+	if err := c.connection.Close(); err != nil {
+		p.server.logger.Warn("failed to close connection", zap.Error(err))
+	}
+
+	p.server.untrackConnection(c.connection)
+	p.server.semaphore.Release()
+}
+
+// closeIdle -- закрывает соединения, которые не обрабатываются воркерами,
+// занятые соединения и соединения с отложенными ответами закроются
+// после отправки ответов
+func (p *netpoll) closeIdle() {
+	p.closeWhere(func(c *pollConnection) bool { return len(c.output) == 0 })
+}
+
+// closeExpired -- закрывает соединения без запросов дольше idle_timeout,
+// idle_timeout перечитывается на каждой проверке, так как меняется через Reload
+func (p *netpoll) closeExpired() {
+	timer := time.NewTimer(idleCheckInterval(p.server.limits.Load().idleTimeout))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-timer.C:
+		}
+
+		idleTimeout := p.server.limits.Load().idleTimeout
+		timer.Reset(idleCheckInterval(idleTimeout))
+		if idleTimeout == 0 {
+			continue
+		}
+
+		deadline := time.Now().Add(-idleTimeout).UnixNano()
+		p.closeWhere(func(c *pollConnection) bool {
+			if c.lastActive.Load() > deadline {
+				return false
+			}
+
+			c.session.logger.Info(
+				"closing connection: idle timeout exceeded",
+				zap.Duration("idle_timeout", idleTimeout),
+			)
+			return true
+		})
+	}
+}
+
+// idleCheckInterval -- без idle_timeout проверка ждет, пока его включат через Reload
+func idleCheckInterval(idleTimeout time.Duration) time.Duration {
+	if idleTimeout == 0 {
+		return disabledIdleCheckInterval
+	}
+
+	return idleTimeout / 2
+}
+
+func (p *netpoll) closeWhere(predicate func(*pollConnection) bool) {
+	p.mutex.Lock()
+	candidates := make([]*pollConnection, 0, len(p.connections))
+	for _, c := range p.connections {
+		candidates = append(candidates, c)
+	}
+	p.mutex.Unlock()
+
+	for _, c := range candidates {
+		// соединение помечается закрытым под блокировкой, чтобы цикл epoll
+		// не успел отдать его воркеру
+		c.mutex.Lock()
+		matched := !c.busy && !c.closed && predicate(c)
+		if matched {
+			c.closed = true
+		}
+		c.mutex.Unlock()
+
+		if matched {
+			p.release(c)
+		}
+	}
+}
+
+// close -- останавливает цикл epoll и воркеры
+func (p *netpoll) close() {
+	close(p.done)
+	p.wg.Wait()
+	_ = syscall.Close(p.epfd)
+}
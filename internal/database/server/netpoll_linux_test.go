@@ -0,0 +1,280 @@
+//go:build linux
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"kava/internal/configuration"
+)
+
+func newNetpollConfig() *configuration.TCPServerConfig {
+	return &configuration.TCPServerConfig{
+		Host:           "localhost",
+		Port:           0,
+		MaxConnections: 100,
+		MaxMessageSize: 64,
+		IdleTimeout:    time.Second * 30,
+		DrainTimeout:   time.Second,
+		Mode:           "netpoll",
+		Workers:        4,
+	}
+}
+
+func startNetpollServer(t *testing.T, cfg *configuration.TCPServerConfig, mockDB *MockDatabase) (*TCPServer, func() error) {
+	t.Helper()
+
+	server, err := NewTCPServer(cfg, mockDB, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start(ctx)
+	}()
+
+	stop := sync.OnceValue(func() error {
+		cancel()
+		return <-stopped
+	})
+	t.Cleanup(func() { _ = stop() })
+
+	return server, stop
+}
+
+func TestNewTCPServerModes(t *testing.T) {
+	cfg := newNetpollConfig()
+	cfg.Mode = "unknown"
+	server, err := NewTCPServer(cfg, new(MockDatabase), zap.NewNop())
+	assert.Error(t, err)
+	assert.Nil(t, server)
+
+	cfg = newNetpollConfig()
+	cfg.TLS = &configuration.TLSConfig{CertFile: "server.crt", KeyFile: "server.key"}
+	server, err = NewTCPServer(cfg, new(MockDatabase), zap.NewNop())
+	assert.Error(t, err)
+	assert.Nil(t, server)
+}
+
+func TestNetpoll_Pipelining(t *testing.T) {
+	mockDB := new(MockDatabase)
+	for i := 0; i < 50; i++ {
+		mockDB.On("HandleQuery", mock.Anything, fmt.Sprintf("GET key_%d", i)).
+			Return(fmt.Sprintf("[ok] value_%d", i))
+	}
+
+	server, _ := startNetpollServer(t, newNetpollConfig(), mockDB)
+
+	// несколько соединений обслуживаются общим пулом воркеров
+	for c := 0; c < 10; c++ {
+		conn, err := net.Dial("tcp", server.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var batch strings.Builder
+		for i := 0; i < 50; i++ {
+			fmt.Fprintf(&batch, "GET key_%d\n", i)
+		}
+		_, err = conn.Write([]byte(batch.String()))
+		require.NoError(t, err)
+
+		reader := bufio.NewReader(conn)
+		for i := 0; i < 50; i++ {
+			response, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("[ok] value_%d\n", i), response)
+		}
+	}
+}
+
+func TestNetpoll_PartialQuery(t *testing.T) {
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "GET first").Return("[ok] 1")
+	mockDB.On("HandleQuery", mock.Anything, "GET last").Return("[ok] 2")
+
+	server, _ := startNetpollServer(t, newNetpollConfig(), mockDB)
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET fi"))
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	_, err = conn.Write([]byte("rst\nGET last"))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	response, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "[ok] 1\n", response)
+
+	require.NoError(t, conn.(*net.TCPConn).CloseWrite())
+	response, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "[ok] 2\n", response)
+
+	_, err = reader.ReadString('\n')
+	assert.Error(t, err)
+}
+
+func TestNetpoll_MessageTooLarge(t *testing.T) {
+	mockDB := new(MockDatabase)
+	server, _ := startNetpollServer(t, newNetpollConfig(), mockDB)
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("SET key " + strings.Repeat("v", 100)))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	response, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "[error] message is too large\n", response)
+
+	_, err = reader.ReadString('\n')
+	assert.Error(t, err)
+	mockDB.AssertNotCalled(t, "HandleQuery", mock.Anything, mock.Anything)
+}
+
+func TestNetpoll_IdleTimeout(t *testing.T) {
+	cfg := newNetpollConfig()
+	cfg.IdleTimeout = 100 * time.Millisecond
+
+	server, _ := startNetpollServer(t, cfg, new(MockDatabase))
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = conn.Read(make([]byte, 16))
+	assert.Error(t, err)
+	assert.False(t, isTimeout(err), "connection must be closed by server, not by client deadline")
+}
+
+func TestNetpoll_DrainInFlightQuery(t *testing.T) {
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "slow query").
+		After(300 * time.Millisecond).
+		Return("slow response")
+
+	server, stop := startNetpollServer(t, newNetpollConfig(), mockDB)
+
+	idle, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer idle.Close()
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("slow query\n"))
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- stop()
+	}()
+
+	reader := bufio.NewReader(conn)
+	response, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "slow response\n", response)
+
+	assert.NoError(t, <-stopped)
+
+	// соединение без запросов закрывается при остановке
+	_, err = idle.Read(make([]byte, 16))
+	assert.Error(t, err)
+}
+
+func TestNetpoll_SlowReader(t *testing.T) {
+	value := strings.Repeat("v", 256<<10)
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "GET big").Return("[ok] " + value)
+	mockDB.On("HandleQuery", mock.Anything, "PING").Return("[ok] PONG")
+
+	cfg := newNetpollConfig()
+	cfg.Workers = 1
+	cfg.MaxMessageSize = 1024
+	server, _ := startNetpollServer(t, cfg, mockDB)
+
+	// ответы не помещаются в буферы сокета, клиент их не читает
+	const requests = 100
+	slow, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer slow.Close()
+	_, err = slow.Write([]byte(strings.Repeat("GET big\n", requests)))
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	// единственный воркер не занят записью медленному клиенту
+	fast, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer fast.Close()
+	require.NoError(t, fast.SetDeadline(time.Now().Add(time.Second)))
+	_, err = fast.Write([]byte("PING\n"))
+	require.NoError(t, err)
+
+	response, err := bufio.NewReader(fast).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "[ok] PONG\n", response)
+
+	// отложенные ответы доходят целиком и по порядку
+	require.NoError(t, slow.SetDeadline(time.Now().Add(5*time.Second)))
+	reader := bufio.NewReaderSize(slow, 512<<10)
+	for i := 0; i < requests; i++ {
+		response, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, "[ok] "+value+"\n", response)
+	}
+}
+
+func TestNetpoll_WaitFailure(t *testing.T) {
+	server, err := NewTCPServer(newNetpollConfig(), new(MockDatabase), zap.NewNop())
+	require.NoError(t, err)
+
+	failed := make(chan struct{})
+	server.poller.(*netpoll).wait = func(int, []syscall.EpollEvent, int) (int, error) {
+		<-failed
+		return 0, syscall.EBADF
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start(context.Background())
+	}()
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	close(failed)
+
+	// сервер останавливается, а не принимает соединения, которые не обслуживаются
+	select {
+	case err := <-stopped:
+		assert.ErrorIs(t, err, syscall.EBADF)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "server is not stopped")
+	}
+
+	_, err = net.Dial("tcp", server.Addr().String())
+	assert.Error(t, err)
+}
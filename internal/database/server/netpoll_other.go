@@ -0,0 +1,22 @@
+//go:build !linux
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// netpoll -- режим netpoll использует epoll и доступен только на linux
+type netpoll struct{}
+
+func newNetpoll(*TCPServer, int) (*netpoll, error) {
+	return nil, errors.New("netpoll mode is supported only on linux")
+}
+
+func (p *netpoll) start(context.Context) {}
+func (p *netpoll) add(net.Conn) error    { return errors.ErrUnsupported }
+func (p *netpoll) failed() <-chan error  { return nil }
+func (p *netpoll) closeIdle()            {}
+func (p *netpoll) close()                {}
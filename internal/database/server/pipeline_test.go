@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"kava/internal/configuration"
+	"kava/internal/database/client"
+)
+
+func startPipelineServer(t *testing.T, mockDB *MockDatabase) *TCPServer {
+	t.Helper()
+
+	cfg := &configuration.TCPServerConfig{
+		Host:           "localhost",
+		Port:           0,
+		MaxConnections: 10,
+		MaxMessageSize: 64,
+		IdleTimeout:    time.Second * 30,
+	}
+
+	server, err := NewTCPServer(cfg, mockDB, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go server.Start(ctx)
+
+	return server
+}
+
+func TestTCPServer_Pipelining(t *testing.T) {
+	mockDB := new(MockDatabase)
+	for i := 0; i < 100; i++ {
+		mockDB.On("HandleQuery", mock.Anything, fmt.Sprintf("GET key_%d", i)).
+			Return(fmt.Sprintf("[ok] value_%d", i))
+	}
+
+	server := startPipelineServer(t, mockDB)
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// все запросы одной записью, последний с \r\n
+	var batch strings.Builder
+	for i := 0; i < 99; i++ {
+		fmt.Fprintf(&batch, "GET key_%d\n", i)
+	}
+	batch.WriteString("GET key_99\r\n")
+
+	_, err = conn.Write([]byte(batch.String()))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	for i := 0; i < 100; i++ {
+		response, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("[ok] value_%d\n", i), response)
+	}
+}
+
+func TestTCPServer_PipeliningPartialQuery(t *testing.T) {
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "GET first").Return("[ok] 1")
+	mockDB.On("HandleQuery", mock.Anything, "GET second").Return("[ok] 2")
+	mockDB.On("HandleQuery", mock.Anything, "GET last").Return("[ok] 3")
+
+	server := startPipelineServer(t, mockDB)
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// ответ на полный запрос не ждет окончания следующего
+	_, err = conn.Write([]byte("GET first\nGET sec"))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	response, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "[ok] 1\n", response)
+
+	_, err = conn.Write([]byte("ond\nGET last"))
+	require.NoError(t, err)
+
+	response, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "[ok] 2\n", response)
+
+	// запрос без перевода строки выполняется при закрытии записи клиентом
+	require.NoError(t, conn.(*net.TCPConn).CloseWrite())
+
+	response, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "[ok] 3\n", response)
+}
+
+func TestTCPServer_MessageTooLarge(t *testing.T) {
+	mockDB := new(MockDatabase)
+	server := startPipelineServer(t, mockDB)
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("SET key " + strings.Repeat("v", 100) + "\n"))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	response, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "[error] message is too large\n", response)
+
+	_, err = reader.ReadString('\n')
+	assert.Error(t, err)
+	mockDB.AssertNotCalled(t, "HandleQuery", mock.Anything, mock.Anything)
+}
+
+func TestTCPServer_SendBatch(t *testing.T) {
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "SET key value").Return("[ok]")
+	mockDB.On("HandleQuery", mock.Anything, "GET key").Return("[ok] value")
+	mockDB.On("HandleQuery", mock.Anything, "DEL key").Return("[ok]")
+
+	server := startPipelineServer(t, mockDB)
+
+	kavaClient, err := client.NewTCPClient(server.Addr().String(), 64, time.Second*5)
+	require.NoError(t, err)
+	defer kavaClient.Close()
+
+	responses, err := kavaClient.SendBatch([][]byte{
+		[]byte("SET key value"),
+		[]byte("GET key\n"),
+		[]byte("DEL key"),
+	})
+	require.NoError(t, err)
+	require.Len(t, responses, 3)
+	assert.Equal(t, "[ok]", string(responses[0]))
+	assert.Equal(t, "[ok] value", string(responses[1]))
+	assert.Equal(t, "[ok]", string(responses[2]))
+
+	// после пачки соединение продолжает работать с одиночными запросами
+	response, err := kavaClient.Send([]byte("GET key"))
+	require.NoError(t, err)
+	assert.Equal(t, "[ok] value", string(response))
+}
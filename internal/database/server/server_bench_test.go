@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kava/internal/configuration"
+)
+
+type staticDatabase struct{}
+
+func (staticDatabase) HandleQuery(context.Context, string) string {
+	return "[ok] value"
+}
+
+// BenchmarkTCPServer -- сравнение горутины на соединение и netpoll с пулом воркеров
+// при большом числе одновременно открытых соединений
+func BenchmarkTCPServer(b *testing.B) {
+	for _, mode := range []string{goroutineMode, netpollMode} {
+		for _, connections := range []int{100, 1000, 5000} {
+			b.Run(fmt.Sprintf("%s/connections=%d", mode, connections), func(b *testing.B) {
+				benchmarkTCPServer(b, mode, connections)
+			})
+		}
+	}
+}
+
+func benchmarkTCPServer(b *testing.B, mode string, connections int) {
+	cfg := &configuration.TCPServerConfig{
+		Host:           "localhost",
+		Port:           0,
+		MaxConnections: connections,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Minute,
+		DrainTimeout:   time.Second,
+		Mode:           mode,
+	}
+
+	server, err := NewTCPServer(cfg, staticDatabase{}, zap.NewNop())
+	if err != nil {
+		b.Skip(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	query := []byte("GET key\n")
+	before := memoryInUse()
+
+	// клиенты открывают соединения заранее, чтобы мерить обработку запросов,
+	// память сервера на соединение считается после первого запроса в каждом
+	clients := make(chan *bufio.ReadWriter, connections)
+	for i := 0; i < connections; i++ {
+		conn, err := net.Dial("tcp", server.Addr().String())
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer conn.Close()
+
+		client := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+		if _, err := client.Write(query); err != nil {
+			b.Fatal(err)
+		}
+		if err := client.Flush(); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := client.ReadString('\n'); err != nil {
+			b.Fatal(err)
+		}
+		clients <- client
+	}
+
+	perConnection := float64(memoryInUse()-before) / float64(connections)
+	b.ReportAllocs()
+	b.SetParallelism(max(1, connections/runtime.GOMAXPROCS(0)))
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		client := <-clients
+		defer func() { clients <- client }()
+
+		for pb.Next() {
+			if _, err := client.Write(query); err != nil {
+				b.Error(err)
+				return
+			}
+			if err := client.Flush(); err != nil {
+				b.Error(err)
+				return
+			}
+			if _, err := client.ReadString('\n'); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+
+	b.ReportMetric(perConnection, "B/conn")
+}
+
+func memoryInUse() int64 {
+	runtime.GC()
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return int64(stats.HeapInuse + stats.StackInuse)
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"kava/internal/common"
+	"kava/internal/database/auth"
+	"math/rand/v2"
+	"strconv"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// connectionIDs -- идентификаторы соединений, общие для всех серверов процесса
+var connectionIDs atomic.Uint64
+
+// session -- состояние соединения, пользователь появляется после AUTH,
+// logger содержит адрес клиента и идентификатор соединения
+type session struct {
+	address      string
+	connectionID string
+	user         *auth.User
+	logger       *zap.Logger
+}
+
+func newSession(address string, logger *zap.Logger) *session {
+	connectionID := strconv.FormatUint(connectionIDs.Add(1), 10)
+	return &session{
+		address:      address,
+		connectionID: connectionID,
+		logger:       logger.With(zap.String("address", address), zap.String("connection_id", connectionID)),
+	}
+}
+
+// requestContext -- контекст запроса с адресом клиента, идентификатором соединения
+// и новым идентификатором запроса
+func (s *session) requestContext(ctx context.Context) context.Context {
+	ctx = common.ContextWithClientAddress(ctx, s.address)
+	ctx = common.ContextWithConnectionID(ctx, s.connectionID)
+	return common.ContextWithRequestID(ctx, newRequestID())
+}
+
+// newRequestID -- случайный 64-битный идентификатор в hex, как trace id в трейсерах
+func newRequestID() string {
+	return fmt.Sprintf("%016x", rand.Uint64())
+}
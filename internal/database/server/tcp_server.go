@@ -1,58 +1,248 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"kava/internal/configuration"
+	"kava/internal/database/auth"
+	"kava/internal/metrics"
 	"kava/pkg/concurrency"
 	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 )
 
+// Режимы обработки соединений
+const (
+	goroutineMode = "goroutine"
+	netpollMode   = "netpoll"
+)
+
+// rejectTimeout -- время на ответ соединению сверх max_connections
+const rejectTimeout = time.Second
+
+const (
+	tooManyConnectionsResponse = "[error] too many connections\n"
+	messageTooLargeResponse    = "[error] message is too large"
+	// queryTimeoutResponse -- ошибка запроса, не уложившегося в query_timeout,
+	// клиенты узнают ее по тексту, поэтому он не зависит от текста ошибки контекста
+	queryTimeoutResponse = "[error] query timeout exceeded"
+)
+
+// ErrDrainTimeout -- соединения не завершились за drain_timeout и были закрыты принудительно
+var ErrDrainTimeout = errors.New("connections draining timed out")
+
 // TCPServer -- структура сервера
 type TCPServer struct {
-	semaphore      concurrency.Semaphore
-	listener       net.Listener
-	maxConnections int
-	bufferSize     configuration.ByteSize
-	idleTimeout    time.Duration
-	database       Database
-	logger         *zap.Logger
+	semaphore     concurrency.Semaphore
+	listener      net.Listener
+	bufferSize    configuration.ByteSize
+	limits        atomic.Pointer[connectionLimits]
+	database      Database
+	authenticator *auth.Authenticator
+	poller        poller
+	logger        *zap.Logger
+
+	mutex       sync.Mutex
+	stopped     atomic.Bool
+	connections sync.WaitGroup
+	active      map[net.Conn]struct{}
+	counter     *atomic.Int64
+	metrics     connectionMetrics
+}
+
+// connectionMetrics -- метрики соединений, общие для всех серверов реестра
+type connectionMetrics struct {
+	accepted *metrics.Counter
+	active   *metrics.Gauge
+	rejected *metrics.Counter
+}
+
+// poller -- мультиплексирование чтения соединений в режиме netpoll
+type poller interface {
+	start(ctx context.Context)
+	add(connection net.Conn) error
+	// failed -- ошибка, после которой соединения больше не обслуживаются
+	failed() <-chan error
+	closeIdle()
+	close()
+}
+
+// TCPServerOption -- необязательная настройка сервера
+type TCPServerOption func(*TCPServer)
+
+// WithAuthenticator -- требует AUTH перед запросами и проверяет права пользователя
+func WithAuthenticator(authenticator *auth.Authenticator) TCPServerOption {
+	return func(s *TCPServer) {
+		s.authenticator = authenticator
+	}
+}
+
+// WithConnectionsCounter -- ведет в counter число открытых соединений,
+// один счетчик может быть общим для нескольких серверов
+func WithConnectionsCounter(counter *atomic.Int64) TCPServerOption {
+	return func(s *TCPServer) {
+		s.counter = counter
+	}
+}
+
+// WithMetrics -- ведет метрики открытых и отклоненных соединений
+func WithMetrics(registry *metrics.Registry) TCPServerOption {
+	return func(s *TCPServer) {
+		s.metrics = connectionMetrics{
+			accepted: registry.NewCounter("kava_connections_accepted_total", "Number of accepted client connections."),
+			active:   registry.NewGauge("kava_connections_active", "Number of open client connections."),
+			rejected: registry.NewCounter("kava_connections_rejected_total", "Number of connections rejected by max_connections."),
+		}
+	}
 }
 
 // NewTCPServer -- конструктор сервера
-func NewTCPServer(cfg *configuration.TCPServerConfig, database Database, logger *zap.Logger) (*TCPServer, error) {
+func NewTCPServer(
+	cfg *configuration.TCPServerConfig,
+	database Database,
+	logger *zap.Logger,
+	options ...TCPServerOption,
+) (*TCPServer, error) {
 
 	if cfg == nil {
 		return nil, errors.New("config is invalid")
 	}
 
-	server := &TCPServer{
-		logger:      logger,
-		database:    database,
-		bufferSize:  cfg.MaxMessageSize,
-		idleTimeout: cfg.IdleTimeout,
-	}
-
 	address := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 	listener, err := net.Listen("tcp", address)
 	if err != nil {
-		return nil, errors.New("failed to listen")
+		return nil, fmt.Errorf("failed to listen: %w", err)
 	}
 
-	server.listener = listener
-	server.maxConnections = cfg.MaxConnections
-	server.semaphore = concurrency.NewSemaphore(cfg.MaxConnections)
+	if cfg.TLS != nil {
+		tlsConfig, err := newTLSConfig(cfg.TLS)
+		if err != nil {
+			_ = listener.Close()
+			return nil, err
+		}
+
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	switch cfg.Mode {
+	case "", goroutineMode:
+	case netpollMode:
+		if cfg.TLS != nil {
+			_ = listener.Close()
+			return nil, errors.New("tls is not supported in netpoll mode")
+		}
+	default:
+		_ = listener.Close()
+		return nil, fmt.Errorf("unknown connection handling mode: %s", cfg.Mode)
+	}
+
+	server := newServer(listener, cfg.MaxMessageSize, tcpLimits(cfg), database, logger, options)
+	if cfg.Mode == netpollMode {
+		poller, err := newNetpoll(server, cfg.Workers)
+		if err != nil {
+			_ = listener.Close()
+			return nil, err
+		}
+		server.poller = poller
+	}
 
 	return server, nil
 }
 
-// Start - запуск сервера
-func (s *TCPServer) Start(ctx context.Context) {
+// connectionLimits -- настройки обработки соединений, общие для TCP и unix сокета,
+// меняются без перезапуска сервера через Reload
+type connectionLimits struct {
+	maxConnections int
+	idleTimeout    time.Duration
+	drainTimeout   time.Duration
+	queryTimeout   time.Duration
+	acquireWait    time.Duration
+}
+
+func tcpLimits(cfg *configuration.TCPServerConfig) *connectionLimits {
+	return &connectionLimits{
+		maxConnections: cfg.MaxConnections,
+		idleTimeout:    cfg.IdleTimeout,
+		drainTimeout:   cfg.DrainTimeout,
+		queryTimeout:   cfg.QueryTimeout,
+		acquireWait:    cfg.MaxConnectionsWait,
+	}
+}
+
+func unixLimits(cfg *configuration.UnixServerConfig) *connectionLimits {
+	return &connectionLimits{
+		maxConnections: cfg.MaxConnections,
+		idleTimeout:    cfg.IdleTimeout,
+		drainTimeout:   cfg.DrainTimeout,
+		queryTimeout:   cfg.QueryTimeout,
+		acquireWait:    cfg.MaxConnectionsWait,
+	}
+}
+
+func newServer(
+	listener net.Listener,
+	bufferSize configuration.ByteSize,
+	limits *connectionLimits,
+	database Database,
+	logger *zap.Logger,
+	options []TCPServerOption,
+) *TCPServer {
+	server := &TCPServer{
+		listener:   listener,
+		semaphore:  concurrency.NewSemaphore(limits.maxConnections),
+		bufferSize: bufferSize,
+		database:   database,
+		logger:     logger,
+		active:     make(map[net.Conn]struct{}),
+	}
+	server.limits.Store(limits)
+
+	for _, option := range options {
+		option(server)
+	}
+
+	return server
+}
+
+// Reload -- применяет max_connections, max_connections_wait и таймауты из новой
+// конфигурации сервера, открытые соединения получают их со следующего запроса.
+// Адрес, режим, TLS и max_message_size меняются только перезапуском
+func (s *TCPServer) Reload(cfg configuration.ServerConfig) error {
+	var limits *connectionLimits
+	switch cfg := cfg.(type) {
+	case *configuration.TCPServerConfig:
+		limits = tcpLimits(cfg)
+	case *configuration.UnixServerConfig:
+		limits = unixLimits(cfg)
+	default:
+		return fmt.Errorf("unexpected server config type %T", cfg)
+	}
+
+	s.limits.Store(limits)
+	s.semaphore.Resize(limits.maxConnections)
+	return nil
+}
+
+// Start - запуск сервера, после отмены контекста перестает принимать соединения
+// и ждет завершения текущих запросов не дольше drain_timeout
+func (s *TCPServer) Start(ctx context.Context) error {
+	// запросы, начатые до остановки, должны завершиться, а не отмениться
+	connectionCtx := context.WithoutCancel(ctx)
+	if s.poller != nil {
+		s.poller.start(connectionCtx)
+		defer s.poller.close()
+	}
+
 	go func() {
 		for {
 			connection, err := s.listener.Accept()
@@ -65,17 +255,183 @@ func (s *TCPServer) Start(ctx context.Context) {
 				continue
 			}
 
-			s.semaphore.Acquire()
+			acquireWait := s.limits.Load().acquireWait
+			if acquireWait == 0 {
+				if !s.semaphore.TryAcquire() {
+					// ответ по TLS начинается с handshake, молчащий клиент
+					// не должен задерживать прием соединений
+					go s.rejectConnection(connection)
+					continue
+				}
+
+				s.serveConnection(connectionCtx, connection)
+				continue
+			}
+
+			// ожидание слота не должно блокировать прием других соединений
 			go func(connection net.Conn) {
-				defer s.semaphore.Release()
-				s.handleConnection(ctx, connection)
+				waitCtx, cancel := context.WithTimeout(ctx, acquireWait)
+				defer cancel()
+
+				if err := s.semaphore.AcquireWithContext(waitCtx); err != nil {
+					s.rejectConnection(connection)
+					return
+				}
+
+				s.serveConnection(connectionCtx, connection)
 			}(connection)
 		}
 	}()
-	<-ctx.Done()
+	var pollErr error
+	select {
+	case <-ctx.Done():
+	case pollErr = <-s.pollerFailed():
+		// принятые соединения больше не обслуживаются, сервер останавливается
+		s.logger.Error("stopping server: netpoll failed", zap.Error(pollErr))
+	}
 	s.listener.Close()
+
+	return errors.Join(pollErr, s.drain())
+}
+
+// pollerFailed -- канал ошибок netpoll, без netpoll канал nil и никогда не готов
+func (s *TCPServer) pollerFailed() <-chan error {
+	if s.poller == nil {
+		return nil
+	}
+
+	return s.poller.failed()
+}
+
+// serveConnection -- обслуживает соединение, слот семафора уже занят
+func (s *TCPServer) serveConnection(ctx context.Context, connection net.Conn) {
+	if !s.trackConnection(connection) {
+		s.semaphore.Release()
+		_ = connection.Close()
+		return
+	}
+
+	if s.poller != nil {
+		if err := s.poller.add(connection); err != nil {
+			s.logger.Warn("failed to add connection to poller", zap.Error(err))
+			s.untrackConnection(connection)
+			s.semaphore.Release()
+			_ = connection.Close()
+		}
+		return
+	}
+
+	go func() {
+		defer s.semaphore.Release()
+		defer s.untrackConnection(connection)
+		s.handleConnection(ctx, connection)
+	}()
+}
+
+// rejectConnection -- отвечает ошибкой и закрывает соединение сверх max_connections,
+// дедлайн ограничивает и TLS handshake, и запись ответа
+func (s *TCPServer) rejectConnection(connection net.Conn) {
+	s.logger.Warn(
+		"connection rejected: too many connections",
+		zap.String("address", connection.RemoteAddr().String()),
+		zap.Int("max_connections", s.limits.Load().maxConnections),
+	)
+	s.metrics.rejected.Inc()
+
+	_ = connection.SetDeadline(time.Now().Add(rejectTimeout))
+	_, _ = connection.Write([]byte(tooManyConnectionsResponse))
+	_ = connection.Close()
+}
+
+// Addr -- адрес, на котором сервер принимает соединения
+func (s *TCPServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *TCPServer) trackConnection(connection net.Conn) bool {
+	tracked := false
+	concurrency.WithLock(&s.mutex, func() {
+		if s.stopped.Load() {
+			return
+		}
+
+		s.connections.Add(1)
+		s.active[connection] = struct{}{}
+		tracked = true
+	})
+
+	if !tracked {
+		return false
+	}
+
+	if s.counter != nil {
+		s.counter.Add(1)
+	}
+	s.metrics.accepted.Inc()
+	s.metrics.active.Inc()
+	return true
+}
+
+func (s *TCPServer) untrackConnection(connection net.Conn) {
+	concurrency.WithLock(&s.mutex, func() {
+		delete(s.active, connection)
+	})
+
+	if s.counter != nil {
+		s.counter.Add(-1)
+	}
+	s.metrics.active.Dec()
+	s.connections.Done()
+}
+
+// drain -- прерывает ожидание новых запросов и ждет завершения текущих
+func (s *TCPServer) drain() error {
+	concurrency.WithLock(&s.mutex, func() {
+		s.stopped.Store(true)
+		for connection := range s.active {
+			_ = connection.SetReadDeadline(time.Now())
+		}
+	})
+
+	// в режиме netpoll соединения без запроса в обработке закрываются сразу
+	if s.poller != nil {
+		s.poller.closeIdle()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		s.connections.Wait()
+		close(drained)
+	}()
+
+	drainTimeout := s.limits.Load().drainTimeout
+	timer := time.NewTimer(drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-drained:
+		return nil
+	case <-timer.C:
+	}
+
+	select {
+	case <-drained:
+		return nil
+	default:
+	}
+
+	concurrency.WithLock(&s.mutex, func() {
+		for connection := range s.active {
+			_ = connection.Close()
+		}
+	})
+
+	s.logger.Warn("connections were closed by drain timeout", zap.Duration("drain_timeout", drainTimeout))
+	return ErrDrainTimeout
 }
 
+// handleConnection -- запросы разделяются переводом строки, клиент может отправить
+// несколько запросов не дожидаясь ответов, ответы возвращаются в том же порядке
 func (s *TCPServer) handleConnection(ctx context.Context, connection net.Conn) {
 	defer func() {
 		if v := recover(); v != nil {
@@ -87,28 +443,151 @@ func (s *TCPServer) handleConnection(ctx context.Context, connection net.Conn) {
 		}
 	}()
 
-	request := make([]byte, s.bufferSize)
+	reader := bufio.NewReaderSize(connection, int(s.bufferSize))
+	writer := bufio.NewWriter(connection)
+	session := newSession(connection.RemoteAddr().String(), s.logger)
 
 	// Обработка запросов в одном соединении с клиентом
 	for {
-		count, err := connection.Read(request)
+		if err := s.setReadDeadline(connection); err != nil {
+			session.logger.Warn("failed to set read deadline", zap.Error(err))
+			break
+		}
+
+		line, err := reader.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			session.logger.Warn(
+				"closing connection: message is too large",
+				zap.Int("max_message_size", int(s.bufferSize)),
+			)
+			_ = s.writeResponse(connection, writer, messageTooLargeResponse, true)
+			break
+		}
+		if err != nil && s.stopped.Load() {
+			break
+		}
+		if len(line) == 0 && err == io.EOF {
+			break
+		}
+		if isTimeout(err) {
+			session.logger.Info(
+				"closing connection: idle timeout exceeded",
+				zap.Duration("idle_timeout", s.limits.Load().idleTimeout),
+			)
+			break
+		}
 		if err != nil && err != io.EOF {
-			s.logger.Warn(
+			session.logger.Warn(
 				"failed to read data",
-				zap.String("address", connection.RemoteAddr().String()),
 				zap.Error(err),
 			)
 			break
 		}
 
-		response := s.database.HandleQuery(ctx, string(request[:count]))
-		if _, err := connection.Write([]byte(response + "\n")); err != nil {
-			s.logger.Warn(
+		// последний запрос без перевода строки перед закрытием записи клиентом
+		closed := err == io.EOF
+		query := trimQuery(string(line))
+		response, timedOut := s.executeQuery(ctx, session, query)
+		flush := closed || timedOut || !hasBufferedQuery(reader)
+		if err := s.writeResponse(connection, writer, response, flush); err != nil {
+			session.logger.Warn(
 				"failed to write data",
-				zap.String("address", connection.RemoteAddr().String()),
 				zap.Error(err),
 			)
 			break
 		}
+
+		if timedOut {
+			session.logger.Warn(
+				"closing connection: query timeout exceeded",
+				zap.Duration("query_timeout", s.limits.Load().queryTimeout),
+			)
+			break
+		}
+
+		if closed {
+			break
+		}
+	}
+}
+
+// executeQuery -- выполняет запрос с учетом прав пользователя и query_timeout,
+// timedOut означает, что соединение нужно закрыть после ответа
+func (s *TCPServer) executeQuery(ctx context.Context, session *session, query string) (response string, timedOut bool) {
+	response, handled := s.authorize(session, query)
+	if handled {
+		return response, false
+	}
+
+	queryCtx, cancel := s.queryContext(session.requestContext(ctx))
+	defer cancel()
+
+	response = s.database.HandleQuery(queryCtx, query)
+	timedOut = errors.Is(queryCtx.Err(), context.DeadlineExceeded)
+	// успешный ответ, полученный после дедлайна, не подменяется: запрос выполнен
+	if timedOut && strings.HasPrefix(response, "[error]") {
+		response = queryTimeoutResponse
+	}
+
+	return response, timedOut
+}
+
+// trimQuery -- отрезает перевод строки, в том числе \r\n
+func trimQuery(line string) string {
+	return strings.TrimRight(line, "\r\n")
+}
+
+// writeResponse -- ответы на пачку запросов копятся в буфере и отправляются
+// одной записью, когда в соединении не осталось прочитанных запросов
+func (s *TCPServer) writeResponse(connection net.Conn, writer *bufio.Writer, response string, flush bool) error {
+	if idleTimeout := s.limits.Load().idleTimeout; idleTimeout != 0 {
+		_ = connection.SetWriteDeadline(time.Now().Add(idleTimeout))
+	}
+
+	if _, err := writer.WriteString(response + "\n"); err != nil {
+		return err
+	}
+
+	if !flush {
+		return nil
+	}
+
+	return writer.Flush()
+}
+
+// hasBufferedQuery -- в буфере уже есть следующий полный запрос
+func hasBufferedQuery(reader *bufio.Reader) bool {
+	buffered, _ := reader.Peek(reader.Buffered())
+	return bytes.IndexByte(buffered, '\n') >= 0
+}
+
+// setReadDeadline -- ограничивает ожидание следующего запроса idle_timeout,
+// во время остановки сервера ожидание прерывается сразу
+func (s *TCPServer) setReadDeadline(connection net.Conn) error {
+	if idleTimeout := s.limits.Load().idleTimeout; idleTimeout != 0 {
+		if err := connection.SetReadDeadline(time.Now().Add(idleTimeout)); err != nil {
+			return err
+		}
+	}
+
+	// drain мог выставить дедлайн до нас, повторяем его
+	if s.stopped.Load() {
+		return connection.SetReadDeadline(time.Now())
+	}
+
+	return nil
+}
+
+func (s *TCPServer) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	queryTimeout := s.limits.Load().queryTimeout
+	if queryTimeout == 0 {
+		return context.WithCancel(ctx)
 	}
+
+	return context.WithTimeout(ctx, queryTimeout)
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
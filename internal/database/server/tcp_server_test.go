@@ -3,13 +3,17 @@ package server
 import (
 	"context"
 	"fmt"
+	"io"
+	"kava/internal/common"
 	"kava/internal/configuration"
+	"kava/internal/metrics"
 	"net"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -101,7 +105,7 @@ func TestTCPServer_HandleConnection(t *testing.T) {
         mockDB.On("HandleQuery", mock.Anything, "test query").Return("test response").Once()
 
         // Отправка тестового запроса
-        _, err = conn.Write([]byte("test query"))
+        _, err = conn.Write([]byte("test query\n"))
         assert.NoError(t, err)
 
         // Чтение ответа
@@ -122,6 +126,9 @@ func TestTCPServer_HandleConnection(t *testing.T) {
             }
         }()
 
+        // Соединение из предыдущего теста должно освободить слот
+        time.Sleep(100 * time.Millisecond)
+
         // Установка максимального количества соединений
         for i := 0; i < cfg.MaxConnections; i++ {
             conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", addr.Port))
@@ -129,26 +136,19 @@ func TestTCPServer_HandleConnection(t *testing.T) {
             connections = append(connections, conn)
         }
 
-        // Попытка получить ответ в дополнительном соединении
-        // Должно завершиться по таймауту
+        // Дополнительное соединение сразу получает отказ
         conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", addr.Port))
-		// Отправка тестового запроса
-		_, err = conn.Write([]byte("test query"))
-		assert.NoError(t, err)
+        assert.NoError(t, err)
+        defer conn.Close()
 
-		// Чтение ответа
-		buffer := make([]byte, 1024)
-		conn.SetReadDeadline(time.Now().Add(time.Second * 3))
-		_, err = conn.Read(buffer)
-		assert.Error(t, err)
-		if err != nil {
-			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-				conn.Close()
-				return
-			}
-		}
-		conn.Close()
-		t.Error("Expected connection to fail due to max connections limit")
+        buffer := make([]byte, 1024)
+        conn.SetReadDeadline(time.Now().Add(time.Second * 3))
+        n, err := conn.Read(buffer)
+        assert.NoError(t, err)
+        assert.Equal(t, "[error] too many connections\n", string(buffer[:n]))
+
+        _, err = conn.Read(buffer)
+        assert.ErrorIs(t, err, io.EOF)
     })
 }
 
@@ -189,3 +189,398 @@ func TestTCPServer_Shutdown(t *testing.T) {
     _, err = net.Dial("tcp", fmt.Sprintf("localhost:%d", addr.Port))
     assert.Error(t, err)
 }
+
+// TestTCPServer_DrainInFlightQuery - текущий запрос завершается при остановке сервера
+func TestTCPServer_DrainInFlightQuery(t *testing.T) {
+	logger := zap.NewNop()
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "slow query").
+		After(300 * time.Millisecond).
+		Return("slow response")
+
+	cfg := &configuration.TCPServerConfig{
+		Host:           "localhost",
+		Port:           0,
+		MaxConnections: 10,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Second * 30,
+		DrainTimeout:   time.Second * 5,
+	}
+
+	server, err := NewTCPServer(cfg, mockDB, logger)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start(ctx)
+	}()
+
+	addr := server.listener.Addr().(*net.TCPAddr)
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", addr.Port))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	idleConn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", addr.Port))
+	assert.NoError(t, err)
+	defer idleConn.Close()
+
+	_, err = conn.Write([]byte("slow query\n"))
+	assert.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	buffer := make([]byte, 1024)
+	n, err := conn.Read(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, "slow response\n", string(buffer[:n]))
+
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(time.Second * 3):
+		t.Error("Start did not return after draining")
+	}
+}
+
+// TestTCPServer_DrainTimeout - зависшие запросы прерываются по drain_timeout
+func TestTCPServer_DrainTimeout(t *testing.T) {
+	logger := zap.NewNop()
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "stuck query").
+		After(2 * time.Second).
+		Return("stuck response")
+
+	cfg := &configuration.TCPServerConfig{
+		Host:           "localhost",
+		Port:           0,
+		MaxConnections: 10,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Second * 30,
+		DrainTimeout:   time.Millisecond * 100,
+	}
+
+	server, err := NewTCPServer(cfg, mockDB, logger)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start(ctx)
+	}()
+
+	addr := server.listener.Addr().(*net.TCPAddr)
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", addr.Port))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("stuck query\n"))
+	assert.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-stopped:
+		assert.ErrorIs(t, err, ErrDrainTimeout)
+	case <-time.After(time.Second):
+		t.Error("Start did not return after drain timeout")
+	}
+}
+
+// TestTCPServer_IdleTimeout - простаивающее соединение закрывается по idle_timeout
+func TestTCPServer_IdleTimeout(t *testing.T) {
+	logger := zap.NewNop()
+	mockDB := new(MockDatabase)
+
+	cfg := &configuration.TCPServerConfig{
+		Host:           "localhost",
+		Port:           0,
+		MaxConnections: 1,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Millisecond * 100,
+	}
+
+	server, err := NewTCPServer(cfg, mockDB, logger)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second * 2))
+	buffer := make([]byte, 1024)
+	_, err = conn.Read(buffer)
+	assert.ErrorIs(t, err, io.EOF)
+
+	// слот семафора освобожден, новое соединение обслуживается
+	mockDB.On("HandleQuery", mock.Anything, "GET key").Return("[ok] value").Once()
+	conn, err = net.Dial("tcp", server.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET key\n"))
+	assert.NoError(t, err)
+	conn.SetReadDeadline(time.Now().Add(time.Second * 2))
+	n, err := conn.Read(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, "[ok] value\n", string(buffer[:n]))
+}
+
+// TestTCPServer_QueryTimeout - запрос получает дедлайн, соединение закрывается после его превышения
+func TestTCPServer_QueryTimeout(t *testing.T) {
+	logger := zap.NewNop()
+	mockDB := new(MockDatabase)
+	hasDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ok
+	})
+	mockDB.On("HandleQuery", hasDeadline, "GET key").Return("[ok] value").Once()
+	mockDB.On("HandleQuery", hasDeadline, "GET slow").
+		After(200 * time.Millisecond).
+		Return("[error] context deadline exceeded").
+		Once()
+
+	cfg := &configuration.TCPServerConfig{
+		Host:           "localhost",
+		Port:           0,
+		MaxConnections: 1,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Second * 30,
+		QueryTimeout:   time.Millisecond * 50,
+	}
+
+	server, err := NewTCPServer(cfg, mockDB, logger)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second * 2))
+
+	buffer := make([]byte, 1024)
+	_, err = conn.Write([]byte("GET key\n"))
+	assert.NoError(t, err)
+	n, err := conn.Read(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, "[ok] value\n", string(buffer[:n]))
+
+	_, err = conn.Write([]byte("GET slow\n"))
+	assert.NoError(t, err)
+	n, err = conn.Read(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, "[error] query timeout exceeded\n", string(buffer[:n]))
+
+	_, err = conn.Read(buffer)
+	assert.ErrorIs(t, err, io.EOF)
+	mockDB.AssertExpectations(t)
+}
+
+// TestTCPServer_MaxConnectionsWait - соединение ждет освобождения слота не дольше max_connections_wait
+func TestTCPServer_MaxConnectionsWait(t *testing.T) {
+	logger := zap.NewNop()
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "GET key").Return("[ok] value")
+
+	cfg := &configuration.TCPServerConfig{
+		Host:               "localhost",
+		Port:               0,
+		MaxConnections:     1,
+		MaxMessageSize:     1024,
+		IdleTimeout:        time.Second * 30,
+		MaxConnectionsWait: time.Millisecond * 500,
+	}
+
+	server, err := NewTCPServer(cfg, mockDB, logger)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+
+	first, err := net.Dial("tcp", server.Addr().String())
+	assert.NoError(t, err)
+
+	queued, err := net.Dial("tcp", server.Addr().String())
+	assert.NoError(t, err)
+	defer queued.Close()
+
+	// освобождаем слот, пока второе соединение в очереди
+	time.Sleep(100 * time.Millisecond)
+	first.Close()
+
+	buffer := make([]byte, 1024)
+	_, err = queued.Write([]byte("GET key\n"))
+	assert.NoError(t, err)
+	queued.SetReadDeadline(time.Now().Add(time.Second * 2))
+	n, err := queued.Read(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, "[ok] value\n", string(buffer[:n]))
+
+	// слот занят, третье соединение получает отказ по истечении ожидания
+	rejected, err := net.Dial("tcp", server.Addr().String())
+	assert.NoError(t, err)
+	defer rejected.Close()
+
+	startedAt := time.Now()
+	rejected.SetReadDeadline(time.Now().Add(time.Second * 2))
+	n, err = rejected.Read(buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, "[error] too many connections\n", string(buffer[:n]))
+	assert.GreaterOrEqual(t, time.Since(startedAt), time.Millisecond*400)
+}
+
+func TestTCPServer_Metrics(t *testing.T) {
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "PING").Return("[ok] PONG")
+
+	cfg := &configuration.TCPServerConfig{
+		Host:           "localhost",
+		MaxConnections: 1,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Minute,
+	}
+
+	registry := metrics.NewRegistry()
+	server, err := NewTCPServer(cfg, mockDB, zap.NewNop(), WithMetrics(registry))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+
+	// ответ на запрос означает, что соединение уже учтено
+	_, err = conn.Write([]byte("PING\n"))
+	require.NoError(t, err)
+	buffer := make([]byte, 1024)
+	_, err = conn.Read(buffer)
+	require.NoError(t, err)
+
+	rejected, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	_, _ = rejected.Read(buffer)
+	_ = rejected.Close()
+
+	assert.Equal(t, 1.0, registry.NewGauge("kava_connections_active", "").Value())
+	assert.Equal(t, 1.0, registry.NewCounter("kava_connections_accepted_total", "").Value())
+	assert.Equal(t, 1.0, registry.NewCounter("kava_connections_rejected_total", "").Value())
+
+	require.NoError(t, conn.Close())
+	assert.Eventually(t, func() bool {
+		return registry.NewGauge("kava_connections_active", "").Value() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTCPServer_RequestContext(t *testing.T) {
+	contexts := make(chan context.Context, 3)
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "PING").
+		Run(func(args mock.Arguments) {
+			contexts <- args.Get(0).(context.Context)
+		}).
+		Return("[ok] PONG")
+
+	cfg := &configuration.TCPServerConfig{
+		Host:           "localhost",
+		MaxConnections: 2,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Minute,
+	}
+
+	server, err := NewTCPServer(cfg, mockDB, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+
+	query := func(conn net.Conn) context.Context {
+		_, err := conn.Write([]byte("PING\n"))
+		require.NoError(t, err)
+		buffer := make([]byte, 1024)
+		_, err = conn.Read(buffer)
+		require.NoError(t, err)
+		return <-contexts
+	}
+
+	first, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+
+	firstCtx := query(first)
+	repeatedCtx := query(first)
+	secondCtx := query(second)
+
+	// идентификатор соединения общий для его запросов, идентификатор запроса - нет
+	assert.NotEmpty(t, common.GetConnectionIDFromContext(firstCtx))
+	assert.Equal(t, common.GetConnectionIDFromContext(firstCtx), common.GetConnectionIDFromContext(repeatedCtx))
+	assert.NotEqual(t, common.GetConnectionIDFromContext(firstCtx), common.GetConnectionIDFromContext(secondCtx))
+
+	assert.Len(t, common.GetRequestIDFromContext(firstCtx), 16)
+	assert.NotEqual(t, common.GetRequestIDFromContext(firstCtx), common.GetRequestIDFromContext(repeatedCtx))
+	assert.Equal(t, first.LocalAddr().String(), common.GetClientAddressFromContext(firstCtx))
+}
+
+func TestTCPServer_Reload(t *testing.T) {
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "PING").Return("[ok] PONG")
+
+	cfg := &configuration.TCPServerConfig{
+		Host:           "localhost",
+		MaxConnections: 1,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Minute,
+	}
+
+	server, err := NewTCPServer(cfg, mockDB, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+
+	ping := func(conn net.Conn) string {
+		_, err := conn.Write([]byte("PING\n"))
+		require.NoError(t, err)
+		buffer := make([]byte, 1024)
+		count, err := conn.Read(buffer)
+		require.NoError(t, err)
+		return string(buffer[:count])
+	}
+
+	first, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer first.Close()
+	assert.Equal(t, "[ok] PONG\n", ping(first))
+
+	rejected, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	assert.Equal(t, tooManyConnectionsResponse, ping(rejected))
+	_ = rejected.Close()
+
+	reloaded := *cfg
+	reloaded.MaxConnections = 2
+	require.NoError(t, server.Reload(&reloaded))
+
+	second, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+	assert.Equal(t, "[ok] PONG\n", ping(second))
+
+	assert.Error(t, server.Reload(&configuration.ConsoleConfig{}))
+}
@@ -0,0 +1,109 @@
+//go:build linux
+
+package server
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// terminal -- переключает терминал в посимвольный режим на время ввода строки,
+// сигналы (Ctrl-C) и обработка вывода остаются включенными
+type terminal struct {
+	fd       uintptr
+	mutex    sync.Mutex
+	original *syscall.Termios
+	closed   bool
+}
+
+var errTerminalClosed = errors.New("terminal is closed")
+
+// newTerminal -- nil, если in не является терминалом
+func newTerminal(in io.Reader) *terminal {
+	file, ok := in.(*os.File)
+	if !ok {
+		return nil
+	}
+
+	var termios syscall.Termios
+	if err := ioctl(file.Fd(), syscall.TCGETS, &termios); err != nil {
+		return nil
+	}
+
+	return &terminal{fd: file.Fd()}
+}
+
+func (t *terminal) makeRaw() error {
+	if t == nil {
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.closed {
+		return errTerminalClosed
+	}
+
+	var original syscall.Termios
+	if err := ioctl(t.fd, syscall.TCGETS, &original); err != nil {
+		return err
+	}
+
+	raw := original
+	raw.Iflag &^= syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(t.fd, syscall.TCSETS, &raw); err != nil {
+		return err
+	}
+
+	t.original = &original
+	return nil
+}
+
+// restore -- возвращает исходный режим, повторный вызов ничего не делает
+func (t *terminal) restore() {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.restoreLocked()
+}
+
+// close -- возвращает исходный режим и больше не дает перейти в посимвольный
+func (t *terminal) close() {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.restoreLocked()
+	t.closed = true
+}
+
+func (t *terminal) restoreLocked() {
+	if t.original != nil {
+		_ = ioctl(t.fd, syscall.TCSETS, t.original)
+		t.original = nil
+	}
+}
+
+func ioctl(fd uintptr, request uintptr, termios *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
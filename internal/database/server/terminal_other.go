@@ -0,0 +1,21 @@
+//go:build !linux
+
+package server
+
+import "io"
+
+// terminal -- посимвольный ввод поддерживается только на linux,
+// на остальных системах консоль читает строки целиком
+type terminal struct{}
+
+func newTerminal(io.Reader) *terminal {
+	return nil
+}
+
+func (t *terminal) makeRaw() error {
+	return nil
+}
+
+func (t *terminal) restore() {}
+
+func (t *terminal) close() {}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"kava/internal/configuration"
+	"kava/internal/database/client"
+)
+
+type testCertificates struct {
+	caFile         string
+	serverCertFile string
+	serverKeyFile  string
+	clientCertFile string
+	clientKeyFile  string
+}
+
+// generateCertificates - выпускает CA, сертификат сервера для localhost и сертификат клиента
+func generateCertificates(t *testing.T) testCertificates {
+	t.Helper()
+
+	directory := t.TempDir()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kava test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	writePEM := func(name, blockType string, data []byte) string {
+		filename := filepath.Join(directory, name)
+		err := os.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600)
+		require.NoError(t, err)
+		return filename
+	}
+
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage) (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			DNSNames:     []string{"localhost"},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+
+		return writePEM(name+".crt", "CERTIFICATE", der), writePEM(name+".key", "EC PRIVATE KEY", keyDER)
+	}
+
+	certificates := testCertificates{caFile: writePEM("ca.crt", "CERTIFICATE", caDER)}
+	certificates.serverCertFile, certificates.serverKeyFile = issue("server", 2, x509.ExtKeyUsageServerAuth)
+	certificates.clientCertFile, certificates.clientKeyFile = issue("client", 3, x509.ExtKeyUsageClientAuth)
+	return certificates
+}
+
+func startTLSServer(t *testing.T, tlsCfg *configuration.TLSConfig) *TCPServer {
+	t.Helper()
+
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "GET key").Return("[ok] value")
+
+	cfg := &configuration.TCPServerConfig{
+		Host:           "localhost",
+		Port:           0,
+		MaxConnections: 10,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Second * 30,
+		TLS:            tlsCfg,
+	}
+
+	server, err := NewTCPServer(cfg, mockDB, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go server.Start(ctx)
+
+	return server
+}
+
+func TestNewTCPServerWithInvalidTLS(t *testing.T) {
+	certificates := generateCertificates(t)
+
+	tests := map[string]*configuration.TLSConfig{
+		"missing certificate": {
+			CertFile: "missing.crt",
+			KeyFile:  "missing.key",
+		},
+		"client certificates without ca": {
+			CertFile:          certificates.serverCertFile,
+			KeyFile:           certificates.serverKeyFile,
+			RequireClientCert: true,
+		},
+		"invalid ca file": {
+			CertFile: certificates.serverCertFile,
+			KeyFile:  certificates.serverKeyFile,
+			CAFile:   certificates.serverKeyFile,
+		},
+	}
+
+	for name, tlsCfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := &configuration.TCPServerConfig{
+				Host:           "localhost",
+				MaxConnections: 1,
+				MaxMessageSize: 1024,
+				TLS:            tlsCfg,
+			}
+
+			server, err := NewTCPServer(cfg, new(MockDatabase), zap.NewNop())
+			assert.Error(t, err)
+			assert.Nil(t, server)
+		})
+	}
+}
+
+func TestTCPServer_TLS(t *testing.T) {
+	certificates := generateCertificates(t)
+	server := startTLSServer(t, &configuration.TLSConfig{
+		CertFile: certificates.serverCertFile,
+		KeyFile:  certificates.serverKeyFile,
+	})
+
+	tlsConfig, err := client.NewTLSConfig("", "", certificates.caFile, "")
+	require.NoError(t, err)
+
+	kavaClient, err := client.NewTLSClient(server.Addr().String(), 1024, time.Second*5, tlsConfig)
+	require.NoError(t, err)
+	defer kavaClient.Close()
+
+	response, err := kavaClient.Send([]byte("GET key"))
+	require.NoError(t, err)
+	assert.Equal(t, "[ok] value", string(response))
+
+	// сертификат сервера не проверяется без CA
+	untrusted, err := client.NewTLSConfig("", "", "", "")
+	require.NoError(t, err)
+	_, err = client.NewTLSClient(server.Addr().String(), 1024, time.Second*5, untrusted)
+	assert.Error(t, err)
+}
+
+func TestTCPServer_MutualTLS(t *testing.T) {
+	certificates := generateCertificates(t)
+	server := startTLSServer(t, &configuration.TLSConfig{
+		CertFile:          certificates.serverCertFile,
+		KeyFile:           certificates.serverKeyFile,
+		CAFile:            certificates.caFile,
+		RequireClientCert: true,
+	})
+
+	tlsConfig, err := client.NewTLSConfig(
+		certificates.clientCertFile,
+		certificates.clientKeyFile,
+		certificates.caFile,
+		"localhost",
+	)
+	require.NoError(t, err)
+
+	kavaClient, err := client.NewTLSClient(server.Addr().String(), 1024, time.Second*5, tlsConfig)
+	require.NoError(t, err)
+	defer kavaClient.Close()
+
+	response, err := kavaClient.Send([]byte("GET key"))
+	require.NoError(t, err)
+	assert.Equal(t, "[ok] value", string(response))
+
+	// без сертификата клиента сервер обрывает handshake
+	anonymous, err := client.NewTLSConfig("", "", certificates.caFile, "localhost")
+	require.NoError(t, err)
+
+	anonymousClient, err := client.NewTLSClient(server.Addr().String(), 1024, time.Second*5, anonymous)
+	if err == nil {
+		defer anonymousClient.Close()
+		_, err = anonymousClient.Send([]byte("GET key"))
+	}
+	assert.Error(t, err)
+}
+
+func TestTCPServer_TLSRejectSilentClient(t *testing.T) {
+	certificates := generateCertificates(t)
+
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "GET key").Return("[ok] value")
+
+	server, err := NewTCPServer(&configuration.TCPServerConfig{
+		Host:           "localhost",
+		MaxConnections: 1,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Second * 30,
+		TLS: &configuration.TLSConfig{
+			CertFile: certificates.serverCertFile,
+			KeyFile:  certificates.serverKeyFile,
+		},
+	}, mockDB, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go server.Start(ctx)
+
+	tlsConfig, err := client.NewTLSConfig("", "", certificates.caFile, "")
+	require.NoError(t, err)
+
+	kavaClient, err := client.NewTLSClient(server.Addr().String(), 1024, time.Second*5, tlsConfig)
+	require.NoError(t, err)
+	defer kavaClient.Close()
+	_, err = kavaClient.Send([]byte("GET key"))
+	require.NoError(t, err)
+
+	// соединение сверх предела без ClientHello
+	silent, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer silent.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	// следующий клиент получает отказ, не дожидаясь молчащего
+	rejected, err := tls.Dial("tcp", server.Addr().String(), tlsConfig)
+	require.NoError(t, err)
+	defer rejected.Close()
+	require.NoError(t, rejected.SetDeadline(time.Now().Add(rejectTimeout/2)))
+
+	buffer := make([]byte, 64)
+	n, err := rejected.Read(buffer)
+	require.NoError(t, err)
+	assert.Equal(t, tooManyConnectionsResponse, string(buffer[:n]))
+
+	// молчащее соединение закрывается по дедлайну отказа
+	require.NoError(t, silent.SetReadDeadline(time.Now().Add(2*rejectTimeout)))
+	_, err = io.ReadAll(silent)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"kava/internal/configuration"
+	"net"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// NewUnixServer -- сервер на unix сокете, соединения обрабатываются так же, как в TCPServer
+func NewUnixServer(
+	cfg *configuration.UnixServerConfig,
+	database Database,
+	logger *zap.Logger,
+	options ...TCPServerOption,
+) (*TCPServer, error) {
+	if cfg == nil {
+		return nil, errors.New("config is invalid")
+	}
+
+	if cfg.Path == "" {
+		return nil, errors.New("socket path is invalid")
+	}
+
+	if err := removeStaleSocket(cfg.Path); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	if cfg.Mode != 0 {
+		if err := os.Chmod(cfg.Path, os.FileMode(cfg.Mode)); err != nil {
+			_ = listener.Close()
+			return nil, fmt.Errorf("failed to set socket mode: %w", err)
+		}
+	}
+
+	return newServer(listener, cfg.MaxMessageSize, unixLimits(cfg), database, logger, options), nil
+}
+
+// removeStaleSocket -- удаляет сокет, оставшийся после аварийной остановки,
+// но не трогает обычные файлы и сокет работающего сервера
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	if connection, err := net.Dial("unix", path); err == nil {
+		_ = connection.Close()
+		return fmt.Errorf("socket %s is already in use", path)
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"kava/internal/configuration"
+	"kava/internal/database/client"
+)
+
+func newUnixServerConfig(path string) *configuration.UnixServerConfig {
+	return &configuration.UnixServerConfig{
+		Path:           path,
+		Mode:           0600,
+		MaxConnections: 10,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Second * 30,
+		DrainTimeout:   time.Second,
+	}
+}
+
+func TestNewUnixServer(t *testing.T) {
+	directory := t.TempDir()
+
+	regularFile := filepath.Join(directory, "regular")
+	require.NoError(t, os.WriteFile(regularFile, nil, 0600))
+
+	tests := map[string]struct {
+		cfg *configuration.UnixServerConfig
+
+		expectedErr bool
+	}{
+		"nil configuration":   {expectedErr: true},
+		"empty path":          {cfg: newUnixServerConfig(""), expectedErr: true},
+		"path is not socket":  {cfg: newUnixServerConfig(regularFile), expectedErr: true},
+		"valid configuration": {cfg: newUnixServerConfig(filepath.Join(directory, "kava.sock"))},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server, err := NewUnixServer(test.cfg, new(MockDatabase), zap.NewNop())
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Nil(t, server)
+				return
+			}
+
+			require.NoError(t, err)
+			defer server.listener.Close()
+
+			info, err := os.Stat(test.cfg.Path)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		})
+	}
+}
+
+func TestUnixServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kava.sock")
+
+	mockDB := new(MockDatabase)
+	mockDB.On("HandleQuery", mock.Anything, "GET key").Return("[ok] value")
+
+	server, err := NewUnixServer(newUnixServerConfig(path), mockDB, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() {
+		stopped <- server.Start(ctx)
+	}()
+
+	// второй сервер не должен занять сокет работающего
+	_, err = NewUnixServer(newUnixServerConfig(path), mockDB, zap.NewNop())
+	assert.Error(t, err)
+
+	kavaClient, err := client.NewTCPClient("unix://"+path, 1024, time.Second*5)
+	require.NoError(t, err)
+
+	response, err := kavaClient.Send([]byte("GET key"))
+	require.NoError(t, err)
+	assert.Equal(t, "[ok] value", string(response))
+	kavaClient.Close()
+
+	cancel()
+	require.NoError(t, <-stopped)
+
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestUnixServerRemovesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kava.sock")
+
+	// сокет остается на диске, если сервер не удалил его при остановке
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, listener.Close())
+
+	server, err := NewUnixServer(newUnixServerConfig(path), new(MockDatabase), zap.NewNop())
+	require.NoError(t, err)
+	assert.NoError(t, server.listener.Close())
+}
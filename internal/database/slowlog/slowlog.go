@@ -0,0 +1,127 @@
+package slowlog
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Entry -- запрос, который выполнялся дольше порога
+type Entry struct {
+	ID        int64
+	Timestamp time.Time
+	Duration  time.Duration
+	Command   string
+	Key       string
+	Client    string
+	RequestID string
+}
+
+// SlowLog -- последние медленные запросы в кольцевом буфере, самые старые
+// записи вытесняются новыми. Методы nil журнала ничего не делают
+type SlowLog struct {
+	threshold time.Duration
+	logger    *zap.Logger
+
+	mutex   sync.Mutex
+	entries []Entry
+	next    int
+	size    int
+	lastID  int64
+}
+
+// NewSlowLog -- конструктор, logger задается, если записи нужно дублировать в лог
+func NewSlowLog(threshold time.Duration, maxLength int, logger *zap.Logger) (*SlowLog, error) {
+	if threshold < 0 {
+		return nil, errors.New("threshold is invalid")
+	}
+
+	if maxLength <= 0 {
+		return nil, errors.New("max length is invalid")
+	}
+
+	return &SlowLog{
+		threshold: threshold,
+		logger:    logger,
+		entries:   make([]Entry, maxLength),
+	}, nil
+}
+
+// Record -- запоминает запрос, если он выполнялся дольше порога
+func (s *SlowLog) Record(entry Entry) {
+	if s == nil || entry.Duration <= s.threshold {
+		return
+	}
+
+	s.mutex.Lock()
+	s.lastID++
+	entry.ID = s.lastID
+	s.entries[s.next] = entry
+	s.next = (s.next + 1) % len(s.entries)
+	s.size = min(s.size+1, len(s.entries))
+	s.mutex.Unlock()
+
+	if s.logger != nil {
+		s.logger.Warn(
+			"slow query",
+			zap.Int64("id", entry.ID),
+			zap.String("command", entry.Command),
+			zap.String("key", entry.Key),
+			zap.Duration("duration", entry.Duration),
+			zap.String("address", entry.Client),
+			zap.String("request_id", entry.RequestID),
+			zap.Time("timestamp", entry.Timestamp),
+		)
+	}
+}
+
+// Get -- не больше count последних записей, начиная с самой новой,
+// count <= 0 возвращает все записи
+func (s *SlowLog) Get(count int) []Entry {
+	if s == nil {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if count <= 0 || count > s.size {
+		count = s.size
+	}
+
+	entries := make([]Entry, 0, count)
+	for i := 1; i <= count; i++ {
+		index := (s.next - i + len(s.entries)) % len(s.entries)
+		entries = append(entries, s.entries[index])
+	}
+
+	return entries
+}
+
+// Len -- число записей в журнале
+func (s *SlowLog) Len() int {
+	if s == nil {
+		return 0
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.size
+}
+
+// Reset -- очищает журнал, идентификаторы записей продолжают расти
+func (s *SlowLog) Reset() {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	clear(s.entries)
+	s.next = 0
+	s.size = 0
+}
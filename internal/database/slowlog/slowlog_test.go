@@ -0,0 +1,99 @@
+package slowlog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNewSlowLog(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewSlowLog(-time.Second, 10, nil)
+	assert.Error(t, err)
+
+	_, err = NewSlowLog(time.Millisecond, 0, nil)
+	assert.Error(t, err)
+
+	slowLog, err := NewSlowLog(time.Millisecond, 10, nil)
+	require.NoError(t, err)
+	assert.NotNil(t, slowLog)
+}
+
+func TestSlowLog_Record(t *testing.T) {
+	t.Parallel()
+
+	slowLog, err := NewSlowLog(10*time.Millisecond, 3, nil)
+	require.NoError(t, err)
+
+	slowLog.Record(Entry{Command: "GET", Key: "fast", Duration: time.Millisecond})
+	slowLog.Record(Entry{Command: "GET", Key: "threshold", Duration: 10 * time.Millisecond})
+	assert.Zero(t, slowLog.Len())
+
+	for i := 1; i <= 5; i++ {
+		slowLog.Record(Entry{Command: "SET", Key: fmt.Sprintf("key%d", i), Duration: time.Second})
+	}
+
+	// в буфере остаются три последние записи, самая новая первой
+	entries := slowLog.Get(0)
+	require.Len(t, entries, 3)
+	assert.Equal(t, int64(5), entries[0].ID)
+	assert.Equal(t, "key5", entries[0].Key)
+	assert.Equal(t, "key4", entries[1].Key)
+	assert.Equal(t, "key3", entries[2].Key)
+
+	entries = slowLog.Get(2)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "key5", entries[0].Key)
+	assert.Equal(t, "key4", entries[1].Key)
+}
+
+func TestSlowLog_Reset(t *testing.T) {
+	t.Parallel()
+
+	slowLog, err := NewSlowLog(0, 3, nil)
+	require.NoError(t, err)
+
+	slowLog.Record(Entry{Command: "GET", Key: "key1", Duration: time.Second})
+	slowLog.Reset()
+	assert.Zero(t, slowLog.Len())
+	assert.Empty(t, slowLog.Get(0))
+
+	slowLog.Record(Entry{Command: "GET", Key: "key2", Duration: time.Second})
+	entries := slowLog.Get(0)
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(2), entries[0].ID)
+}
+
+func TestSlowLog_Logger(t *testing.T) {
+	t.Parallel()
+
+	core, logs := observer.New(zap.WarnLevel)
+	slowLog, err := NewSlowLog(0, 3, zap.New(core))
+	require.NoError(t, err)
+
+	slowLog.Record(Entry{Command: "SET", Key: "key", Client: "127.0.0.1:5000", Duration: time.Second})
+
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0]
+	assert.Equal(t, "slow query", entry.Message)
+	assert.Equal(t, "SET", entry.ContextMap()["command"])
+	assert.Equal(t, "127.0.0.1:5000", entry.ContextMap()["address"])
+}
+
+func TestSlowLog_Nil(t *testing.T) {
+	t.Parallel()
+
+	var slowLog *SlowLog
+	require.NotPanics(t, func() {
+		slowLog.Record(Entry{Duration: time.Second})
+		slowLog.Reset()
+	})
+	assert.Zero(t, slowLog.Len())
+	assert.Nil(t, slowLog.Get(0))
+}
@@ -5,9 +5,15 @@ import (
 	"errors"
 	"sync"
 
+	"kava/internal/common"
+
 	"go.uber.org/zap"
 )
 
+// entryOverhead -- примерный расход памяти map на одну запись
+// помимо самих строк: заголовки строк и служебные данные бакета
+const entryOverhead = 64
+
 // NewEngine - конструктор движка
 func NewEngine(logger *zap.Logger) (*Engine, error) {
 	if logger == nil {
@@ -26,6 +32,7 @@ func NewEngine(logger *zap.Logger) (*Engine, error) {
 type Engine struct {
 	mu     sync.RWMutex
 	data   map[string]string
+	memory int64
 	logger *zap.Logger
 }
 
@@ -34,12 +41,19 @@ func (e *Engine) Set(ctx context.Context, key, value string) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	if previous, exist := e.data[key]; exist {
+		e.memory -= entrySize(key, previous)
+	}
 	e.data[key] = value
+	e.memory += entrySize(key, value)
 	e.logger.Debug(
 		"successfull set query",
-		zap.String("msg", "SET"),
-		zap.String("key", key),
-		zap.String("value", value))
+		common.LogFields(
+			ctx,
+			zap.String("msg", "SET"),
+			zap.String("key", key),
+			zap.String("value", value),
+		)...)
 }
 
 // Get - возвращает значение по ключу
@@ -51,14 +65,12 @@ func (e *Engine) Get(ctx context.Context, key string) (string, bool) {
 	if exist {
 		e.logger.Debug(
 			"successfull get query",
-			zap.String("msg", "GET"),
-			zap.String("key", key),
+			common.LogFields(ctx, zap.String("msg", "GET"), zap.String("key", key))...,
 		)
 	} else {
 		e.logger.Debug(
 			"key not found",
-			zap.String("msg", "key not found"),
-			zap.String("key", key),
+			common.LogFields(ctx, zap.String("msg", "key not found"), zap.String("key", key))...,
 		)
 	}
 
@@ -68,11 +80,42 @@ func (e *Engine) Get(ctx context.Context, key string) (string, bool) {
 // Del - удаляет значение по ключу
 func (e *Engine) Del(ctx context.Context, key string) {
 	e.mu.Lock()
-	delete(e.data, key)
+	if value, exist := e.data[key]; exist {
+		e.memory -= entrySize(key, value)
+		delete(e.data, key)
+	}
 	e.mu.Unlock()
 	e.logger.Debug(
 		"successfull del query",
-		zap.String("msg", "DEL"),
-		zap.String("key", key),
+		common.LogFields(ctx, zap.String("msg", "DEL"), zap.String("key", key))...,
 	)
 }
+
+// Clear - удаляет все значения
+func (e *Engine) Clear(ctx context.Context) {
+	e.mu.Lock()
+	e.data = make(map[string]string)
+	e.memory = 0
+	e.mu.Unlock()
+	e.logger.Debug("successfull flushall query", common.LogFields(ctx, zap.String("msg", "FLUSHALL"))...)
+}
+
+// Size - количество ключей
+func (e *Engine) Size() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return len(e.data)
+}
+
+// MemoryUsage - оценка памяти, занятой ключами и значениями, в байтах
+func (e *Engine) MemoryUsage() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.memory
+}
+
+func entrySize(key, value string) int64 {
+	return int64(len(key) + len(value) + entryOverhead)
+}
@@ -146,4 +146,42 @@ func TestEngineGet(t *testing.T) {
 			assert.Equal(t, testValue, value)
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestEngineClear(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewEngine(zap.NewNop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	engine.Set(ctx, "key1", "value1")
+	engine.Set(ctx, "key2", "value2")
+	assert.Equal(t, 2, engine.Size())
+	assert.Equal(t, 2*entrySize("key1", "value1"), engine.MemoryUsage())
+
+	engine.Clear(ctx)
+	assert.Equal(t, 0, engine.Size())
+	assert.Zero(t, engine.MemoryUsage())
+
+	_, exist := engine.Get(ctx, "key1")
+	assert.False(t, exist)
+}
+
+func TestEngineMemoryUsage(t *testing.T) {
+	t.Parallel()
+
+	engine, err := NewEngine(zap.NewNop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	engine.Set(ctx, "key", "value")
+	engine.Set(ctx, "key", "longer value")
+	assert.Equal(t, entrySize("key", "longer value"), engine.MemoryUsage())
+
+	engine.Del(ctx, "missing")
+	assert.Equal(t, entrySize("key", "longer value"), engine.MemoryUsage())
+
+	engine.Del(ctx, "key")
+	assert.Zero(t, engine.MemoryUsage())
+}
@@ -15,6 +15,11 @@ func NewIDGenerator(previousID int64) *IDGenerator {
 	return generator
 }
 
+// Last -- последний выданный идентификатор
+func (g *IDGenerator) Last() int64 {
+	return g.counter.Load()
+}
+
 func (g *IDGenerator) Generate() int64 {
 	g.counter.CompareAndSwap(math.MaxInt64, 0)
 	return g.counter.Add(1)
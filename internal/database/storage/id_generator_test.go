@@ -43,6 +43,7 @@ func TestGenerateID(t *testing.T) {
 	nextID := generator.Generate()
 	expectedID := goroutinesNumber + 1
 	assert.Equal(t, int64(expectedID), nextID)
+	assert.Equal(t, nextID, generator.Last())
 }
 
 func TestGenerateIDOverflow(t *testing.T) {
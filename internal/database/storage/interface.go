@@ -13,6 +13,9 @@ type Engine interface {
 	Set(context.Context, string, string)
 	Get(context.Context, string) (string, bool)
 	Del(context.Context, string)
+	Clear(context.Context)
+	Size() int
+	MemoryUsage() int64
 }
 
 // WAL - интерфейс для WAL
@@ -20,4 +23,5 @@ type WAL interface {
 	Recover() ([]wal.Log, error)
 	Set(context.Context, string, string) concurrency.FutureError
 	Del(context.Context, string) concurrency.FutureError
+	FlushAll(context.Context) concurrency.FutureError
 }
@@ -3,14 +3,37 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
+
 	"kava/internal/common"
 	"kava/internal/database/compute"
 	"kava/internal/database/storage/wal"
+	"kava/internal/tracing"
+	"kava/pkg/concurrency"
 
 	"go.uber.org/zap"
 )
 
-var ErrorNotExist = errors.New("key not exist")
+var (
+	ErrorNotExist = errors.New("key not exist")
+	ErrorReadOnly = errors.New("storage is read-only")
+)
+
+// RecoveryTarget -- точка, на которой останавливается восстановление из WAL:
+// логи после LSN или после момента времени не применяются
+type RecoveryTarget struct {
+	LSN  int64
+	Time time.Time
+}
+
+func (t *RecoveryTarget) reached(log wal.Log) bool {
+	if t.LSN != 0 && log.LSN > t.LSN {
+		return true
+	}
+
+	return !t.Time.IsZero() && log.Timestamp > t.Time.UnixNano()
+}
 
 // Storage - хранит данные используя engine
 type Storage struct {
@@ -18,11 +41,26 @@ type Storage struct {
 	wal       WAL
 	stream    <-chan []wal.Log
 	generator *IDGenerator
+	readOnly  bool
 	logger    *zap.Logger
 }
 
 // NewStorage - конструктор
 func NewStorage(engine Engine, wal WAL, logger *zap.Logger) (*Storage, error) {
+	return newStorage(engine, wal, nil, logger)
+}
+
+// NewRecoveredStorage - конструктор хранилища, восстановленного до цели,
+// хранилище доступно только на чтение
+func NewRecoveredStorage(engine Engine, wal WAL, target *RecoveryTarget, logger *zap.Logger) (*Storage, error) {
+	if target == nil {
+		return nil, errors.New("recovery target is invalid")
+	}
+
+	return newStorage(engine, wal, target, logger)
+}
+
+func newStorage(engine Engine, wal WAL, target *RecoveryTarget, logger *zap.Logger) (*Storage, error) {
 	if engine == nil {
 		return nil, errors.New("engine is invalid")
 	}
@@ -32,19 +70,22 @@ func NewStorage(engine Engine, wal WAL, logger *zap.Logger) (*Storage, error) {
 	}
 
 	storage := &Storage{
-		engine: engine,
-		logger: logger,
-		wal:    wal,
+		engine:   engine,
+		logger:   logger,
+		wal:      wal,
+		readOnly: target != nil,
 	}
 
 	var lastLSN int64
 	if storage.wal != nil {
+		// без восстановленных данных LSN начались бы заново
+		// и перемешались с записанными ранее
 		logs, err := storage.wal.Recover()
 		if err != nil {
-			logger.Error("failed to recover data from WAL", zap.Error(err))
-		} else {
-			lastLSN = storage.applyData(logs)
+			return nil, fmt.Errorf("failed to recover data from WAL: %w", err)
 		}
+
+		lastLSN = storage.applyData(logs, target)
 	}
 
 	storage.generator = NewIDGenerator(lastLSN)
@@ -53,17 +94,28 @@ func NewStorage(engine Engine, wal WAL, logger *zap.Logger) (*Storage, error) {
 
 // Set - сохраняет данные используя engine
 func (s *Storage) Set(ctx context.Context, key, value string) error {
+	if s.readOnly {
+		return ErrorReadOnly
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	txID := s.generator.Generate()
 	ctx = common.ContextWithTxID(ctx, txID)
 	
+	// после записи в WAL запрос доводится до движка,
+	// поэтому дедлайн проверяется только до нее
 	if s.wal != nil {
-		futureResponse := s.wal.Set(ctx, key, value)
-		if err := futureResponse.Get(); err != nil {
+		if err := s.waitWAL(ctx, s.wal.Set(ctx, key, value)); err != nil {
 			return err
 		}
 	}
 
+	ctx, span := tracing.StartSpan(ctx, "engine.apply")
 	s.engine.Set(ctx, key, value)
+	span.End(nil)
 	return nil
 }
 
@@ -85,23 +137,85 @@ func (s *Storage) Get(ctx context.Context, key string) (string, error) {
 
 // Del - удаляет данные, используя движок
 func (s *Storage) Del(ctx context.Context, key string) error {
+	if s.readOnly {
+		return ErrorReadOnly
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	txID := s.generator.Generate()
 	ctx = common.ContextWithTxID(ctx, txID)
 
 	if s.wal != nil {
-		futureResponse := s.wal.Del(ctx, key)
-		if err := futureResponse.Get(); err != nil {
+		if err := s.waitWAL(ctx, s.wal.Del(ctx, key)); err != nil {
 			return err
 		}
 	}
 
+	ctx, span := tracing.StartSpan(ctx, "engine.apply")
 	s.engine.Del(ctx, key)
+	span.End(nil)
+	return nil
+}
+
+// FlushAll -- удаляет все ключи, удаление записывается в WAL
+func (s *Storage) FlushAll(ctx context.Context) error {
+	if s.readOnly {
+		return ErrorReadOnly
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	txID := s.generator.Generate()
+	ctx = common.ContextWithTxID(ctx, txID)
+
+	if s.wal != nil {
+		if err := s.waitWAL(ctx, s.wal.FlushAll(ctx)); err != nil {
+			return err
+		}
+	}
+
+	ctx, span := tracing.StartSpan(ctx, "engine.apply")
+	s.engine.Clear(ctx)
+	span.End(nil)
 	return nil
 }
 
-func (s *Storage) applyData(logs []wal.Log) int64 {
+// waitWAL -- ожидает записи лога на диск в отдельном спане
+func (s *Storage) waitWAL(ctx context.Context, futureResponse concurrency.FutureError) error {
+	_, span := tracing.StartSpan(ctx, "wal.wait")
+	err := futureResponse.Get()
+	span.End(err)
+	return err
+}
+
+// Size -- количество ключей
+func (s *Storage) Size() int {
+	return s.engine.Size()
+}
+
+// MemoryUsage -- оценка памяти, занятой данными, в байтах
+func (s *Storage) MemoryUsage() int64 {
+	return s.engine.MemoryUsage()
+}
+
+// LastLSN -- последний выданный номер транзакции
+func (s *Storage) LastLSN() int64 {
+	return s.generator.Last()
+}
+
+func (s *Storage) applyData(logs []wal.Log, target *RecoveryTarget) int64 {
 	var lastLSN int64
 	for _, log := range logs {
+		if target != nil && target.reached(log) {
+			s.logger.Info("recovery target reached", zap.Int64("lsn", lastLSN))
+			break
+		}
+
 		lastLSN = max(lastLSN, log.LSN)
 		ctx := common.ContextWithTxID(context.Background(), log.LSN)
 		switch log.CommandID {
@@ -109,6 +223,8 @@ func (s *Storage) applyData(logs []wal.Log) int64 {
 			s.engine.Set(ctx, log.Arguments[0], log.Arguments[1])
 		case compute.DelCommandID:
 			s.engine.Del(ctx, log.Arguments[0])
+		case compute.FlushAllCommandID:
+			s.engine.Clear(ctx)
 		}
 	}
 
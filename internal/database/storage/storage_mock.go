@@ -42,6 +42,18 @@ func (m *MockEngine) EXPECT() *MockEngineMockRecorder {
 	return m.recorder
 }
 
+// Clear mocks base method.
+func (m *MockEngine) Clear(arg0 context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Clear", arg0)
+}
+
+// Clear indicates an expected call of Clear.
+func (mr *MockEngineMockRecorder) Clear(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Clear", reflect.TypeOf((*MockEngine)(nil).Clear), arg0)
+}
+
 // Del mocks base method.
 func (m *MockEngine) Del(arg0 context.Context, arg1 string) {
 	m.ctrl.T.Helper()
@@ -69,6 +81,20 @@ func (mr *MockEngineMockRecorder) Get(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockEngine)(nil).Get), arg0, arg1)
 }
 
+// MemoryUsage mocks base method.
+func (m *MockEngine) MemoryUsage() int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MemoryUsage")
+	ret0, _ := ret[0].(int64)
+	return ret0
+}
+
+// MemoryUsage indicates an expected call of MemoryUsage.
+func (mr *MockEngineMockRecorder) MemoryUsage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MemoryUsage", reflect.TypeOf((*MockEngine)(nil).MemoryUsage))
+}
+
 // Set mocks base method.
 func (m *MockEngine) Set(arg0 context.Context, arg1, arg2 string) {
 	m.ctrl.T.Helper()
@@ -81,6 +107,20 @@ func (mr *MockEngineMockRecorder) Set(arg0, arg1, arg2 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockEngine)(nil).Set), arg0, arg1, arg2)
 }
 
+// Size mocks base method.
+func (m *MockEngine) Size() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Size")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// Size indicates an expected call of Size.
+func (mr *MockEngineMockRecorder) Size() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Size", reflect.TypeOf((*MockEngine)(nil).Size))
+}
+
 // MockWAL is a mock of WAL interface.
 type MockWAL struct {
 	ctrl     *gomock.Controller
@@ -106,10 +146,10 @@ func (m *MockWAL) EXPECT() *MockWALMockRecorder {
 }
 
 // Del mocks base method.
-func (m *MockWAL) Del(arg0 context.Context, arg1 string) concurrency.Future[error] {
+func (m *MockWAL) Del(arg0 context.Context, arg1 string) concurrency.FutureError {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Del", arg0, arg1)
-	ret0, _ := ret[0].(concurrency.Future[error])
+	ret0, _ := ret[0].(concurrency.FutureError)
 	return ret0
 }
 
@@ -119,6 +159,20 @@ func (mr *MockWALMockRecorder) Del(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Del", reflect.TypeOf((*MockWAL)(nil).Del), arg0, arg1)
 }
 
+// FlushAll mocks base method.
+func (m *MockWAL) FlushAll(arg0 context.Context) concurrency.FutureError {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlushAll", arg0)
+	ret0, _ := ret[0].(concurrency.FutureError)
+	return ret0
+}
+
+// FlushAll indicates an expected call of FlushAll.
+func (mr *MockWALMockRecorder) FlushAll(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlushAll", reflect.TypeOf((*MockWAL)(nil).FlushAll), arg0)
+}
+
 // Recover mocks base method.
 func (m *MockWAL) Recover() ([]wal.Log, error) {
 	m.ctrl.T.Helper()
@@ -135,10 +189,10 @@ func (mr *MockWALMockRecorder) Recover() *gomock.Call {
 }
 
 // Set mocks base method.
-func (m *MockWAL) Set(arg0 context.Context, arg1, arg2 string) concurrency.Future[error] {
+func (m *MockWAL) Set(arg0 context.Context, arg1, arg2 string) concurrency.FutureError {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Set", arg0, arg1, arg2)
-	ret0, _ := ret[0].(concurrency.Future[error])
+	ret0, _ := ret[0].(concurrency.FutureError)
 	return ret0
 }
 
@@ -4,12 +4,18 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 
+	"kava/internal/common"
+	"kava/internal/database/compute"
+	"kava/internal/database/storage/wal"
+	"kava/internal/tracing"
 	"kava/pkg/concurrency"
 )
 
@@ -62,6 +68,20 @@ func TestNewStorage(t *testing.T) {
 	}
 }
 
+func TestNewStorageWithRecoveryError(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	writeAheadLog := NewMockWAL(ctrl)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, errors.New("broken segment"))
+
+	storage, err := NewStorage(NewMockEngine(ctrl), writeAheadLog, zap.NewNop())
+	assert.ErrorContains(t, err, "broken segment")
+	assert.Nil(t, storage)
+}
+
 func TestStorageSet(t *testing.T) {
 	t.Parallel()
 
@@ -259,3 +279,230 @@ func TestStorageGet(t *testing.T) {
 		})
 	}
 }
+
+
+func TestNewRecoveredStorage(t *testing.T) {
+	t.Parallel()
+
+	targetTime := time.Date(2025, time.March, 1, 14, 3, 0, 0, time.UTC)
+	logs := []wal.Log{
+		{LSN: 1, CommandID: compute.SetCommandID, Arguments: []string{"key1", "value1"}, Timestamp: targetTime.Add(-2 * time.Minute).UnixNano()},
+		{LSN: 2, CommandID: compute.SetCommandID, Arguments: []string{"key2", "value2"}, Timestamp: targetTime.Add(-time.Minute).UnixNano()},
+		{LSN: 3, CommandID: compute.DelCommandID, Arguments: []string{"key1"}, Timestamp: targetTime.Add(time.Minute).UnixNano()},
+	}
+
+	ctrl := gomock.NewController(t)
+
+	tests := map[string]struct {
+		target *RecoveryTarget
+		engine func() Engine
+
+		expectedErr error
+	}{
+		"create storage without target": {
+			engine:      func() Engine { return NewMockEngine(ctrl) },
+			expectedErr: errors.New("recovery target is invalid"),
+		},
+		"recover to lsn": {
+			target: &RecoveryTarget{LSN: 1},
+			engine: func() Engine {
+				engine := NewMockEngine(ctrl)
+				engine.EXPECT().
+					Set(gomock.Any(), "key1", "value1")
+				return engine
+			},
+		},
+		"recover to time": {
+			target: &RecoveryTarget{Time: targetTime},
+			engine: func() Engine {
+				engine := NewMockEngine(ctrl)
+				engine.EXPECT().
+					Set(gomock.Any(), "key1", "value1")
+				engine.EXPECT().
+					Set(gomock.Any(), "key2", "value2")
+				return engine
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			writeAheadLog := NewMockWAL(ctrl)
+			writeAheadLog.EXPECT().
+				Recover().
+				Return(logs, nil).
+				MaxTimes(1)
+
+			storage, err := NewRecoveredStorage(test.engine(), writeAheadLog, test.target, zap.NewNop())
+			assert.Equal(t, test.expectedErr, err)
+			if test.expectedErr != nil {
+				assert.Nil(t, storage)
+				return
+			}
+
+			require.NotNil(t, storage)
+			assert.Equal(t, ErrorReadOnly, storage.Set(context.Background(), "key", "value"))
+			assert.Equal(t, ErrorReadOnly, storage.Del(context.Background(), "key"))
+			assert.Equal(t, ErrorReadOnly, storage.FlushAll(context.Background()))
+		})
+	}
+}
+
+func TestStorageWithExpiredContext(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	writeAheadLog := NewMockWAL(ctrl)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(nil, nil)
+
+	storage, err := NewStorage(NewMockEngine(ctrl), writeAheadLog, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, storage.Set(ctx, "key", "value"))
+	assert.Equal(t, context.DeadlineExceeded, storage.Del(ctx, "key"))
+}
+
+func TestStorageFlushAll(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+
+	tests := map[string]struct {
+		engine func() Engine
+		wal    func() WAL
+
+		expectedErr error
+	}{
+		"flushall without wal": {
+			engine: func() Engine {
+				engine := NewMockEngine(ctrl)
+				engine.EXPECT().
+					Clear(gomock.Any())
+				return engine
+			},
+			wal: func() WAL { return nil },
+		},
+		"flushall with error from wal": {
+			engine: func() Engine { return NewMockEngine(ctrl) },
+			wal: func() WAL {
+				result := make(chan error, 1)
+				result <- errors.New("wal error")
+				future := concurrency.NewFuture(result)
+
+				wal := NewMockWAL(ctrl)
+				wal.EXPECT().
+					Recover().
+					Return(nil, nil)
+				wal.EXPECT().
+					FlushAll(gomock.Any()).
+					Return(future)
+				return wal
+			},
+			expectedErr: errors.New("wal error"),
+		},
+		"flushall with wal": {
+			engine: func() Engine {
+				engine := NewMockEngine(ctrl)
+				engine.EXPECT().
+					Clear(gomock.Any())
+				return engine
+			},
+			wal: func() WAL {
+				result := make(chan error, 1)
+				result <- nil
+				future := concurrency.NewFuture(result)
+
+				wal := NewMockWAL(ctrl)
+				wal.EXPECT().
+					Recover().
+					Return(nil, nil)
+				wal.EXPECT().
+					FlushAll(gomock.Any()).
+					Return(future)
+				return wal
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			storage, err := NewStorage(test.engine(), test.wal(), zap.NewNop())
+			require.NoError(t, err)
+
+			err = storage.FlushAll(context.Background())
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
+
+func TestStorageTracing(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	engine := NewMockEngine(ctrl)
+	engine.EXPECT().
+		Set(gomock.Any(), "key", "value")
+
+	result := make(chan error, 1)
+	result <- nil
+	wal := NewMockWAL(ctrl)
+	wal.EXPECT().
+		Recover().
+		Return(nil, nil)
+	wal.EXPECT().
+		Set(gomock.Any(), "key", "value").
+		Return(concurrency.NewFuture(result))
+
+	storage, err := NewStorage(engine, wal, zap.NewNop())
+	require.NoError(t, err)
+
+	core, logs := observer.New(zap.DebugLevel)
+	ctx := tracing.ContextWithTracer(context.Background(), tracing.NewLogTracer(zap.New(core)))
+	ctx = common.ContextWithRequestID(ctx, "request")
+	require.NoError(t, storage.Set(ctx, "key", "value"))
+
+	spans := logs.FilterMessage("span finished").All()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "wal.wait", spans[0].ContextMap()["span"])
+	assert.Equal(t, "engine.apply", spans[1].ContextMap()["span"])
+	for _, span := range spans {
+		assert.Equal(t, "request", span.ContextMap()["request_id"])
+		assert.Equal(t, int64(1), span.ContextMap()["tx_id"])
+	}
+}
+
+func TestStorageRecoverFlushAll(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	logs := []wal.Log{
+		{LSN: 1, CommandID: compute.SetCommandID, Arguments: []string{"key1", "value1"}},
+		{LSN: 2, CommandID: compute.FlushAllCommandID},
+		{LSN: 3, CommandID: compute.SetCommandID, Arguments: []string{"key2", "value2"}},
+	}
+
+	writeAheadLog := NewMockWAL(ctrl)
+	writeAheadLog.EXPECT().
+		Recover().
+		Return(logs, nil)
+
+	engine := NewMockEngine(ctrl)
+	gomock.InOrder(
+		engine.EXPECT().Set(gomock.Any(), "key1", "value1"),
+		engine.EXPECT().Clear(gomock.Any()),
+		engine.EXPECT().Set(gomock.Any(), "key2", "value2"),
+	)
+
+	storage, err := NewStorage(engine, writeAheadLog, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), storage.LastLSN())
+}
@@ -0,0 +1,128 @@
+package wal
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type Compression byte
+
+const (
+	CompressionNone Compression = iota
+	CompressionFlate
+	CompressionGzip
+)
+
+// batchMagic starts a header of the compressed batch, gob stream
+// can't start with this byte, so plain logs and batches can be mixed
+const batchMagic = 0xC1
+
+var errInvalidBatch = errors.New("invalid compressed batch")
+
+var compressionNames = map[string]Compression{
+	"":      CompressionNone,
+	"none":  CompressionNone,
+	"flate": CompressionFlate,
+	"gzip":  CompressionGzip,
+}
+
+func ParseCompression(name string) (Compression, error) {
+	compression, found := compressionNames[name]
+	if !found {
+		return CompressionNone, fmt.Errorf("unknown compression: %s", name)
+	}
+
+	return compression, nil
+}
+
+// encodeBatch wraps logs data to the batch: magic, compression, payload length, payload
+func encodeBatch(compression Compression, data []byte) ([]byte, error) {
+	payload, err := compress(compression, data)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 2, 2+binary.MaxVarintLen64)
+	header[0] = batchMagic
+	header[1] = byte(compression)
+	header = binary.AppendUvarint(header, uint64(len(payload)))
+
+	return append(header, payload...), nil
+}
+
+// decodeBatch returns decompressed logs data and the length of the batch
+func decodeBatch(data []byte) ([]byte, int, error) {
+	if len(data) < 2 || data[0] != batchMagic {
+		return nil, 0, errInvalidBatch
+	}
+
+	length, size := binary.Uvarint(data[2:])
+	if size <= 0 {
+		return nil, 0, errInvalidBatch
+	}
+
+	start := 2 + size
+	if uint64(len(data)-start) < length {
+		return nil, 0, errInvalidBatch
+	}
+
+	end := start + int(length)
+	payload, err := decompress(Compression(data[1]), data[start:end])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return payload, end, nil
+}
+
+func compress(compression Compression, data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	var writer io.WriteCloser
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionFlate:
+		var err error
+		if writer, err = flate.NewWriter(&buffer, flate.DefaultCompression); err != nil {
+			return nil, err
+		}
+	case CompressionGzip:
+		writer = gzip.NewWriter(&buffer)
+	default:
+		return nil, fmt.Errorf("unknown compression: %d", compression)
+	}
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func decompress(compression Compression, data []byte) ([]byte, error) {
+	var reader io.ReadCloser
+	switch compression {
+	case CompressionNone:
+		return data, nil
+	case CompressionFlate:
+		reader = flate.NewReader(bytes.NewReader(data))
+	case CompressionGzip:
+		var err error
+		if reader, err = gzip.NewReader(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown compression: %d", compression)
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
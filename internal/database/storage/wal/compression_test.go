@@ -0,0 +1,85 @@
+package wal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"kava/internal/database/compute"
+)
+
+func TestParseCompression(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		name string
+
+		expectedCompression Compression
+		expectedErr         bool
+	}{
+		"empty compression": {name: "", expectedCompression: CompressionNone},
+		"none compression":  {name: "none", expectedCompression: CompressionNone},
+		"flate compression": {name: "flate", expectedCompression: CompressionFlate},
+		"gzip compression":  {name: "gzip", expectedCompression: CompressionGzip},
+		"unknown compression": {
+			name:        "zstd",
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			compression, err := ParseCompression(test.name)
+			assert.Equal(t, test.expectedErr, err != nil)
+			assert.Equal(t, test.expectedCompression, compression)
+		})
+	}
+}
+
+func TestReadMixedSegment(t *testing.T) {
+	t.Parallel()
+
+	expectedLogs := []Log{
+		{LSN: 1, CommandID: compute.SetCommandID, Arguments: []string{"key1", "value1"}},
+		{LSN: 2, CommandID: compute.SetCommandID, Arguments: []string{"key2", "value2"}},
+		{LSN: 3, CommandID: compute.DelCommandID, Arguments: []string{"key1"}},
+		{LSN: 4, CommandID: compute.SetCommandID, Arguments: []string{"key3", "value3"}},
+	}
+
+	var segment bytes.Buffer
+	err := expectedLogs[0].Encode(&segment)
+	require.NoError(t, err)
+
+	for _, compression := range []Compression{CompressionFlate, CompressionGzip} {
+		var batch bytes.Buffer
+		logs := expectedLogs[1:3]
+		if compression == CompressionGzip {
+			logs = expectedLogs[3:]
+		}
+
+		for idx := range logs {
+			err := logs[idx].Encode(&batch)
+			require.NoError(t, err)
+		}
+
+		data, err := encodeBatch(compression, batch.Bytes())
+		require.NoError(t, err)
+		segment.Write(data)
+	}
+
+	data := segment.Bytes()
+	logs, length, err := ReadSegment(data)
+	require.NoError(t, err)
+	assert.Equal(t, expectedLogs, logs)
+	assert.Equal(t, len(data), length)
+
+	// torn compressed batch is not a part of the valid prefix
+	logs, length, err = ReadSegment(data[:len(data)-1])
+	assert.Error(t, err)
+	assert.Equal(t, expectedLogs[:3], logs)
+	assert.Equal(t, length, ValidLength(data[:len(data)-1]))
+}
@@ -9,6 +9,13 @@ type Log struct {
 	LSN       int64
 	CommandID int
 	Arguments []string
+	// Timestamp is a wall-clock time of the write in unix nanoseconds,
+	// it is zero for logs written before it was introduced
+	Timestamp int64
+	// RequestID is an id of the request that wrote the log, it links
+	// the log with the server logs and the slow log; it is empty
+	// for logs written outside of a request or before it was introduced
+	RequestID string
 }
 
 func (l *Log) Encode(buffer *bytes.Buffer) error {
@@ -25,12 +25,22 @@ func NewLogsReader(segmentsDirectory segmentsDirectory) (*LogsReader, error) {
 	}, nil
 }
 
+// Read -- читает логи всех сегментов. Оборванная запись допускается только
+// в конце последнего сегмента: от нее остается корректный префикс, а хвост
+// обрезается при первой записи в сегмент
 func (r *LogsReader) Read() ([]Log, error) {
 	var logs []Log
+	var tailErr error
 	err := r.segmentsDirectory.ForEach(func(data []byte) error {
-		var err error
-		logs, err = r.readSegment(logs, data)
-		return err
+		if tailErr != nil {
+			// поврежденный сегмент оказался не последним
+			return tailErr
+		}
+
+		segmentLogs, _, err := ReadSegment(data)
+		logs = append(logs, segmentLogs...)
+		tailErr = err
+		return nil
 	})
 
 	if err != nil {
@@ -45,16 +55,57 @@ func (r *LogsReader) Read() ([]Log, error) {
 	return logs, nil
 }
 
-func (r *LogsReader) readSegment(logs []Log, data []byte) ([]Log, error) {
-	buffer := bytes.NewBuffer(data)
+// ReadSegment decodes logs of the segment data, plain logs and compressed
+// batches can be mixed; it also returns the length of the decoded prefix
+func ReadSegment(data []byte) ([]Log, int, error) {
+	var logs []Log
+	length := 0
+	for length < len(data) {
+		recordLogs, recordLength, err := readRecord(data[length:])
+		if err != nil {
+			return logs, length, fmt.Errorf("failed to parse logs data: %w", err)
+		}
+
+		logs = append(logs, recordLogs...)
+		length += recordLength
+	}
+
+	return logs, length, nil
+}
+
+// ValidLength returns the length of the segment data prefix
+// which consists of completely written logs
+func ValidLength(data []byte) int {
+	_, length, _ := ReadSegment(data)
+	return length
+}
+
+func readRecord(data []byte) ([]Log, int, error) {
+	if data[0] != batchMagic {
+		buffer := bytes.NewBuffer(data)
+		var log Log
+		if err := log.Decode(buffer); err != nil {
+			return nil, 0, err
+		}
+
+		return []Log{log}, len(data) - buffer.Len(), nil
+	}
+
+	payload, length, err := decodeBatch(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var logs []Log
+	buffer := bytes.NewBuffer(payload)
 	for buffer.Len() > 0 {
 		var log Log
 		if err := log.Decode(buffer); err != nil {
-			return nil, fmt.Errorf("failed to parse logs data: %w", err)
+			return nil, 0, err
 		}
 
 		logs = append(logs, log)
 	}
 
-	return logs, nil
-}
\ No newline at end of file
+	return logs, length, nil
+}
@@ -1,12 +1,15 @@
 package wal
 
 import (
+	"bytes"
 	"errors"
 	"testing"
 
 	gomock "go.uber.org/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"kava/internal/database/compute"
 )
 
 // mockgen -source=logs_reader.go -destination=logs_reader_mock.go -package=wal
@@ -78,4 +81,88 @@ func TestRead(t *testing.T) {
 	logs, err := reader.Read()
 	assert.Nil(t, err)
 	assert.Nil(t, logs)
-}
\ No newline at end of file
+}
+
+func TestValidLength(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	logs := []Log{
+		{LSN: 1, CommandID: compute.SetCommandID, Arguments: []string{"key", "value"}},
+		{LSN: 2, CommandID: compute.DelCommandID, Arguments: []string{"key"}},
+	}
+
+	err := logs[0].Encode(&buffer)
+	require.NoError(t, err)
+	firstLength := buffer.Len()
+
+	err = logs[1].Encode(&buffer)
+	require.NoError(t, err)
+	data := buffer.Bytes()
+
+	assert.Equal(t, len(data), ValidLength(data))
+	assert.Equal(t, firstLength, ValidLength(data[:len(data)-3]))
+	assert.Equal(t, 0, ValidLength(data[:firstLength-1]))
+	assert.Equal(t, 0, ValidLength(nil))
+}
+
+func TestReadBrokenSegments(t *testing.T) {
+	t.Parallel()
+
+	var first, second bytes.Buffer
+	require.NoError(t, (&Log{LSN: 1, CommandID: compute.SetCommandID, Arguments: []string{"key_1", "value"}}).Encode(&first))
+	require.NoError(t, (&Log{LSN: 2, CommandID: compute.SetCommandID, Arguments: []string{"key_2", "value"}}).Encode(&second))
+	torn := append(bytes.Clone(second.Bytes()), first.Bytes()[:first.Len()-3]...)
+
+	tests := map[string]struct {
+		segments [][]byte
+
+		expectedLSNs []int64
+		expectedErr  bool
+	}{
+		"torn tail of the last segment": {
+			segments:     [][]byte{first.Bytes(), torn},
+			expectedLSNs: []int64{1, 2},
+		},
+		"broken segment before the last one": {
+			segments:    [][]byte{torn, first.Bytes()},
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			directory := NewMocksegmentsDirectory(ctrl)
+			directory.EXPECT().
+				ForEach(gomock.Any()).
+				DoAndReturn(func(action func([]byte) error) error {
+					for _, data := range test.segments {
+						if err := action(data); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+
+			reader, err := NewLogsReader(directory)
+			require.NoError(t, err)
+
+			logs, err := reader.Read()
+			if test.expectedErr {
+				assert.Error(t, err)
+				assert.Nil(t, logs)
+				return
+			}
+
+			require.NoError(t, err)
+			var lsns []int64
+			for _, log := range logs {
+				lsns = append(lsns, log.LSN)
+			}
+			assert.Equal(t, test.expectedLSNs, lsns)
+		})
+	}
+}
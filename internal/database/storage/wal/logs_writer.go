@@ -3,20 +3,65 @@ package wal
 import (
 	"bytes"
 	"errors"
+	"time"
 
 	"go.uber.org/zap"
+
+	"kava/internal/metrics"
 )
 
 type segment interface {
 	Write([]byte) error
+	Close() error
+}
+
+// syncObserver -- сегмент, который сообщает длительность fsync отдельно от записи
+type syncObserver interface {
+	ObserveSync(func(time.Duration))
 }
 
 type LogsWriter struct {
-	segment segment
-	logger  *zap.Logger
+	segment     segment
+	compression Compression
+	metrics     writerMetrics
+	logger      *zap.Logger
 }
 
-func NewLogsWriter(segment segment, logger *zap.Logger) (*LogsWriter, error) {
+// writerMetrics -- метрики записи батчей, без реестра обновления ничего не делают
+type writerMetrics struct {
+	batchSize     *metrics.Histogram
+	flushDuration *metrics.Histogram
+	syncDuration  *metrics.Histogram
+}
+
+// LogsWriterOption -- необязательная настройка записи логов
+type LogsWriterOption func(*LogsWriter)
+
+// WithMetrics -- ведет метрики размера батчей, длительности их записи и fsync сегмента,
+// fsync измеряется, только если сегмент реализует ObserveSync
+func WithMetrics(registry *metrics.Registry) LogsWriterOption {
+	return func(w *LogsWriter) {
+		w.metrics = writerMetrics{
+			batchSize: registry.NewHistogram(
+				"kava_wal_batch_size",
+				"Number of log records in a flushed WAL batch.",
+				metrics.ExponentialBuckets(1, 2, 11),
+			),
+			flushDuration: registry.NewHistogram(
+				"kava_wal_flush_duration_seconds",
+				"Time to encode, compress and write a WAL batch.",
+				metrics.DefaultDurationBuckets,
+			),
+			syncDuration: registry.NewHistogram(
+				"kava_wal_fsync_duration_seconds",
+				"Time to fsync the WAL segment file after writing a batch.",
+				metrics.DefaultDurationBuckets,
+			),
+		}
+	}
+}
+
+func NewLogsWriter(segment segment, compression Compression, logger *zap.Logger, options ...LogsWriterOption) (*LogsWriter, error) {
 	if segment == nil {
 		return nil, errors.New("segment is invalid")
 	}
@@ -24,13 +69,32 @@ func NewLogsWriter(segment segment, logger *zap.Logger) (*LogsWriter, error) {
 		return nil, errors.New("logger is invalid")
 	}
 
-	return &LogsWriter{
-		segment: segment,
-		logger:  logger,
-	}, nil
+	writer := &LogsWriter{
+		segment:     segment,
+		compression: compression,
+		logger:      logger,
+	}
+
+	for _, option := range options {
+		option(writer)
+	}
+
+	if observer, ok := segment.(syncObserver); ok && writer.metrics.syncDuration != nil {
+		observer.ObserveSync(func(duration time.Duration) {
+			writer.metrics.syncDuration.Observe(duration.Seconds())
+		})
+	}
+
+	return writer, nil
 }
 
 func (w *LogsWriter) Write(requests []WriteRequest) {
+	started := time.Now()
+	w.metrics.batchSize.Observe(float64(len(requests)))
+	defer func() {
+		w.metrics.flushDuration.Observe(time.Since(started).Seconds())
+	}()
+
 	var buffer bytes.Buffer
 	for idx := range requests {
 		log := requests[idx].Log()
@@ -41,7 +105,17 @@ func (w *LogsWriter) Write(requests []WriteRequest) {
 		}
 	}
 
-	err := w.segment.Write(buffer.Bytes())
+	data := buffer.Bytes()
+	if w.compression != CompressionNone {
+		var err error
+		if data, err = encodeBatch(w.compression, data); err != nil {
+			w.logger.Warn("failed to compress logs data", zap.Error(err))
+			w.acknowledgeWrite(requests, err)
+			return
+		}
+	}
+
+	err := w.segment.Write(data)
 	if err != nil {
 		w.logger.Warn("failed to write logs data", zap.Error(err))
 	}
@@ -53,4 +127,8 @@ func (w *LogsWriter) acknowledgeWrite(requests []WriteRequest, err error) {
 	for idx := range requests {
 		requests[idx].SetResponse(err)
 	}
-}
\ No newline at end of file
+}
+
+func (w *LogsWriter) Close() error {
+	return w.segment.Close()
+}
@@ -39,6 +39,20 @@ func (m *Mocksegment) EXPECT() *MocksegmentMockRecorder {
 	return m.recorder
 }
 
+// Close mocks base method.
+func (m *Mocksegment) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MocksegmentMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*Mocksegment)(nil).Close))
+}
+
 // Write mocks base method.
 func (m *Mocksegment) Write(arg0 []byte) error {
 	m.ctrl.T.Helper()
@@ -11,6 +11,8 @@ import (
 	"go.uber.org/zap"
 
 	"kava/internal/database/compute"
+	"kava/internal/database/filesystem"
+	"kava/internal/metrics"
 )
 
 // mockgen -source=logs_writer.go -destination=logs_writer_mock.go -package=wal
@@ -46,7 +48,7 @@ func TestNewLogsWriter(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			writer, err := NewLogsWriter(test.segment, test.logger)
+			writer, err := NewLogsWriter(test.segment, CompressionNone, test.logger)
 			assert.Equal(t, test.expectedErr, err)
 			if test.expectedNilObj {
 				assert.Nil(t, writer)
@@ -80,7 +82,7 @@ func TestWriteWithErrors(t *testing.T) {
 		Write(buffer.Bytes()).
 		Return(expectedErr)
 
-	writer, err := NewLogsWriter(segment, zap.NewNop())
+	writer, err := NewLogsWriter(segment, CompressionNone, zap.NewNop())
 	require.NoError(t, err)
 	writer.Write(requests)
 
@@ -112,7 +114,7 @@ func TestWrite(t *testing.T) {
 		Write(buffer.Bytes()).
 		Return(nil)
 
-	writer, err := NewLogsWriter(segment, zap.NewNop())
+	writer, err := NewLogsWriter(segment, CompressionNone, zap.NewNop())
 	require.NoError(t, err)
 	writer.Write(requests)
 
@@ -120,4 +122,64 @@ func TestWrite(t *testing.T) {
 		futureResponse := request.FutureResponse()
 		assert.Nil(t, futureResponse.Get())
 	}
-}
\ No newline at end of file
+}
+
+func TestWriteWithCompression(t *testing.T) {
+	t.Parallel()
+
+	requests := []WriteRequest{
+		NewWriteRequest(100, compute.SetCommandID, []string{"key", "value"}),
+		NewWriteRequest(200, compute.DelCommandID, []string{"key"}),
+	}
+
+	var written []byte
+	ctrl := gomock.NewController(t)
+	segment := NewMocksegment(ctrl)
+	segment.EXPECT().
+		Write(gomock.Any()).
+		DoAndReturn(func(data []byte) error {
+			written = data
+			return nil
+		})
+
+	writer, err := NewLogsWriter(segment, CompressionFlate, zap.NewNop())
+	require.NoError(t, err)
+	writer.Write(requests)
+
+	for _, request := range requests {
+		futureResponse := request.FutureResponse()
+		assert.Nil(t, futureResponse.Get())
+	}
+
+	logs, _, err := ReadSegment(written)
+	require.NoError(t, err)
+	assert.Equal(t, []Log{requests[0].Log(), requests[1].Log()}, logs)
+}
+
+func TestWriteMetrics(t *testing.T) {
+	t.Parallel()
+
+	requests := []WriteRequest{
+		NewWriteRequest(100, compute.SetCommandID, []string{"key", "value"}),
+		NewWriteRequest(200, compute.DelCommandID, []string{"key"}),
+	}
+
+	// fsync измеряется сегментом файловой системы отдельно от записи
+	segment := filesystem.NewSegment(t.TempDir(), 1<<20, nil)
+	defer segment.Close()
+
+	registry := metrics.NewRegistry()
+	writer, err := NewLogsWriter(segment, CompressionNone, zap.NewNop(), WithMetrics(registry))
+	require.NoError(t, err)
+	writer.Write(requests)
+	for _, request := range requests {
+		future := request.FutureResponse()
+		require.NoError(t, future.Get())
+	}
+
+	batchSize := registry.NewHistogram("kava_wal_batch_size", "", nil)
+	assert.Equal(t, uint64(1), batchSize.Count())
+	assert.Equal(t, 2.0, batchSize.Sum())
+	assert.Equal(t, uint64(1), registry.NewHistogram("kava_wal_flush_duration_seconds", "", nil).Count())
+	assert.Equal(t, uint64(1), registry.NewHistogram("kava_wal_fsync_duration_seconds", "", nil).Count())
+}
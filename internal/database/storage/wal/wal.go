@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"kava/internal/common"
@@ -13,6 +14,7 @@ import (
 
 type logsWriter interface {
 	Write([]WriteRequest)
+	Close() error
 }
 
 type logsReader interface {
@@ -23,14 +25,24 @@ type WAL struct {
 	logsWriter logsWriter
 	logsReader logsReader
 
-	flushTimeout time.Duration
+	flushTimeout atomic.Int64
 	maxBatchSize int
+	// timeoutChanged -- сигнал циклу записи перезапустить таймер
+	timeoutChanged chan struct{}
 
 	batches chan []WriteRequest
+	// sending -- полные батчи, которые отправляются в batches без мьютекса
+	sending sync.WaitGroup
 	mutex   sync.Mutex
 	batch   []WriteRequest
+
+	closed  bool
+	started atomic.Bool
+	stopped chan struct{}
 }
 
+var ErrClosed = errors.New("wal is closed")
+
 func NewWAL(writer logsWriter, reader logsReader, flushTimeout time.Duration, maxBatchSize int) (*WAL, error) {
 	if writer == nil {
 		return nil, errors.New("writer is invalid")
@@ -39,42 +51,78 @@ func NewWAL(writer logsWriter, reader logsReader, flushTimeout time.Duration, ma
 		return nil, errors.New("reader is invalid")
 	}
 
-	return &WAL{
+	wal := &WAL{
 		logsWriter:   writer,
 		logsReader:   reader,
-		flushTimeout: flushTimeout,
 		maxBatchSize: maxBatchSize,
 		batches:      make(chan []WriteRequest, 1),
-	}, nil
+		stopped:      make(chan struct{}),
+
+		timeoutChanged: make(chan struct{}, 1),
+	}
+	wal.flushTimeout.Store(int64(flushTimeout))
+
+	return wal, nil
+}
+
+// SetFlushTimeout -- меняет интервал записи неполного батча без остановки WAL,
+// неположительный интервал игнорируется
+func (w *WAL) SetFlushTimeout(flushTimeout time.Duration) {
+	if flushTimeout <= 0 {
+		return
+	}
+
+	w.flushTimeout.Store(int64(flushTimeout))
+
+	select {
+	case w.timeoutChanged <- struct{}{}:
+	default:
+	}
 }
 
 func (w *WAL) Start(ctx context.Context) {
+	w.started.Store(true)
 	go func() {
-		ticker := time.NewTicker(w.flushTimeout)
+		ticker := time.NewTicker(w.getFlushTimeout())
 		defer ticker.Stop()
+		defer close(w.stopped)
 
 		for {
 			select {
 			case <-ctx.Done():
-				w.flushBatch()
+				w.shutdown()
 				return
 			default:
 			}
 
 			select {
 			case <-ctx.Done():
-				w.flushBatch()
+				w.shutdown()
 				return
 			case batch := <-w.batches:
 				w.logsWriter.Write(batch)
-				ticker.Reset(w.flushTimeout)
+				ticker.Reset(w.getFlushTimeout())
 			case <-ticker.C:
 				w.flushBatch()
+			case <-w.timeoutChanged:
+				ticker.Reset(w.getFlushTimeout())
 			}
 		}
 	}()
 }
 
+// Close waits for the final flush after the context of Start
+// is done and closes the segment
+func (w *WAL) Close() error {
+	if w.started.Load() {
+		<-w.stopped
+	} else {
+		w.shutdown()
+	}
+
+	return w.logsWriter.Close()
+}
+
 func (w *WAL) Recover() ([]Log, error) {
 	// TODO: need to compact WAL segments
 	return w.logsReader.Read()
@@ -88,18 +136,43 @@ func (w *WAL) Del(ctx context.Context, key string) concurrency.FutureError {
 	return w.push(ctx, compute.DelCommandID, []string{key})
 }
 
+// FlushAll -- записывает удаление всех ключей, при восстановлении
+// оно применяется к данным из предыдущих логов
+func (w *WAL) FlushAll(ctx context.Context) concurrency.FutureError {
+	return w.push(ctx, compute.FlushAllCommandID, nil)
+}
+
+func (w *WAL) getFlushTimeout() time.Duration {
+	return time.Duration(w.flushTimeout.Load())
+}
+
 func (w *WAL) push(ctx context.Context, commandID int, args []string) concurrency.FutureError {
 	txID := common.GetTxIDFromContext(ctx)
 	record := NewWriteRequest(txID, commandID, args)
+	record.log.RequestID = common.GetRequestIDFromContext(ctx)
 
+	var batch []WriteRequest
 	concurrency.WithLock(&w.mutex, func() {
+		if w.closed {
+			record.SetResponse(ErrClosed)
+			return
+		}
+
 		w.batch = append(w.batch, record)
 		if len(w.batch) == w.maxBatchSize {
-			w.batches <- w.batch
+			batch = w.batch
 			w.batch = nil
+			w.sending.Add(1)
 		}
 	})
 
+	// отправка блокируется до записи предыдущего батча, поэтому
+	// выполняется без мьютекса, который нужен shutdown
+	if batch != nil {
+		w.batches <- batch
+		w.sending.Done()
+	}
+
 	return record.FutureResponse()
 }
 
@@ -113,4 +186,50 @@ func (w *WAL) flushBatch() {
 	if len(batch) != 0 {
 		w.logsWriter.Write(batch)
 	}
-}
\ No newline at end of file
+}
+
+// shutdown writes all accepted requests, requests pushed after it are rejected
+func (w *WAL) shutdown() {
+	var batch []WriteRequest
+	concurrency.WithLock(&w.mutex, func() {
+		w.closed = true
+		batch = w.batch
+		w.batch = nil
+	})
+
+	w.writeSendingBatches()
+	if len(batch) != 0 {
+		w.logsWriter.Write(batch)
+	}
+}
+
+// writeSendingBatches writes full batches until all pushes accepted
+// before closing have sent theirs
+func (w *WAL) writeSendingBatches() {
+	sent := make(chan struct{})
+	go func() {
+		w.sending.Wait()
+		close(sent)
+	}()
+
+	for {
+		select {
+		case batch := <-w.batches:
+			w.logsWriter.Write(batch)
+		case <-sent:
+			w.writePendingBatches()
+			return
+		}
+	}
+}
+
+func (w *WAL) writePendingBatches() {
+	for {
+		select {
+		case batch := <-w.batches:
+			w.logsWriter.Write(batch)
+		default:
+			return
+		}
+	}
+}
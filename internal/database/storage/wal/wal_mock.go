@@ -39,6 +39,20 @@ func (m *MocklogsWriter) EXPECT() *MocklogsWriterMockRecorder {
 	return m.recorder
 }
 
+// Close mocks base method.
+func (m *MocklogsWriter) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MocklogsWriterMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MocklogsWriter)(nil).Close))
+}
+
 // Write mocks base method.
 func (m *MocklogsWriter) Write(arg0 []WriteRequest) {
 	m.ctrl.T.Helper()
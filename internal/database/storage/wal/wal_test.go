@@ -3,6 +3,7 @@ package wal
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"kava/internal/common"
+	"kava/pkg/concurrency"
 )
 
 // mockgen -source=wal.go -destination=wal_mock.go -package=wal
@@ -88,6 +90,46 @@ func TestWALFlushByTimeout(t *testing.T) {
 	assert.NoError(t, future2.Get())
 }
 
+func TestWALSetFlushTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	logsReader := NewMocklogsReader(ctrl)
+	logsWriter := NewMocklogsWriter(ctrl)
+	logsWriter.EXPECT().
+		Write(gomock.Any()).
+		Do(func(requests []WriteRequest) {
+			for _, request := range requests {
+				request.SetResponse(nil)
+			}
+		})
+
+	wal, err := NewWAL(logsWriter, logsReader, time.Hour, 1000)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wal.Start(ctx)
+	wal.SetFlushTimeout(20 * time.Millisecond)
+	// неположительный интервал не применяется
+	wal.SetFlushTimeout(0)
+
+	future := wal.Set(common.ContextWithTxID(context.Background(), 10), "key", "value")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- future.Get()
+	}()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch was not flushed by the new timeout")
+	}
+}
+
 func TestWALFlushBySize(t *testing.T) {
 	t.Parallel()
 
@@ -116,4 +158,141 @@ func TestWALFlushBySize(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 	assert.NoError(t, future1.Get())
 	assert.NoError(t, future2.Get())
-}
\ No newline at end of file
+}
+
+func TestWALFlushOnClose(t *testing.T) {
+	t.Parallel()
+
+	var written []int64
+	ctrl := gomock.NewController(t)
+	logsReader := NewMocklogsReader(ctrl)
+	logsWriter := NewMocklogsWriter(ctrl)
+	logsWriter.EXPECT().
+		Write(gomock.Any()).
+		Do(func(requests []WriteRequest) {
+			for _, request := range requests {
+				written = append(written, request.Log().LSN)
+				request.SetResponse(nil)
+			}
+		}).
+		Times(2)
+	logsWriter.EXPECT().
+		Close().
+		Return(nil)
+
+	wal, err := NewWAL(logsWriter, logsReader, time.Minute, 2)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wal.Start(ctx)
+
+	future1 := wal.Set(common.ContextWithTxID(context.Background(), 10), "key1", "value1")
+	future2 := wal.Set(common.ContextWithTxID(context.Background(), 20), "key2", "value2")
+	future3 := wal.Del(common.ContextWithTxID(context.Background(), 30), "key1")
+
+	cancel()
+	require.NoError(t, wal.Close())
+
+	assert.NoError(t, future1.Get())
+	assert.NoError(t, future2.Get())
+	assert.NoError(t, future3.Get())
+	assert.Equal(t, []int64{10, 20, 30}, written)
+
+	future4 := wal.Set(common.ContextWithTxID(context.Background(), 40), "key4", "value4")
+	assert.Equal(t, ErrClosed, future4.Get())
+}
+
+func TestWALShutdownWithConcurrentPushes(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	logsReader := NewMocklogsReader(ctrl)
+	logsWriter := NewMocklogsWriter(ctrl)
+	logsWriter.EXPECT().
+		Write(gomock.Any()).
+		Do(func(requests []WriteRequest) {
+			time.Sleep(time.Millisecond)
+			for _, request := range requests {
+				request.SetResponse(nil)
+			}
+		}).
+		AnyTimes()
+	logsWriter.EXPECT().
+		Close().
+		Return(nil)
+
+	wal, err := NewWAL(logsWriter, logsReader, time.Minute, 1)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wal.Start(ctx)
+
+	const pushers = 16
+	futures := make(chan concurrency.FutureError, pushers*10)
+	var wg sync.WaitGroup
+	for i := 0; i < pushers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				futures <- wal.Set(common.ContextWithTxID(context.Background(), int64(i*10+j)), "key", "value")
+			}
+		}()
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+
+	closed := make(chan error, 1)
+	go func() {
+		wg.Wait()
+		closed <- wal.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("wal shutdown is blocked by pushes")
+	}
+
+	close(futures)
+	for future := range futures {
+		err := future.Get()
+		if err != nil {
+			assert.ErrorIs(t, err, ErrClosed)
+		}
+	}
+}
+
+func TestWALRequestID(t *testing.T) {
+	t.Parallel()
+
+	var written []Log
+	ctrl := gomock.NewController(t)
+	logsReader := NewMocklogsReader(ctrl)
+	logsWriter := NewMocklogsWriter(ctrl)
+	logsWriter.EXPECT().
+		Write(gomock.Any()).
+		Do(func(requests []WriteRequest) {
+			for _, request := range requests {
+				written = append(written, request.Log())
+				request.SetResponse(nil)
+			}
+		})
+
+	wal, err := NewWAL(logsWriter, logsReader, time.Minute, 100)
+	require.NoError(t, err)
+
+	ctx := common.ContextWithTxID(context.Background(), 10)
+	future1 := wal.Set(common.ContextWithRequestID(ctx, "8f2c1a"), "key", "value")
+	future2 := wal.Del(common.ContextWithTxID(context.Background(), 20), "key")
+
+	wal.flushBatch()
+	require.NoError(t, future1.Get())
+	require.NoError(t, future2.Get())
+
+	require.Len(t, written, 2)
+	assert.Equal(t, "8f2c1a", written[0].RequestID)
+	assert.Empty(t, written[1].RequestID)
+}
@@ -1,6 +1,12 @@
 package wal
 
-import "kava/pkg/concurrency"
+import (
+	"time"
+
+	"kava/pkg/concurrency"
+)
+
+var now = time.Now
 
 type WriteRequest struct {
 	log     Log
@@ -13,6 +19,7 @@ func NewWriteRequest(lsn int64, commandID int, args []string) WriteRequest {
 			LSN:       lsn,
 			CommandID: commandID,
 			Arguments: args,
+			Timestamp: now().UnixNano(),
 		},
 		promise: concurrency.NewPromise[error](),
 	}
@@ -21,6 +21,7 @@ func TestNewWriteRequest(t *testing.T) {
 	assert.Equal(t, lsn, request.log.LSN)
 	assert.Equal(t, commandID, request.log.CommandID)
 	assert.True(t, reflect.DeepEqual(argumnets, request.log.Arguments))
+	assert.NotZero(t, request.log.Timestamp)
 }
 
 func TestWriteRequestWithError(t *testing.T) {
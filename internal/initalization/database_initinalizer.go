@@ -0,0 +1,86 @@
+package initialization
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"kava/internal/configuration"
+	"kava/internal/database"
+	"kava/internal/database/compute"
+	"kava/internal/database/slowlog"
+	"kava/internal/database/storage"
+	"kava/internal/metrics"
+	"kava/internal/tracing"
+)
+
+const logTracingExporter = "log"
+
+// CreateDatabase -- создание базы, connections - счетчик соединений,
+// общий с серверами, для команды INFO
+func CreateDatabase(
+	cfg *configuration.Config,
+	computeLayer *compute.Compute,
+	storageLayer *storage.Storage,
+	connections *atomic.Int64,
+	registry *metrics.Registry,
+	logger *zap.Logger,
+) (*database.Database, error) {
+	slowLog, err := CreateSlowLog(cfg.SlowLog, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	tracer, err := CreateTracer(cfg.Tracing, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	registry.NewGaugeFunc("kava_engine_keys", "Number of keys in the engine.", func() float64 {
+		return float64(storageLayer.Size())
+	})
+	registry.NewGaugeFunc("kava_engine_memory_bytes", "Estimated memory used by keys and values.", func() float64 {
+		return float64(storageLayer.MemoryUsage())
+	})
+
+	return database.NewDatabase(
+		computeLayer,
+		storageLayer,
+		logger,
+		database.WithAdminCommands(cfg.Admin != nil && cfg.Admin.Enabled),
+		database.WithWALDirectory(WALDirectory(cfg.WAL)),
+		database.WithConnectionsCounter(connections),
+		database.WithMetrics(registry),
+		database.WithSlowLog(slowLog),
+		database.WithTracer(tracer),
+	)
+}
+
+// CreateTracer -- трейсер запросов, nil, если трассировка не настроена
+func CreateTracer(cfg *configuration.TracingConfig, logger *zap.Logger) (tracing.Tracer, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	switch cfg.Exporter {
+	case "", logTracingExporter:
+		return tracing.NewLogTracer(logger), nil
+	}
+
+	return nil, fmt.Errorf("unsupported tracing exporter: %s", cfg.Exporter)
+}
+
+// CreateSlowLog -- журнал медленных запросов, nil, если он не настроен
+func CreateSlowLog(cfg *configuration.SlowLogConfig, logger *zap.Logger) (*slowlog.SlowLog, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	var slowLogger *zap.Logger
+	if cfg.Log {
+		slowLogger = logger
+	}
+
+	return slowlog.NewSlowLog(cfg.Threshold, cfg.MaxLength, slowLogger)
+}
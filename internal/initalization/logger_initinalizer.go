@@ -13,46 +13,67 @@ const (
 	infoLevel  = "info"
 	warnLevel  = "warn"
 	errorLevel = "error"
+	fatalLevel = "fatal"
 )
 
-const (
-	defaultEncoding   = "json"
-	defaultLevel      = zapcore.InfoLevel
-	defaultOutputPath = "kava.log"
-)
+const defaultEncoding = "json"
 
 // CreateLogger -- конструктор логгера
 func CreateLogger(cfg *configuration.LoggingConfig) (*zap.Logger, error) {
-	level := defaultLevel
-	output := defaultOutputPath
-
-	if cfg != nil {
-		if cfg.Level != "" {
-			supportedLoggingLevels := map[string]zapcore.Level{
-				debugLevel: zapcore.DebugLevel,
-				infoLevel:  zapcore.InfoLevel,
-				warnLevel:  zapcore.WarnLevel,
-				errorLevel: zapcore.ErrorLevel,
-			}
-
-			var exist bool
-			if level, exist = supportedLoggingLevels[cfg.Level]; !exist {
-				return nil, errors.New("logging level is incorrect")
-			}
-			
-			if cfg.Output != "" {
-				// TODO: need to create a
-				// directory if it is missing
-				output = cfg.Output
-			}
-		}
+	logger, _, err := CreateLoggerWithLevel(cfg)
+	return logger, err
+}
+
+// CreateLoggerWithLevel -- логгер и его уровень, который можно менять
+// без пересоздания логгера, например, при перезагрузке конфигурации
+func CreateLoggerWithLevel(cfg *configuration.LoggingConfig) (*zap.Logger, zap.AtomicLevel, error) {
+	level, err := LoggingLevel(cfg)
+	if err != nil {
+		return nil, zap.AtomicLevel{}, err
 	}
 
+	output := configuration.DefaultLogOutput()
+	if cfg != nil && cfg.Output != "" {
+		// TODO: need to create a
+		// directory if it is missing
+		output = cfg.Output
+	}
+
+	atomicLevel := zap.NewAtomicLevelAt(level)
 	loggerCfg := zap.Config{
 		Encoding:    defaultEncoding,
-		Level:       zap.NewAtomicLevelAt(level),
+		Level:       atomicLevel,
 		OutputPaths: []string{output},
 	}
 
-	return loggerCfg.Build()
+	logger, err := loggerCfg.Build()
+	if err != nil {
+		return nil, zap.AtomicLevel{}, err
+	}
+
+	return logger, atomicLevel, nil
+}
+
+// LoggingLevel -- уровень логгера из конфигурации, без уровня - уровень
+// по умолчанию из configuration
+func LoggingLevel(cfg *configuration.LoggingConfig) (zapcore.Level, error) {
+	name := configuration.DefaultLogLevel()
+	if cfg != nil && cfg.Level != "" {
+		name = cfg.Level
+	}
+
+	supportedLoggingLevels := map[string]zapcore.Level{
+		debugLevel: zapcore.DebugLevel,
+		infoLevel:  zapcore.InfoLevel,
+		warnLevel:  zapcore.WarnLevel,
+		errorLevel: zapcore.ErrorLevel,
+		fatalLevel: zapcore.FatalLevel,
+	}
+
+	level, exist := supportedLoggingLevels[name]
+	if !exist {
+		return zapcore.InfoLevel, errors.New("logging level is incorrect")
+	}
+
+	return level, nil
 }
@@ -65,6 +65,15 @@ func TestCreateLogger(t *testing.T) {
 		assert.NotNil(t, logger)
 	})
 
+	t.Run("Create logger with valid fatal level", func(t *testing.T) {
+		cfg := &configuration.LoggingConfig{
+			Level: "fatal",
+		}
+		logger, err := CreateLogger(cfg)
+		assert.NoError(t, err)
+		assert.NotNil(t, logger)
+	})
+
 	t.Run("Create logger with invalid level", func(t *testing.T) {
 		cfg := &configuration.LoggingConfig{
 			Level: "invalid",
@@ -0,0 +1,35 @@
+package initialization
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+
+	"kava/internal/configuration"
+	"kava/internal/metrics"
+)
+
+// CreateMetricsRegistry -- реестр метрик, nil, если метрики не настроены:
+// компоненты с nil реестром метрики не ведут
+func CreateMetricsRegistry(cfg *configuration.MetricsConfig) *metrics.Registry {
+	if cfg == nil {
+		return nil
+	}
+
+	return metrics.NewRegistry()
+}
+
+// CreateMetricsServer -- HTTP сервер метрик, nil, если метрики не настроены
+func CreateMetricsServer(
+	cfg *configuration.MetricsConfig,
+	registry *metrics.Registry,
+	logger *zap.Logger,
+) (*metrics.Server, error) {
+	if logger == nil {
+		return nil, errors.New("logger is invalid")
+	} else if cfg == nil {
+		return nil, nil
+	}
+
+	return metrics.NewServer(cfg.Address, cfg.Path, registry, logger)
+}
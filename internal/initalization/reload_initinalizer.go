@@ -0,0 +1,215 @@
+package initialization
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"kava/internal/configuration"
+	"kava/internal/database/storage/wal"
+)
+
+// Reloader -- применяет конфигурацию, перечитанную из файла, к работающему процессу.
+// На лету меняются уровень лога, настройки соединений, интервал записи WAL и набор
+// серверов, об остальных изменениях пишется в лог, что они требуют перезапуска
+type Reloader struct {
+	path    string
+	level   zap.AtomicLevel
+	wal     *wal.WAL
+	servers *ServerGroup
+	logger  *zap.Logger
+	// overrides -- изменения конфигурации из флагов запуска, которые
+	// применяются к каждой перечитанной конфигурации
+	overrides []configuration.Override
+
+	mutex sync.Mutex
+	// cfg -- примененная конфигурация, разделы, которые требуют
+	// перезапуска, остаются в ней в прежнем виде
+	cfg *configuration.Config
+}
+
+// NewReloader -- cfg - конфигурация, с которой процесс запущен, wal может быть nil
+func NewReloader(
+	path string,
+	cfg *configuration.Config,
+	level zap.AtomicLevel,
+	wal *wal.WAL,
+	servers *ServerGroup,
+	logger *zap.Logger,
+	overrides ...configuration.Override,
+) (*Reloader, error) {
+	if cfg == nil {
+		return nil, errors.New("config is invalid")
+	}
+
+	if servers == nil {
+		return nil, errors.New("servers are invalid")
+	}
+
+	if logger == nil {
+		return nil, errors.New("logger is invalid")
+	}
+
+	return &Reloader{
+		path:      path,
+		cfg:       cfg,
+		level:     level,
+		wal:       wal,
+		servers:   servers,
+		logger:    logger,
+		overrides: overrides,
+	}, nil
+}
+
+// Watch -- перезагружает конфигурацию на каждый сигнал до отмены контекста
+func (r *Reloader) Watch(ctx context.Context, signals <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+		}
+
+		r.logger.Info("reloading configuration", zap.String("path", r.path))
+		if err := r.Reload(); err != nil {
+			r.logger.Error("failed to reload configuration", zap.Error(err))
+		}
+	}
+}
+
+// Reload -- перечитывает файл конфигурации, с некорректной конфигурацией
+// процесс продолжает работать со старой
+func (r *Reloader) Reload() error {
+	file, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	cfg, err := configuration.Load(file, r.overrides...)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range cfg.UnknownEnv() {
+		r.logger.Warn("unknown environment variable is ignored", zap.String("name", name))
+	}
+
+	return r.Apply(cfg)
+}
+
+// Apply -- применяет изменения, которые не требуют перезапуска
+func (r *Reloader) Apply(cfg *configuration.Config) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	level, err := LoggingLevel(cfg.Logging)
+	if err != nil {
+		return err
+	}
+
+	restartRequired := restartRequiredSettings(r.cfg, cfg)
+
+	if r.level.Level() != level {
+		r.logger.Info("logging level changed", zap.Stringer("level", level))
+		r.level.SetLevel(level)
+	}
+
+	// без раздела wal интервал остается прежним, удаление раздела требует перезапуска
+	if flushTimeout := FlushingBatchTimeout(cfg.WAL); r.wal != nil && flushTimeout > 0 {
+		r.wal.SetFlushTimeout(flushTimeout)
+	}
+
+	serversRestartRequired, err := r.servers.Apply(cfg.Servers)
+	restartRequired = append(restartRequired, serversRestartRequired...)
+	for _, setting := range restartRequired {
+		r.logger.Warn("configuration change requires restart", zap.String("setting", setting))
+	}
+
+	r.cfg = appliedConfig(r.cfg, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to apply servers configuration: %w", err)
+	}
+
+	r.logger.Info("configuration reloaded")
+	return nil
+}
+
+// restartRequiredSettings -- измененные разделы, которые применяются только при запуске
+func restartRequiredSettings(previous, next *configuration.Config) []string {
+	sections := []struct {
+		name           string
+		previous, next any
+	}{
+		{"engine", previous.Engine, next.Engine},
+		{"wal", restartOnlyWAL(previous.WAL), restartOnlyWAL(next.WAL)},
+		{"logging.output", loggingOutput(previous.Logging), loggingOutput(next.Logging)},
+		{"users", previous.Users, next.Users},
+		{"admin", previous.Admin, next.Admin},
+		{"metrics", previous.Metrics, next.Metrics},
+		{"slowlog", previous.SlowLog, next.SlowLog},
+		{"tracing", previous.Tracing, next.Tracing},
+	}
+
+	var changed []string
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.previous, section.next) {
+			changed = append(changed, section.name)
+		}
+	}
+
+	return changed
+}
+
+// appliedConfig -- новая конфигурация, в которой разделы, требующие перезапуска,
+// остаются прежними, чтобы о них сообщалось и при следующих перезагрузках
+func appliedConfig(previous, next *configuration.Config) *configuration.Config {
+	applied := *previous
+	applied.Servers = next.Servers
+
+	if next.Logging != nil {
+		logging := configuration.LoggingConfig{Level: next.Logging.Level}
+		if previous.Logging != nil {
+			logging.Output = previous.Logging.Output
+		}
+		applied.Logging = &logging
+	} else if previous.Logging != nil {
+		logging := *previous.Logging
+		logging.Level = ""
+		applied.Logging = &logging
+	}
+
+	if previous.WAL != nil {
+		walCfg := *previous.WAL
+		walCfg.FlushingBatchTimeout = 0
+		if next.WAL != nil {
+			walCfg.FlushingBatchTimeout = next.WAL.FlushingBatchTimeout
+		}
+		applied.WAL = &walCfg
+	}
+
+	return &applied
+}
+
+func restartOnlyWAL(cfg *configuration.WALConfig) *configuration.WALConfig {
+	if cfg == nil {
+		return nil
+	}
+
+	walCfg := *cfg
+	walCfg.FlushingBatchTimeout = 0
+	return &walCfg
+}
+
+func loggingOutput(cfg *configuration.LoggingConfig) string {
+	if cfg == nil {
+		return ""
+	}
+
+	return cfg.Output
+}
@@ -0,0 +1,263 @@
+package initialization
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"kava/internal/common"
+	"kava/internal/configuration"
+	"kava/internal/database"
+	"kava/internal/database/compute"
+	"kava/internal/database/storage"
+	"kava/internal/database/storage/engine/in_memory"
+)
+
+func newTestDatabase(t *testing.T) *database.Database {
+	t.Helper()
+
+	logger := zap.NewNop()
+	engine, err := in_memory.NewEngine(logger)
+	require.NoError(t, err)
+	storageLayer, err := storage.NewStorage(engine, nil, logger)
+	require.NoError(t, err)
+	computeLayer, err := compute.NewCompute(logger)
+	require.NoError(t, err)
+	db, err := database.NewDatabase(computeLayer, storageLayer, logger)
+	require.NoError(t, err)
+
+	return db
+}
+
+func unixServerConfig(name, path string) *configuration.UnixServerConfig {
+	return &configuration.UnixServerConfig{
+		BaseServer:     configuration.BaseServer{Type: "unix", Name: name},
+		Path:           path,
+		MaxConnections: 10,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Minute,
+		DrainTimeout:   time.Second,
+	}
+}
+
+func ping(t *testing.T, path string) {
+	t.Helper()
+
+	connection, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer connection.Close()
+
+	_, err = connection.Write([]byte("GET key\n"))
+	require.NoError(t, err)
+	buffer := make([]byte, 1024)
+	count, err := connection.Read(buffer)
+	require.NoError(t, err)
+	assert.Equal(t, "[error] key not exist\n", string(buffer[:count]))
+}
+
+func TestServerGroup_Apply(t *testing.T) {
+	directory := t.TempDir()
+	firstPath := filepath.Join(directory, "first.sock")
+	secondPath := filepath.Join(directory, "second.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	group := NewServerGroup(ctx, newTestDatabase(t), nil, zap.NewNop())
+
+	first := unixServerConfig("first", firstPath)
+	restartRequired, err := group.Apply(configuration.ServerConfigs{first})
+	require.NoError(t, err)
+	assert.Empty(t, restartRequired)
+	ping(t, firstPath)
+
+	// настройки соединений применяются без пересоздания сокета
+	connection, err := net.Dial("unix", firstPath)
+	require.NoError(t, err)
+	defer connection.Close()
+
+	reloaded := *first
+	reloaded.MaxConnections = 20
+	_, err = group.Apply(configuration.ServerConfigs{&reloaded})
+	require.NoError(t, err)
+
+	_, err = connection.Write([]byte("GET key\n"))
+	require.NoError(t, err)
+	buffer := make([]byte, 1024)
+	count, err := connection.Read(buffer)
+	require.NoError(t, err)
+	assert.Equal(t, "[error] key not exist\n", string(buffer[:count]))
+
+	// удаленный сервер останавливается, новый запускается
+	_, err = group.Apply(configuration.ServerConfigs{unixServerConfig("second", secondPath)})
+	require.NoError(t, err)
+	ping(t, secondPath)
+	_, err = net.Dial("unix", firstPath)
+	assert.Error(t, err)
+
+	_, err = group.Apply(configuration.ServerConfigs{
+		unixServerConfig("second", secondPath),
+		unixServerConfig("second", firstPath),
+	})
+	assert.ErrorContains(t, err, `duplicate server "second"`)
+
+	cancel()
+	assert.NoError(t, group.Wait())
+}
+
+func TestServerGroup_ApplyConsole(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	group := NewServerGroup(ctx, newTestDatabase(t), nil, zap.NewNop())
+	group.Start("console", noopServer{})
+
+	restartRequired, err := group.Apply(configuration.ServerConfigs{
+		&configuration.ConsoleConfig{BaseServer: configuration.BaseServer{Type: "console"}},
+	})
+	assert.ErrorContains(t, err, `server name "console" is reserved`)
+	assert.Empty(t, restartRequired)
+}
+
+type noopServer struct{}
+
+func (noopServer) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func TestReloader_Apply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(core)
+
+	cfg := &configuration.Config{
+		Engine:  &configuration.EngineConfig{Type: "in_memory"},
+		Logging: &configuration.LoggingConfig{Level: "info", Output: "kava.log"},
+	}
+
+	group := NewServerGroup(ctx, newTestDatabase(t), nil, logger)
+	reloader, err := NewReloader("config.yaml", cfg, level, nil, group, logger)
+	require.NoError(t, err)
+
+	err = reloader.Apply(&configuration.Config{
+		Engine:  &configuration.EngineConfig{Type: "in_memory"},
+		Logging: &configuration.LoggingConfig{Level: "invalid"},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, zapcore.InfoLevel, level.Level())
+
+	next := &configuration.Config{
+		Engine:  &configuration.EngineConfig{Type: "persistent"},
+		Logging: &configuration.LoggingConfig{Level: "debug", Output: "kava.log"},
+	}
+	require.NoError(t, reloader.Apply(next))
+	assert.Equal(t, zapcore.DebugLevel, level.Level())
+
+	restartRequired := logs.FilterMessage("configuration change requires restart").All()
+	require.Len(t, restartRequired, 1)
+	assert.Equal(t, "engine", restartRequired[0].ContextMap()["setting"])
+
+	// раздел, требующий перезапуска, не считается примененным
+	require.NoError(t, reloader.Apply(next))
+	assert.Equal(t, 2, logs.FilterMessage("configuration change requires restart").Len())
+}
+
+func TestReloader_ApplyWithoutWAL(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(core)
+
+	walCfg := &configuration.WALConfig{
+		FlushingBatchLength:  100,
+		FlushingBatchTimeout: 10 * time.Millisecond,
+		MaxSegmentSize:       4 << 10,
+		DataDirectory:        t.TempDir(),
+	}
+	writeAheadLog, err := CreateWAL(walCfg, nil, logger)
+	require.NoError(t, err)
+
+	walCtx, stopWAL := context.WithCancel(context.Background())
+	writeAheadLog.Start(walCtx)
+	defer func() {
+		stopWAL()
+		assert.NoError(t, writeAheadLog.Close())
+	}()
+
+	cfg := &configuration.Config{WAL: walCfg}
+	group := NewServerGroup(ctx, newTestDatabase(t), nil, logger)
+	reloader, err := NewReloader("config.yaml", cfg, zap.NewAtomicLevel(), writeAheadLog, group, logger)
+	require.NoError(t, err)
+
+	// без раздела wal интервал записи остается прежним
+	require.NoError(t, reloader.Apply(&configuration.Config{}))
+
+	restartRequired := logs.FilterMessage("configuration change requires restart").All()
+	require.Len(t, restartRequired, 1)
+	assert.Equal(t, "wal", restartRequired[0].ContextMap()["setting"])
+
+	future := writeAheadLog.Set(common.ContextWithTxID(context.Background(), 1), "key", "value")
+	done := make(chan error, 1)
+	go func() {
+		done <- future.Get()
+	}()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch was not flushed by the previous timeout")
+	}
+}
+
+func TestReloader_Reload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("logging:\n  level: warn\n"), 0o600))
+
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	group := NewServerGroup(ctx, newTestDatabase(t), nil, zap.NewNop())
+	reloader, err := NewReloader(path, &configuration.Config{}, level, nil, group, zap.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, zapcore.WarnLevel, level.Level())
+
+	require.NoError(t, os.WriteFile(path, []byte("servers: invalid\n"), 0o600))
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, zapcore.WarnLevel, level.Level())
+}
+
+func TestReloader_ReloadOverrides(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("logging:\n  level: warn\n"), 0o600))
+
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	group := NewServerGroup(ctx, newTestDatabase(t), nil, zap.NewNop())
+	reloader, err := NewReloader(path, &configuration.Config{}, level, nil, group, zap.NewNop(),
+		configuration.WithLoggingLevel("debug"))
+	require.NoError(t, err)
+
+	// уровень из флагов запуска не сбрасывается значением из файла
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, zapcore.DebugLevel, level.Level())
+}
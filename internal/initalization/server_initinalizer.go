@@ -2,51 +2,490 @@ package initialization
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"kava/internal/configuration"
 	"kava/internal/database"
+	"kava/internal/database/auth"
 	"kava/internal/database/server"
-	"log"
+	"kava/internal/metrics"
+	"kava/pkg/concurrency"
+	"maps"
 	"os"
+	"reflect"
 	"sync"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 )
 
 // Server -- интерфейс сервера
 type Server interface {
-	Start(context.Context)
+	Start(context.Context) error
 }
 
-func NewServers(cfg *configuration.Config, database *database.Database, logger *zap.Logger) []Server {
-	var wg sync.WaitGroup
+// ServerOptions -- настройки, общие для всех серверов
+func ServerOptions(
+	cfg *configuration.Config,
+	connections *atomic.Int64,
+	registry *metrics.Registry,
+) ([]server.TCPServerOption, error) {
+	options := []server.TCPServerOption{
+		server.WithConnectionsCounter(connections),
+		server.WithMetrics(registry),
+	}
+	if len(cfg.Users) != 0 {
+		authenticator, err := auth.NewAuthenticator(cfg.Users)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, server.WithAuthenticator(authenticator))
+	}
 
-	servers := make([]Server, 0, len(cfg.Servers))
+	return options, nil
+}
 
-	for _, cfg := range cfg.Servers {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
+// CreateServer -- сервер по его конфигурации, nil для неизвестного типа.
+// Записи лога сервера и его соединений помечаются именем сервера
+func CreateServer(
+	cfg configuration.ServerConfig,
+	database *database.Database,
+	options []server.TCPServerOption,
+	logger *zap.Logger,
+) (Server, error) {
+	logger = logger.With(zap.String("server", serverKey(cfg)))
 
-			switch cfg := cfg.(type) {
-			case *configuration.TCPServerConfig:
-				tcpServer, err := server.NewTCPServer(cfg, database, logger)
-				if err != nil {
-					log.Printf("failed to create tcp server: %v", err)
-					return
-				}
-				servers = append(servers, tcpServer)
-			case *configuration.ConsoleConfig:
-				console, err := server.NewConsole(os.Stdin, os.Stdout, database, logger)
-				if err != nil {
-					log.Printf("failed to create console: %v", err)
-					return
+	switch cfg := cfg.(type) {
+	case *configuration.TCPServerConfig:
+		tcpServer, err := server.NewTCPServer(cfg, database, logger, options...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tcp server: %w", err)
+		}
+		return tcpServer, nil
+	case *configuration.UnixServerConfig:
+		unixServer, err := server.NewUnixServer(cfg, database, logger, options...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create unix server: %w", err)
+		}
+		return unixServer, nil
+	case *configuration.ConsoleConfig:
+		console, err := server.NewConsole(
+			os.Stdin,
+			os.Stdout,
+			database,
+			logger,
+			server.WithHistory(cfg.HistoryFile, cfg.HistorySize),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create console: %w", err)
+		}
+		return console, nil
+	}
+
+	return nil, nil
+}
+
+// reloadableServer -- сервер, который применяет настройки соединений без перезапуска
+type reloadableServer interface {
+	Reload(configuration.ServerConfig) error
+}
+
+// ServerGroup -- запущенные серверы по ключам, ключ сервера из конфигурации -
+// его имя, а без имени - тип и адрес. Серверы можно добавлять, останавливать
+// и перенастраивать без перезапуска процесса
+type ServerGroup struct {
+	ctx      context.Context
+	database *database.Database
+	options  []server.TCPServerOption
+	logger   *zap.Logger
+
+	// applyMutex -- Apply не выполняется конкурентно, поэтому cfg
+	// запущенных серверов меняется только под ним
+	applyMutex sync.Mutex
+
+	mutex   sync.Mutex
+	running map[string]*runningServer
+	wg      sync.WaitGroup
+	// active -- серверы, которые еще работают, в отличие от running
+	// сюда не входят серверы, завершившиеся сами
+	active int
+	// stopped -- закрывается, когда все серверы завершились сами,
+	// после этого серверы не запускаются
+	stopped    chan struct{}
+	allStopped bool
+	// err -- первая ошибка сервера, который не был остановлен группой
+	err error
+}
+
+type runningServer struct {
+	// cfg -- nil для серверов не из раздела servers, например, сервера метрик
+	cfg     configuration.ServerConfig
+	server  Server
+	cancel  context.CancelFunc
+	done    chan struct{}
+	removed atomic.Bool
+}
+
+// NewServerGroup -- серверы останавливаются по отмене ctx
+func NewServerGroup(
+	ctx context.Context,
+	database *database.Database,
+	options []server.TCPServerOption,
+	logger *zap.Logger,
+) *ServerGroup {
+	return &ServerGroup{
+		ctx:      ctx,
+		database: database,
+		options:  options,
+		logger:   logger,
+		running:  make(map[string]*runningServer),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Start -- запускает сервер не из раздела servers, Apply его не трогает
+func (g *ServerGroup) Start(key string, server Server) {
+	g.start(key, nil, server)
+}
+
+// Apply -- приводит запущенные серверы к разделу servers: запускает новые,
+// останавливает удаленные, применяет настройки соединений на лету, а при смене
+// адреса или режима пересоздает сервер (см. recreate). Серверы создаются последовательно в
+// порядке конфигурации, сервер, который не открыл сокет, с политикой
+// on_listen_error: skip пропускается, иначе ошибка возвращается вместе с
+// остальными. Возвращает изменения, которые требуют перезапуска процесса
+func (g *ServerGroup) Apply(configs configuration.ServerConfigs) ([]string, error) {
+	g.applyMutex.Lock()
+	defer g.applyMutex.Unlock()
+
+	var errs []error
+	var restartRequired []string
+
+	keys := make(map[string]configuration.ServerConfig, len(configs))
+	for _, cfg := range configs {
+		key := serverKey(cfg)
+		if _, exist := keys[key]; exist {
+			errs = append(errs, fmt.Errorf("duplicate server %q", key))
+			continue
+		}
+		keys[key] = cfg
+	}
+
+	for key, running := range g.snapshot() {
+		if running.cfg == nil {
+			continue
+		}
+
+		if _, exist := keys[key]; exist {
+			continue
+		}
+
+		if _, console := running.cfg.(*configuration.ConsoleConfig); console {
+			restartRequired = append(restartRequired, "servers."+key)
+			continue
+		}
+
+		g.logger.Info("stopping removed server", zap.String("server", key))
+		g.stop(key)
+	}
+
+	for _, cfg := range configs {
+		key := serverKey(cfg)
+		if keys[key] != cfg {
+			continue
+		}
+
+		running, exist := g.snapshot()[key]
+		if exist && running.cfg == nil {
+			errs = append(errs, fmt.Errorf("server name %q is reserved", key))
+			continue
+		}
+
+		if exist {
+			switch {
+			case reflect.DeepEqual(running.cfg, cfg):
+				continue
+			case isConsole(running.cfg) || isConsole(cfg):
+				restartRequired = append(restartRequired, "servers."+key)
+				continue
+			case sameListener(running.cfg, cfg):
+				if err := g.reload(key, running, cfg); err != nil {
+					errs = append(errs, err)
 				}
-				servers = append(servers, console)
+				continue
 			}
-		}()
+
+			g.logger.Info("recreating server with changed listener", zap.String("server", key))
+			err := g.recreate(key, running, cfg)
+			if err != nil && onListenError(cfg) == configuration.ListenErrorSkip {
+				g.logger.Warn("skipping changed listener that failed to start", zap.String("server", key), zap.Error(err))
+			} else if err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		server, err := CreateServer(cfg, g.database, g.options, g.logger)
+		if err != nil && onListenError(cfg) == configuration.ListenErrorSkip {
+			g.logger.Warn("skipping server that failed to start", zap.String("server", key), zap.Error(err))
+			continue
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("server %q: %w", key, err))
+			continue
+		}
+		if server != nil {
+			g.start(key, cfg, server)
+		}
+	}
+
+	return restartRequired, errors.Join(errs...)
+}
+
+// Wait -- ждет остановки всех серверов после отмены контекста или пока все
+// серверы не завершатся сами, например, с ошибкой netpoll. Возвращает первую
+// ошибку сервера, который завершился не по остановке группой
+func (g *ServerGroup) Wait() error {
+	select {
+	case <-g.ctx.Done():
+	case <-g.stopped:
+	}
+
+	// после отмены контекста или остановки всех серверов start
+	// не добавляет серверы, поэтому счетчик больше не растет
+	concurrency.WithLock(&g.mutex, func() {})
+	g.wg.Wait()
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.err
+}
+
+func (g *ServerGroup) start(key string, cfg configuration.ServerConfig, server Server) {
+	ctx, cancel := context.WithCancel(g.ctx)
+	running := &runningServer{
+		cfg:    cfg,
+		server: server,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	started := false
+	concurrency.WithLock(&g.mutex, func() {
+		if g.ctx.Err() != nil || g.allStopped {
+			return
+		}
+
+		g.running[key] = running
+		g.wg.Add(1)
+		g.active++
+		started = true
+	})
+
+	if !started {
+		cancel()
+		return
+	}
+
+	go func() {
+		defer g.wg.Done()
+		defer close(running.done)
+		defer cancel()
+
+		err := server.Start(ctx)
+		if err != nil {
+			g.logger.Error("server stopped with error", zap.String("server", key), zap.Error(err))
+		}
+
+		g.finish(key, running, err)
+	}()
+}
+
+// finish -- учитывает завершение сервера. Остановка удаленного при перезагрузке
+// сервера не влияет на результат Wait и не считается остановкой всех серверов
+func (g *ServerGroup) finish(key string, running *runningServer, err error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.active--
+	if running.removed.Load() {
+		return
+	}
+
+	if err != nil && g.err == nil {
+		g.err = fmt.Errorf("server %q: %w", key, err)
+	}
+
+	if g.active == 0 && g.ctx.Err() == nil && !g.allStopped {
+		g.logger.Error("all servers stopped")
+		g.allStopped = true
+		close(g.stopped)
+	}
+}
+
+// recreate -- заменяет сервер сервером с новым сокетом. Новый сервер создается до
+// остановки старого, и при ошибке старый продолжает работать. Если адреса совпадают,
+// новый сокет не открыть, пока работает старый: тогда старый останавливается,
+// а при ошибке запускается снова со своей конфигурацией
+func (g *ServerGroup) recreate(key string, running *runningServer, cfg configuration.ServerConfig) error {
+	server, err := CreateServer(cfg, g.database, g.options, g.logger)
+	if err == nil {
+		g.stop(key)
+		g.start(key, cfg, server)
+		return nil
+	}
+
+	if !sharedAddress(running.cfg, cfg) {
+		return fmt.Errorf("server %q: %w, previous server keeps running", key, err)
+	}
+
+	g.stop(key)
+	server, err = CreateServer(cfg, g.database, g.options, g.logger)
+	if err == nil {
+		g.start(key, cfg, server)
+		return nil
 	}
-	wg.Wait()
 
-	return servers
+	previous, restoreErr := CreateServer(running.cfg, g.database, g.options, g.logger)
+	if restoreErr != nil {
+		return fmt.Errorf("server %q: %w, failed to restart previous server: %w", key, err, restoreErr)
+	}
+
+	g.start(key, running.cfg, previous)
+	return fmt.Errorf("server %q: %w, previous server is restarted", key, err)
+}
+
+// stop -- останавливает сервер и ждет завершения его соединений
+func (g *ServerGroup) stop(key string) {
+	var running *runningServer
+	concurrency.WithLock(&g.mutex, func() {
+		running = g.running[key]
+		delete(g.running, key)
+	})
+
+	if running == nil {
+		return
+	}
+
+	running.removed.Store(true)
+	running.cancel()
+	<-running.done
+}
+
+func (g *ServerGroup) reload(key string, running *runningServer, cfg configuration.ServerConfig) error {
+	server, ok := running.server.(reloadableServer)
+	if !ok {
+		return fmt.Errorf("server %q does not support reload", key)
+	}
+
+	if err := server.Reload(cfg); err != nil {
+		return fmt.Errorf("server %q: %w", key, err)
+	}
+
+	running.cfg = cfg
+	g.logger.Info("server settings reloaded", zap.String("server", key))
+	return nil
+}
+
+func (g *ServerGroup) snapshot() map[string]*runningServer {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return maps.Clone(g.running)
+}
+
+// serverKey -- имя сервера, а без имени - тип и адрес
+func serverKey(cfg configuration.ServerConfig) string {
+	switch cfg := cfg.(type) {
+	case *configuration.TCPServerConfig:
+		if cfg.Name != "" {
+			return cfg.Name
+		}
+		return fmt.Sprintf("tcp %s:%d", cfg.Host, cfg.Port)
+	case *configuration.UnixServerConfig:
+		if cfg.Name != "" {
+			return cfg.Name
+		}
+		return "unix " + cfg.Path
+	case *configuration.ConsoleConfig:
+		if cfg.Name != "" {
+			return cfg.Name
+		}
+		return "console"
+	}
+
+	return fmt.Sprintf("%T", cfg)
+}
+
+// onListenError -- политика при ошибке открытия сокета, у консоли сокета нет
+func onListenError(cfg configuration.ServerConfig) string {
+	switch cfg := cfg.(type) {
+	case *configuration.TCPServerConfig:
+		return cfg.OnListenError
+	case *configuration.UnixServerConfig:
+		return cfg.OnListenError
+	}
+
+	return configuration.ListenErrorFail
+}
+
+func isConsole(cfg configuration.ServerConfig) bool {
+	_, ok := cfg.(*configuration.ConsoleConfig)
+	return ok
+}
+
+// sharedAddress -- сокеты серверов занимают один адрес: тот же порт tcp
+// на любом хосте или тот же путь unix сокета
+func sharedAddress(previous, next configuration.ServerConfig) bool {
+	switch previous := previous.(type) {
+	case *configuration.TCPServerConfig:
+		next, ok := next.(*configuration.TCPServerConfig)
+		return ok && previous.Port != 0 && previous.Port == next.Port
+	case *configuration.UnixServerConfig:
+		next, ok := next.(*configuration.UnixServerConfig)
+		return ok && previous.Path == next.Path
+	}
+
+	return false
+}
+
+// sameListener -- конфигурации отличаются только настройками соединений,
+// которые сервер применяет через Reload
+func sameListener(previous, next configuration.ServerConfig) bool {
+	switch previous := previous.(type) {
+	case *configuration.TCPServerConfig:
+		next, ok := next.(*configuration.TCPServerConfig)
+		if !ok {
+			return false
+		}
+
+		left, right := *previous, *next
+		for _, cfg := range []*configuration.TCPServerConfig{&left, &right} {
+			cfg.MaxConnections = 0
+			cfg.MaxConnectionsWait = 0
+			cfg.IdleTimeout = 0
+			cfg.DrainTimeout = 0
+			cfg.QueryTimeout = 0
+			cfg.OnListenError = ""
+		}
+		return reflect.DeepEqual(left, right)
+	case *configuration.UnixServerConfig:
+		next, ok := next.(*configuration.UnixServerConfig)
+		if !ok {
+			return false
+		}
+
+		left, right := *previous, *next
+		for _, cfg := range []*configuration.UnixServerConfig{&left, &right} {
+			cfg.MaxConnections = 0
+			cfg.MaxConnectionsWait = 0
+			cfg.IdleTimeout = 0
+			cfg.DrainTimeout = 0
+			cfg.QueryTimeout = 0
+			cfg.OnListenError = ""
+		}
+		return reflect.DeepEqual(left, right)
+	}
 
+	return false
 }
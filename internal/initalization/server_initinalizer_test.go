@@ -0,0 +1,302 @@
+package initialization
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"kava/internal/configuration"
+)
+
+func TestServerGroup_ApplyListenError(t *testing.T) {
+	directory := t.TempDir()
+	missingDirectory := filepath.Join(directory, "missing")
+
+	t.Run("fail policy", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		group := NewServerGroup(ctx, newTestDatabase(t), nil, zap.NewNop())
+
+		broken := unixServerConfig("broken", filepath.Join(missingDirectory, "broken.sock"))
+		broken.OnListenError = configuration.ListenErrorFail
+		firstPath := filepath.Join(directory, "first.sock")
+		lastPath := filepath.Join(directory, "last.sock")
+
+		_, err := group.Apply(configuration.ServerConfigs{
+			unixServerConfig("first", firstPath),
+			broken,
+			unixServerConfig("last", lastPath),
+		})
+		assert.ErrorContains(t, err, `server "broken": failed to create unix server: failed to listen`)
+
+		// ошибка одного сервера не мешает создать остальные, решение
+		// об остановке принимает вызывающий
+		ping(t, firstPath)
+		ping(t, lastPath)
+
+		cancel()
+		assert.NoError(t, group.Wait())
+	})
+
+	t.Run("skip policy", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		core, logs := observer.New(zapcore.InfoLevel)
+		group := NewServerGroup(ctx, newTestDatabase(t), nil, zap.New(core))
+
+		skipped := unixServerConfig("skipped", filepath.Join(missingDirectory, "skipped.sock"))
+		skipped.OnListenError = configuration.ListenErrorSkip
+
+		_, err := group.Apply(configuration.ServerConfigs{skipped})
+		require.NoError(t, err)
+
+		warnings := logs.FilterMessage("skipping server that failed to start").All()
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "skipped", warnings[0].ContextMap()["server"])
+
+		// пропущенный сервер запускается при следующем Apply
+		require.NoError(t, os.Mkdir(missingDirectory, 0o700))
+		_, err = group.Apply(configuration.ServerConfigs{skipped})
+		require.NoError(t, err)
+		ping(t, skipped.Path)
+
+		cancel()
+		assert.NoError(t, group.Wait())
+	})
+}
+
+func TestCreateServer_LoggerName(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	cfg := unixServerConfig("sidecar", filepath.Join(t.TempDir(), "kava.sock"))
+	cfg.MaxMessageSize = 16
+
+	server, err := CreateServer(cfg, newTestDatabase(t), nil, zap.New(core))
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		done <- server.Start(ctx)
+	}()
+
+	connection, err := net.Dial("unix", cfg.Path)
+	require.NoError(t, err)
+	defer connection.Close()
+
+	_, err = connection.Write([]byte("SET key " + strings.Repeat("v", 32) + "\n"))
+	require.NoError(t, err)
+	_, err = bufio.NewReader(connection).ReadString('\n')
+	require.NoError(t, err)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	entries := logs.FilterMessage("closing connection: message is too large").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "sidecar", entries[0].ContextMap()["server"])
+}
+
+func TestServerGroup_ConcurrentApply(t *testing.T) {
+	directory := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	group := NewServerGroup(ctx, newTestDatabase(t), nil, zap.NewNop())
+
+	const workers = 8
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			configs := configuration.ServerConfigs{unixServerConfig("shared", filepath.Join(directory, "shared.sock"))}
+			for i := 0; i < 3; i++ {
+				name := fmt.Sprintf("worker-%d-%d", worker, i)
+				configs = append(configs, unixServerConfig(name, filepath.Join(directory, name+".sock")))
+			}
+
+			_, err := group.Apply(configs)
+			assert.NoError(t, err)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		group.Start("metrics", noopServer{})
+	}()
+	wg.Wait()
+
+	// Apply выполняются по очереди, поэтому остаются серверы последнего из них
+	running := group.snapshot()
+	assert.Len(t, running, 5)
+	assert.Contains(t, running, "shared")
+	assert.Contains(t, running, "metrics")
+	ping(t, filepath.Join(directory, "shared.sock"))
+
+	cancel()
+	assert.NoError(t, group.Wait())
+}
+
+// failingServer -- сервер, который завершается с ошибкой после закрытия fail
+type failingServer struct {
+	fail <-chan struct{}
+	err  error
+}
+
+func (s failingServer) Start(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-s.fail:
+		return s.err
+	}
+}
+
+func TestServerGroup_WaitStoppedServers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	group := NewServerGroup(ctx, newTestDatabase(t), nil, zap.NewNop())
+
+	failFirst, failSecond := make(chan struct{}), make(chan struct{})
+	group.Start("first", failingServer{fail: failFirst, err: errors.New("epoll failed")})
+	group.Start("second", failingServer{fail: failSecond, err: errors.New("accept failed")})
+
+	waited := make(chan error, 1)
+	go func() {
+		waited <- group.Wait()
+	}()
+
+	// пока работает хотя бы один сервер, Wait ждет отмены контекста
+	close(failFirst)
+	select {
+	case err := <-waited:
+		t.Fatalf("wait returned while a server is running: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(failSecond)
+	select {
+	case err := <-waited:
+		assert.EqualError(t, err, `server "first": epoll failed`)
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not return after all servers stopped")
+	}
+
+	// после остановки всех серверов новые не запускаются
+	group.Start("late", noopServer{})
+	assert.NotContains(t, group.snapshot(), "late")
+}
+
+func TestServerGroup_RecreateListener(t *testing.T) {
+	directory := t.TempDir()
+
+	t.Run("new address fails", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		group := NewServerGroup(ctx, newTestDatabase(t), nil, zap.NewNop())
+		previous := unixServerConfig("moved", filepath.Join(directory, "moved.sock"))
+		_, err := group.Apply(configuration.ServerConfigs{previous})
+		require.NoError(t, err)
+
+		// новый сервер создается до остановки старого, старый продолжает работать
+		next := unixServerConfig("moved", filepath.Join(directory, "missing", "moved.sock"))
+		_, err = group.Apply(configuration.ServerConfigs{next})
+		assert.ErrorContains(t, err, "previous server keeps running")
+		ping(t, previous.Path)
+
+		cancel()
+		assert.NoError(t, group.Wait())
+	})
+
+	t.Run("same address", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		group := NewServerGroup(ctx, newTestDatabase(t), nil, zap.NewNop())
+		previous := unixServerConfig("shared", filepath.Join(directory, "shared.sock"))
+		_, err := group.Apply(configuration.ServerConfigs{previous})
+		require.NoError(t, err)
+
+		next := unixServerConfig("shared", previous.Path)
+		next.Mode = 0o600
+		_, err = group.Apply(configuration.ServerConfigs{next})
+		require.NoError(t, err)
+		ping(t, next.Path)
+
+		info, err := os.Stat(next.Path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		cancel()
+		assert.NoError(t, group.Wait())
+	})
+
+	t.Run("same address fails", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		listener, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+		port := listener.Addr().(*net.TCPAddr).Port
+		require.NoError(t, listener.Close())
+
+		group := NewServerGroup(ctx, newTestDatabase(t), nil, zap.NewNop())
+		previous := &configuration.TCPServerConfig{
+			BaseServer:     configuration.BaseServer{Type: "tcp", Name: "main"},
+			Host:           "localhost",
+			Port:           port,
+			MaxConnections: 10,
+			MaxMessageSize: 1024,
+			IdleTimeout:    time.Minute,
+			DrainTimeout:   time.Second,
+		}
+		_, err = group.Apply(configuration.ServerConfigs{previous})
+		require.NoError(t, err)
+
+		// старый сервер останавливается ради адреса, а после ошибки запускается снова
+		next := *previous
+		next.TLS = &configuration.TLSConfig{
+			CertFile: filepath.Join(directory, "missing.crt"),
+			KeyFile:  filepath.Join(directory, "missing.key"),
+		}
+		_, err = group.Apply(configuration.ServerConfigs{&next})
+		assert.ErrorContains(t, err, "previous server is restarted")
+
+		connection, err := net.Dial("tcp", net.JoinHostPort("localhost", strconv.Itoa(port)))
+		require.NoError(t, err)
+		defer connection.Close()
+
+		_, err = connection.Write([]byte("GET key\n"))
+		require.NoError(t, err)
+		response, err := bufio.NewReader(connection).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "[error] key not exist\n", response)
+
+		cancel()
+		assert.NoError(t, group.Wait())
+	})
+}
@@ -0,0 +1,113 @@
+package initialization
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"kava/internal/configuration"
+	"kava/internal/database"
+	"kava/internal/database/client"
+	"kava/internal/database/compute"
+	"kava/internal/database/server"
+	"kava/internal/database/storage/engine/in_memory"
+)
+
+func TestShutdownKeepsAcknowledgedWrites(t *testing.T) {
+	logger := zap.NewNop()
+	walCfg := &configuration.WALConfig{
+		FlushingBatchLength:  10,
+		FlushingBatchTimeout: 5 * time.Millisecond,
+		MaxSegmentSize:       4 << 10,
+		DataDirectory:        t.TempDir(),
+	}
+
+	writeAheadLog, err := CreateWAL(walCfg, nil, logger)
+	require.NoError(t, err)
+
+	walCtx, stopWAL := context.WithCancel(context.Background())
+	defer stopWAL()
+	writeAheadLog.Start(walCtx)
+
+	engine, err := in_memory.NewEngine(logger)
+	require.NoError(t, err)
+	storage, err := CreateStorage(walCfg, engine, writeAheadLog, logger)
+	require.NoError(t, err)
+	computeLayer, err := compute.NewCompute(logger)
+	require.NoError(t, err)
+	db, err := database.NewDatabase(computeLayer, storage, logger)
+	require.NoError(t, err)
+
+	tcpServer, err := server.NewTCPServer(&configuration.TCPServerConfig{
+		Host:           "localhost",
+		MaxConnections: 10,
+		MaxMessageSize: 1024,
+		IdleTimeout:    time.Minute,
+		DrainTimeout:   5 * time.Second,
+	}, db, logger)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- tcpServer.Start(ctx)
+	}()
+
+	var mutex sync.Mutex
+	var acknowledged []string
+	var wg sync.WaitGroup
+	for worker := 0; worker < 5; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			kavaClient, err := client.NewTCPClient(tcpServer.Addr().String(), 1024, time.Minute)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer kavaClient.Close()
+
+			for idx := 0; ; idx++ {
+				key := fmt.Sprintf("key_%d_%d", worker, idx)
+				response, err := kavaClient.Send([]byte(fmt.Sprintf("SET %s value\n", key)))
+				if err != nil || !strings.HasPrefix(string(response), "[ok]") {
+					return
+				}
+
+				mutex.Lock()
+				acknowledged = append(acknowledged, key)
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	require.NoError(t, <-stopped)
+	wg.Wait()
+
+	stopWAL()
+	require.NoError(t, writeAheadLog.Close())
+
+	recoveredWAL, err := CreateWAL(walCfg, nil, logger)
+	require.NoError(t, err)
+	logs, err := recoveredWAL.Recover()
+	require.NoError(t, err)
+
+	recovered := make(map[string]struct{}, len(logs))
+	for _, log := range logs {
+		recovered[log.Arguments[0]] = struct{}{}
+	}
+
+	require.NotEmpty(t, acknowledged)
+	for _, key := range acknowledged {
+		assert.Contains(t, recovered, key)
+	}
+}
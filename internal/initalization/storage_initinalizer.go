@@ -0,0 +1,40 @@
+package initialization
+
+import (
+	"go.uber.org/zap"
+
+	"kava/internal/configuration"
+	"kava/internal/database/storage"
+	"kava/internal/database/storage/wal"
+)
+
+// CreateStorage -- создание хранилища, при заданной цели восстановления
+// хранилище запускается только на чтение
+func CreateStorage(
+	cfg *configuration.WALConfig,
+	engine storage.Engine,
+	writeAheadLog *wal.WAL,
+	logger *zap.Logger,
+) (*storage.Storage, error) {
+	var walLayer storage.WAL
+	if writeAheadLog != nil {
+		walLayer = writeAheadLog
+	}
+
+	if cfg == nil || cfg.Recovery == nil {
+		return storage.NewStorage(engine, walLayer, logger)
+	}
+
+	target := &storage.RecoveryTarget{
+		LSN:  cfg.Recovery.TargetLSN,
+		Time: cfg.Recovery.TargetTime,
+	}
+
+	logger.Warn(
+		"point-in-time recovery, storage is read-only",
+		zap.Int64("target_lsn", target.LSN),
+		zap.Time("target_time", target.Time),
+	)
+
+	return storage.NewRecoveredStorage(engine, walLayer, target, logger)
+}
@@ -9,54 +9,89 @@ import (
 	"kava/internal/configuration"
 	"kava/internal/database/filesystem"
 	"kava/internal/database/storage/wal"
+	"kava/internal/metrics"
 )
 
-const (
-	defaultFlushingBatchSize    = 100
-	defaultFlushingBatchTimeout = time.Millisecond * 10
-	defaultMaxSegmentSize       = 10 << 20
-	defaultWALDataDirectory     = "./data/spider/wal"
-)
+// Значения по умолчанию заполняются при загрузке конфигурации (configuration.Load)
 
-func CreateWAL(cfg *configuration.WALConfig, logger *zap.Logger) (*wal.WAL, error) {
+func CreateWAL(cfg *configuration.WALConfig, registry *metrics.Registry, logger *zap.Logger) (*wal.WAL, error) {
 	if logger == nil {
 		return nil, errors.New("logger is invalid")
 	} else if cfg == nil {
 		return nil, nil
 	}
 
-	flushingBatchSize := defaultFlushingBatchSize
-	flushingBatchTimeout := defaultFlushingBatchTimeout
-	maxSegmentSize := defaultMaxSegmentSize
-	dataDirectory := defaultWALDataDirectory
-
-	if cfg.FlushingBatchLength != 0 {
-		flushingBatchSize = cfg.FlushingBatchLength
-	}
-
-	if cfg.FlushingBatchTimeout != 0 {
-		flushingBatchTimeout = cfg.FlushingBatchTimeout
+	dataDirectory := WALDirectory(cfg)
+	var directoryOptions []filesystem.SegmentsDirectoryOption
+	if cfg.Retention != nil {
+		// без снимков состояния архив - часть истории, без него данные потеряются
+		directoryOptions = append(directoryOptions, filesystem.WithArchiveDirectory(cfg.Retention.ArchiveDirectory))
 	}
 
-	maxSegmentSize = int(cfg.MaxSegmentSize)
-
-	if cfg.DataDirectory != "" {
-		// TODO: need to create a directory,
-		// if it is missing
-		dataDirectory = cfg.DataDirectory
+	segmentsDirectory := filesystem.NewSegmentsDirectory(dataDirectory, directoryOptions...)
+	reader, err := wal.NewLogsReader(segmentsDirectory)
+	if err != nil {
+		return nil, err
 	}
 
-	segmentsDirectory := filesystem.NewSegmentsDirectory(dataDirectory)
-	reader, err := wal.NewLogsReader(segmentsDirectory)
+	compression, err := wal.ParseCompression(cfg.Compression)
 	if err != nil {
 		return nil, err
 	}
 
-	segment := filesystem.NewSegment(dataDirectory, maxSegmentSize)
-	writer, err := wal.NewLogsWriter(segment, logger)
+	segment := filesystem.NewSegment(dataDirectory, int(cfg.MaxSegmentSize), wal.ValidLength)
+	writer, err := wal.NewLogsWriter(segment, compression, logger, wal.WithMetrics(registry))
 	if err != nil {
 		return nil, err
 	}
 
-	return wal.NewWAL(writer, reader, flushingBatchTimeout, flushingBatchSize)
-}
\ No newline at end of file
+	return wal.NewWAL(writer, reader, FlushingBatchTimeout(cfg), cfg.FlushingBatchLength)
+}
+
+// FlushingBatchTimeout -- интервал записи неполного батча WAL
+func FlushingBatchTimeout(cfg *configuration.WALConfig) time.Duration {
+	if cfg == nil {
+		return 0
+	}
+
+	return cfg.FlushingBatchTimeout
+}
+
+// WALDirectory -- каталог сегментов WAL, пустая строка, если WAL выключен
+func WALDirectory(cfg *configuration.WALConfig) string {
+	if cfg == nil {
+		return ""
+	}
+
+	// TODO: need to create a directory,
+	// if it is missing
+	return cfg.DataDirectory
+}
+
+// CreateSegmentsArchiver -- создание архиватора сегментов WAL,
+// возвращает nil, если политика хранения не задана
+func CreateSegmentsArchiver(cfg *configuration.WALConfig, logger *zap.Logger) (*filesystem.SegmentsArchiver, error) {
+	if logger == nil {
+		return nil, errors.New("logger is invalid")
+	} else if cfg == nil || cfg.Retention == nil {
+		return nil, nil
+	} else if cfg.Recovery != nil {
+		// segments must stay untouched while the node is being inspected
+		return nil, nil
+	}
+
+	policy := filesystem.RetentionPolicy{
+		MaxTotalSize: int64(cfg.Retention.MaxTotalSize),
+		MaxAge:       cfg.Retention.MaxAge,
+		KeepSegments: cfg.Retention.KeepSegments,
+	}
+
+	return filesystem.NewSegmentsArchiver(
+		WALDirectory(cfg),
+		cfg.Retention.ArchiveDirectory,
+		cfg.Retention.Compress,
+		policy,
+		cfg.Retention.CheckInterval,
+		logger,
+	)
+}
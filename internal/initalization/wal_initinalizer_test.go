@@ -0,0 +1,135 @@
+package initialization
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"kava/internal/configuration"
+	"kava/internal/database/filesystem"
+	"kava/internal/database/storage/engine/in_memory"
+)
+
+func TestWALRestartAfterTornWrite(t *testing.T) {
+	t.Parallel()
+
+	logger := zap.NewNop()
+	walCfg := &configuration.WALConfig{
+		FlushingBatchLength:  1,
+		FlushingBatchTimeout: time.Millisecond,
+		MaxSegmentSize:       4 << 10,
+		DataDirectory:        t.TempDir(),
+	}
+
+	run := func(keys ...string) {
+		writeAheadLog, err := CreateWAL(walCfg, nil, logger)
+		require.NoError(t, err)
+
+		ctx, stop := context.WithCancel(context.Background())
+		writeAheadLog.Start(ctx)
+
+		engine, err := in_memory.NewEngine(logger)
+		require.NoError(t, err)
+		storage, err := CreateStorage(walCfg, engine, writeAheadLog, logger)
+		require.NoError(t, err)
+
+		for _, key := range keys {
+			require.NoError(t, storage.Set(context.Background(), key, "value"))
+		}
+
+		stop()
+		require.NoError(t, writeAheadLog.Close())
+	}
+
+	run("key_1", "key_2")
+
+	// запись оборвалась на середине лога
+	segmentName, err := filesystem.SegmentLast(walCfg.DataDirectory)
+	require.NoError(t, err)
+	segment, err := os.OpenFile(filepath.Join(walCfg.DataDirectory, segmentName), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = segment.Write([]byte{0x01, 0x02, 0x03})
+	require.NoError(t, err)
+	require.NoError(t, segment.Close())
+
+	run("key_3")
+
+	writeAheadLog, err := CreateWAL(walCfg, nil, logger)
+	require.NoError(t, err)
+	logs, err := writeAheadLog.Recover()
+	require.NoError(t, err)
+
+	var keys []string
+	var lsns []int64
+	for _, log := range logs {
+		keys = append(keys, log.Arguments[0])
+		lsns = append(lsns, log.LSN)
+	}
+
+	assert.Equal(t, []string{"key_1", "key_2", "key_3"}, keys)
+	assert.Equal(t, []int64{1, 2, 3}, lsns)
+}
+
+func TestWALRecoverArchivedSegments(t *testing.T) {
+	t.Parallel()
+
+	logger := zap.NewNop()
+	dataDirectory := t.TempDir()
+	walCfg := &configuration.WALConfig{
+		FlushingBatchLength:  1,
+		FlushingBatchTimeout: time.Millisecond,
+		MaxSegmentSize:       1,
+		DataDirectory:        dataDirectory,
+		Retention: &configuration.WALRetentionConfig{
+			KeepSegments:     1,
+			MaxTotalSize:     1,
+			ArchiveDirectory: filepath.Join(dataDirectory, "archive"),
+			Compress:         true,
+			CheckInterval:    time.Hour,
+		},
+	}
+
+	writeAheadLog, err := CreateWAL(walCfg, nil, logger)
+	require.NoError(t, err)
+	ctx, stop := context.WithCancel(context.Background())
+	writeAheadLog.Start(ctx)
+
+	engine, err := in_memory.NewEngine(logger)
+	require.NoError(t, err)
+	storage, err := CreateStorage(walCfg, engine, writeAheadLog, logger)
+	require.NoError(t, err)
+
+	for _, key := range []string{"key_1", "key_2", "key_3"} {
+		require.NoError(t, storage.Set(context.Background(), key, "value"))
+		// имя сегмента -- время создания в миллисекундах
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	stop()
+	require.NoError(t, writeAheadLog.Close())
+
+	archiver, err := CreateSegmentsArchiver(walCfg, logger)
+	require.NoError(t, err)
+	require.NoError(t, archiver.Archive())
+
+	count, _, err := filesystem.SegmentsStat(dataDirectory)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	writeAheadLog, err = CreateWAL(walCfg, nil, logger)
+	require.NoError(t, err)
+	logs, err := writeAheadLog.Recover()
+	require.NoError(t, err)
+
+	var keys []string
+	for _, log := range logs {
+		keys = append(keys, log.Arguments[0])
+	}
+	assert.Equal(t, []string{"key_1", "key_2", "key_3"}, keys)
+}
@@ -0,0 +1,259 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultDurationBuckets -- границы гистограммы длительностей в секундах,
+// рассчитаны на запросы к памяти: от десятков микросекунд до секунды
+var DefaultDurationBuckets = []float64{
+	0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1,
+}
+
+// ExponentialBuckets -- count границ, начиная со start, каждая следующая в factor раз больше
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+
+	return buckets
+}
+
+// float -- float64, изменяемый атомарно
+type float struct {
+	bits atomic.Uint64
+}
+
+func (f *float) add(value float64) {
+	for {
+		old := f.bits.Load()
+		updated := math.Float64bits(math.Float64frombits(old) + value)
+		if f.bits.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
+
+func (f *float) set(value float64) {
+	f.bits.Store(math.Float64bits(value))
+}
+
+func (f *float) load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+// Counter -- монотонно растущий счетчик. Методы nil счетчика ничего не делают,
+// поэтому метрики можно не проверять, если они не собираются
+type Counter struct {
+	value float
+}
+
+// Inc -- увеличивает счетчик на единицу
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add -- увеличивает счетчик, отрицательные значения игнорируются
+func (c *Counter) Add(value float64) {
+	if c == nil || value < 0 {
+		return
+	}
+
+	c.value.add(value)
+}
+
+// Value -- текущее значение
+func (c *Counter) Value() float64 {
+	if c == nil {
+		return 0
+	}
+
+	return c.value.load()
+}
+
+// Gauge -- значение, которое может расти и уменьшаться
+type Gauge struct {
+	value float
+}
+
+// Set -- устанавливает значение
+func (g *Gauge) Set(value float64) {
+	if g == nil {
+		return
+	}
+
+	g.value.set(value)
+}
+
+// Inc -- увеличивает значение на единицу
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec -- уменьшает значение на единицу
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Add -- изменяет значение на value
+func (g *Gauge) Add(value float64) {
+	if g == nil {
+		return
+	}
+
+	g.value.add(value)
+}
+
+// Value -- текущее значение
+func (g *Gauge) Value() float64 {
+	if g == nil {
+		return 0
+	}
+
+	return g.value.load()
+}
+
+// Histogram -- распределение наблюдений по корзинам с верхними границами buckets
+type Histogram struct {
+	buckets []float64
+	counts  []atomic.Uint64
+	count   atomic.Uint64
+	sum     float
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]atomic.Uint64, len(buckets)),
+	}
+}
+
+// Observe -- добавляет наблюдение
+func (h *Histogram) Observe(value float64) {
+	if h == nil {
+		return
+	}
+
+	// наблюдение попадает в первую корзину, граница которой не меньше значения,
+	// накопительные суммы считаются при выводе
+	if index := sort.SearchFloat64s(h.buckets, value); index < len(h.counts) {
+		h.counts[index].Add(1)
+	}
+	h.count.Add(1)
+	h.sum.add(value)
+}
+
+// Count -- число наблюдений
+func (h *Histogram) Count() uint64 {
+	if h == nil {
+		return 0
+	}
+
+	return h.count.Load()
+}
+
+// Sum -- сумма наблюдений
+func (h *Histogram) Sum() float64 {
+	if h == nil {
+		return 0
+	}
+
+	return h.sum.load()
+}
+
+// vector -- метрики одного имени, различающиеся значениями меток
+type vector[T any] struct {
+	labels []string
+	create func() *T
+
+	mutex  sync.RWMutex
+	series map[string]*labeledSeries[T]
+}
+
+type labeledSeries[T any] struct {
+	values []string
+	metric *T
+}
+
+func newVector[T any](labels []string, create func() *T) *vector[T] {
+	return &vector[T]{
+		labels: labels,
+		create: create,
+		series: make(map[string]*labeledSeries[T]),
+	}
+}
+
+func (v *vector[T]) with(values []string) *T {
+	if v == nil || len(values) != len(v.labels) {
+		return nil
+	}
+
+	key := strings.Join(values, "\xff")
+	v.mutex.RLock()
+	series, exist := v.series[key]
+	v.mutex.RUnlock()
+	if exist {
+		return series.metric
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if series, exist := v.series[key]; exist {
+		return series.metric
+	}
+
+	series = &labeledSeries[T]{values: append([]string(nil), values...), metric: v.create()}
+	v.series[key] = series
+	return series.metric
+}
+
+// snapshot -- серии, отсортированные по значениям меток
+func (v *vector[T]) snapshot() []*labeledSeries[T] {
+	v.mutex.RLock()
+	series := make([]*labeledSeries[T], 0, len(v.series))
+	for _, s := range v.series {
+		series = append(series, s)
+	}
+	v.mutex.RUnlock()
+
+	sort.Slice(series, func(i, j int) bool {
+		return strings.Join(series[i].values, "\xff") < strings.Join(series[j].values, "\xff")
+	})
+	return series
+}
+
+// CounterVec -- счетчики с метками
+type CounterVec struct {
+	vector *vector[Counter]
+}
+
+// WithLabelValues -- счетчик для значений меток в порядке их объявления,
+// при неверном числе значений возвращается nil
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	if c == nil {
+		return nil
+	}
+
+	return c.vector.with(values)
+}
+
+// HistogramVec -- гистограммы с метками
+type HistogramVec struct {
+	vector *vector[Histogram]
+}
+
+// WithLabelValues -- гистограмма для значений меток в порядке их объявления
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	if h == nil {
+		return nil
+	}
+
+	return h.vector.with(values)
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNilMetrics(t *testing.T) {
+	t.Parallel()
+
+	var registry *Registry
+	counter := registry.NewCounter("counter", "help")
+	gauge := registry.NewGauge("gauge", "help")
+	histogram := registry.NewHistogram("histogram", "help", DefaultDurationBuckets)
+	counterVec := registry.NewCounterVec("counter_vec", "help", "label")
+	histogramVec := registry.NewHistogramVec("histogram_vec", "help", DefaultDurationBuckets, "label")
+	registry.NewGaugeFunc("gauge_func", "help", func() float64 { return 1 })
+
+	require.NotPanics(t, func() {
+		counter.Inc()
+		gauge.Set(1)
+		gauge.Dec()
+		histogram.Observe(1)
+		counterVec.WithLabelValues("value").Inc()
+		histogramVec.WithLabelValues("value").Observe(1)
+	})
+
+	assert.Zero(t, counter.Value())
+	assert.Zero(t, gauge.Value())
+	assert.Zero(t, histogram.Count())
+}
+
+func TestCounter(t *testing.T) {
+	t.Parallel()
+
+	counter := NewRegistry().NewCounter("counter", "help")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counter.Inc()
+		}()
+	}
+	wg.Wait()
+
+	counter.Add(0.5)
+	counter.Add(-10)
+	assert.Equal(t, 100.5, counter.Value())
+}
+
+func TestGauge(t *testing.T) {
+	t.Parallel()
+
+	gauge := NewRegistry().NewGauge("gauge", "help")
+	gauge.Inc()
+	gauge.Inc()
+	gauge.Dec()
+	assert.Equal(t, 1.0, gauge.Value())
+
+	gauge.Set(-3)
+	assert.Equal(t, -3.0, gauge.Value())
+}
+
+func TestHistogram(t *testing.T) {
+	t.Parallel()
+
+	histogram := NewRegistry().NewHistogram("histogram", "help", []float64{1, 5, 10})
+	for _, value := range []float64{0.5, 1, 3, 7, 20} {
+		histogram.Observe(value)
+	}
+
+	assert.Equal(t, uint64(5), histogram.Count())
+	assert.Equal(t, 31.5, histogram.Sum())
+	assert.Equal(t, uint64(2), histogram.counts[0].Load())
+	assert.Equal(t, uint64(1), histogram.counts[1].Load())
+	assert.Equal(t, uint64(1), histogram.counts[2].Load())
+}
+
+func TestVectorWithLabelValues(t *testing.T) {
+	t.Parallel()
+
+	counters := NewRegistry().NewCounterVec("counter", "help", "command", "status")
+	counters.WithLabelValues("GET", "ok").Inc()
+	counters.WithLabelValues("GET", "ok").Inc()
+	counters.WithLabelValues("GET", "error").Inc()
+
+	assert.Equal(t, 2.0, counters.WithLabelValues("GET", "ok").Value())
+	assert.Equal(t, 1.0, counters.WithLabelValues("GET", "error").Value())
+	assert.Nil(t, counters.WithLabelValues("GET"))
+}
+
+func TestExponentialBuckets(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []float64{1, 2, 4, 8}, ExponentialBuckets(1, 2, 4))
+}
@@ -0,0 +1,282 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	counterType   = "counter"
+	gaugeType     = "gauge"
+	histogramType = "histogram"
+)
+
+// collector -- зарегистрированная метрика, умеет выводить себя в текстовом формате
+type collector interface {
+	metricType() string
+	write(writer *bufio.Writer, name string)
+}
+
+type entry struct {
+	name      string
+	help      string
+	collector collector
+}
+
+// Registry -- набор метрик. Методы nil реестра возвращают nil метрики,
+// обновление которых ничего не делает: так компоненты работают без метрик
+type Registry struct {
+	mutex   sync.Mutex
+	entries map[string]*entry
+}
+
+// NewRegistry -- конструктор
+func NewRegistry() *Registry {
+	return &Registry{
+		entries: make(map[string]*entry),
+	}
+}
+
+// register -- повторная регистрация имени возвращает уже созданную метрику,
+// чтобы несколько серверов могли вести общие метрики
+func register[T collector](r *Registry, name, help string, create func() T) T {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if existing, exist := r.entries[name]; exist {
+		collector, ok := existing.collector.(T)
+		if !ok {
+			panic(fmt.Sprintf("metric %s is already registered with type %s", name, existing.collector.metricType()))
+		}
+		return collector
+	}
+
+	collector := create()
+	r.entries[name] = &entry{name: name, help: help, collector: collector}
+	return collector
+}
+
+// NewCounter -- счетчик без меток
+func (r *Registry) NewCounter(name, help string) *Counter {
+	if r == nil {
+		return nil
+	}
+
+	return register(r, name, help, func() *counterCollector {
+		return &counterCollector{vector: newVector(nil, func() *Counter { return &Counter{} })}
+	}).vector.with(nil)
+}
+
+// NewCounterVec -- счетчики с метками labels
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	if r == nil {
+		return nil
+	}
+
+	return &CounterVec{vector: register(r, name, help, func() *counterCollector {
+		return &counterCollector{vector: newVector(labels, func() *Counter { return &Counter{} })}
+	}).vector}
+}
+
+// NewGauge -- значение без меток
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	if r == nil {
+		return nil
+	}
+
+	return register(r, name, help, func() *gaugeCollector {
+		return &gaugeCollector{gauge: &Gauge{}}
+	}).gauge
+}
+
+// NewGaugeFunc -- значение, которое вычисляется функцией при каждом выводе
+func (r *Registry) NewGaugeFunc(name, help string, function func() float64) {
+	if r == nil {
+		return
+	}
+
+	register(r, name, help, func() *gaugeFuncCollector {
+		return &gaugeFuncCollector{function: function}
+	})
+}
+
+// NewHistogram -- гистограмма без меток
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	if r == nil {
+		return nil
+	}
+
+	return register(r, name, help, func() *histogramCollector {
+		return newHistogramCollector(buckets, nil)
+	}).vector.with(nil)
+}
+
+// NewHistogramVec -- гистограммы с метками labels
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if r == nil {
+		return nil
+	}
+
+	return &HistogramVec{vector: register(r, name, help, func() *histogramCollector {
+		return newHistogramCollector(buckets, labels)
+	}).vector}
+}
+
+// WriteTo -- выводит метрики, отсортированные по имени, в текстовом формате Prometheus
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mutex.Lock()
+	entries := make([]*entry, 0, len(r.entries))
+	for _, e := range r.entries {
+		entries = append(entries, e)
+	}
+	r.mutex.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+
+	counter := &countingWriter{writer: w}
+	writer := bufio.NewWriter(counter)
+	for _, e := range entries {
+		fmt.Fprintf(writer, "# HELP %s %s\n", e.name, escapeHelp(e.help))
+		fmt.Fprintf(writer, "# TYPE %s %s\n", e.name, e.collector.metricType())
+		e.collector.write(writer, e.name)
+	}
+
+	err := writer.Flush()
+	return counter.written, err
+}
+
+type counterCollector struct {
+	vector *vector[Counter]
+}
+
+func (c *counterCollector) metricType() string { return counterType }
+
+func (c *counterCollector) write(writer *bufio.Writer, name string) {
+	for _, series := range c.vector.snapshot() {
+		writeSample(writer, name, c.vector.labels, series.values, "", "", series.metric.Value())
+	}
+}
+
+type gaugeCollector struct {
+	gauge *Gauge
+}
+
+func (c *gaugeCollector) metricType() string { return gaugeType }
+
+func (c *gaugeCollector) write(writer *bufio.Writer, name string) {
+	writeSample(writer, name, nil, nil, "", "", c.gauge.Value())
+}
+
+type gaugeFuncCollector struct {
+	function func() float64
+}
+
+func (c *gaugeFuncCollector) metricType() string { return gaugeType }
+
+func (c *gaugeFuncCollector) write(writer *bufio.Writer, name string) {
+	writeSample(writer, name, nil, nil, "", "", c.function())
+}
+
+type histogramCollector struct {
+	buckets []float64
+	vector  *vector[Histogram]
+}
+
+func newHistogramCollector(buckets []float64, labels []string) *histogramCollector {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &histogramCollector{
+		buckets: buckets,
+		vector:  newVector(labels, func() *Histogram { return newHistogram(buckets) }),
+	}
+}
+
+func (c *histogramCollector) metricType() string { return histogramType }
+
+func (c *histogramCollector) write(writer *bufio.Writer, name string) {
+	for _, series := range c.vector.snapshot() {
+		histogram := series.metric
+		count := histogram.Count()
+
+		var cumulative uint64
+		for i, bound := range c.buckets {
+			cumulative += histogram.counts[i].Load()
+			writeSample(writer, name+"_bucket", c.vector.labels, series.values, "le", formatFloat(bound), float64(cumulative))
+		}
+		writeSample(writer, name+"_bucket", c.vector.labels, series.values, "le", "+Inf", float64(count))
+		writeSample(writer, name+"_sum", c.vector.labels, series.values, "", "", histogram.Sum())
+		writeSample(writer, name+"_count", c.vector.labels, series.values, "", "", float64(count))
+	}
+}
+
+// writeSample -- строка вида name{label="value",...} value, extraName добавляет
+// служебную метку, например le у корзин гистограммы
+func writeSample(writer *bufio.Writer, name string, labels, values []string, extraName, extraValue string, value float64) {
+	writer.WriteString(name)
+	if len(labels) != 0 || extraName != "" {
+		writer.WriteByte('{')
+		for i, label := range labels {
+			if i != 0 {
+				writer.WriteByte(',')
+			}
+			fmt.Fprintf(writer, "%s=\"%s\"", label, escapeLabelValue(values[i]))
+		}
+		if extraName != "" {
+			if len(labels) != 0 {
+				writer.WriteByte(',')
+			}
+			fmt.Fprintf(writer, "%s=\"%s\"", extraName, extraValue)
+		}
+		writer.WriteByte('}')
+	}
+
+	writer.WriteByte(' ')
+	writer.WriteString(formatFloat(value))
+	writer.WriteByte('\n')
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelReplacer.Replace(value)
+}
+
+type countingWriter struct {
+	writer  io.Writer
+	written int64
+}
+
+func (w *countingWriter) Write(data []byte) (int, error) {
+	count, err := w.writer.Write(data)
+	w.written += int64(count)
+	return count, err
+}
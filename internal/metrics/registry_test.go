@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+	queries := registry.NewCounterVec("kava_queries_total", "Number of queries.", "command", "status")
+	queries.WithLabelValues("SET", "ok").Inc()
+	queries.WithLabelValues("GET", "error").Add(2)
+
+	registry.NewGauge("kava_connections_active", "Open connections.").Set(3)
+	registry.NewGaugeFunc("kava_engine_keys", "Keys.", func() float64 { return 42 })
+
+	latency := registry.NewHistogramVec("kava_query_duration_seconds", "Latency.", []float64{0.1, 1}, "command")
+	latency.WithLabelValues("GET").Observe(0.05)
+	latency.WithLabelValues("GET").Observe(0.5)
+	latency.WithLabelValues("GET").Observe(2)
+
+	var output strings.Builder
+	written, err := registry.WriteTo(&output)
+	require.NoError(t, err)
+	assert.Equal(t, int64(output.Len()), written)
+
+	expected := `# HELP kava_connections_active Open connections.
+# TYPE kava_connections_active gauge
+kava_connections_active 3
+# HELP kava_engine_keys Keys.
+# TYPE kava_engine_keys gauge
+kava_engine_keys 42
+# HELP kava_queries_total Number of queries.
+# TYPE kava_queries_total counter
+kava_queries_total{command="GET",status="error"} 2
+kava_queries_total{command="SET",status="ok"} 1
+# HELP kava_query_duration_seconds Latency.
+# TYPE kava_query_duration_seconds histogram
+kava_query_duration_seconds_bucket{command="GET",le="0.1"} 1
+kava_query_duration_seconds_bucket{command="GET",le="1"} 2
+kava_query_duration_seconds_bucket{command="GET",le="+Inf"} 3
+kava_query_duration_seconds_sum{command="GET"} 2.55
+kava_query_duration_seconds_count{command="GET"} 3
+`
+	assert.Equal(t, expected, output.String())
+}
+
+func TestRegistry_RegisterTwice(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+	first := registry.NewCounter("kava_connections_rejected_total", "Rejected.")
+	second := registry.NewCounter("kava_connections_rejected_total", "Rejected.")
+	require.Same(t, first, second)
+
+	assert.Panics(t, func() {
+		registry.NewGauge("kava_connections_rejected_total", "Rejected.")
+	})
+}
+
+func TestRegistry_EscapeLabelValues(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+	registry.NewCounterVec("kava_test_total", "Line\nbreak.", "value").
+		WithLabelValues("quote \" slash \\ line \n").Inc()
+
+	var output strings.Builder
+	_, err := registry.WriteTo(&output)
+	require.NoError(t, err)
+
+	assert.Contains(t, output.String(), `# HELP kava_test_total Line\nbreak.`)
+	assert.Contains(t, output.String(), `kava_test_total{value="quote \" slash \\ line \n"} 1`)
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	contentType     = "text/plain; version=0.0.4; charset=utf-8"
+	shutdownTimeout = 5 * time.Second
+	// readHeaderTimeout -- защита от клиентов, которые не дописывают заголовки
+	readHeaderTimeout = 5 * time.Second
+)
+
+// Handler -- отдает метрики реестра в текстовом формате Prometheus
+func Handler(registry *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		if registry == nil {
+			return
+		}
+
+		_, _ = registry.WriteTo(w)
+	})
+}
+
+// Server -- HTTP сервер метрик
+type Server struct {
+	listener net.Listener
+	server   *http.Server
+	logger   *zap.Logger
+}
+
+// NewServer -- конструктор, адрес занимается сразу, чтобы ошибка была видна при запуске
+func NewServer(address, path string, registry *Registry, logger *zap.Logger) (*Server, error) {
+	if registry == nil {
+		return nil, errors.New("registry is invalid")
+	}
+
+	if logger == nil {
+		return nil, errors.New("logger is invalid")
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, Handler(registry))
+
+	return &Server{
+		listener: listener,
+		server: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: readHeaderTimeout,
+		},
+		logger: logger,
+	}, nil
+}
+
+// Addr -- адрес, на котором сервер принимает соединения
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Start -- обслуживает запросы до отмены контекста
+func (s *Server) Start(ctx context.Context) error {
+	served := make(chan error, 1)
+	go func() {
+		served <- s.server.Serve(s.listener)
+	}()
+
+	s.logger.Info("metrics server started", zap.String("address", s.listener.Addr().String()))
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := s.server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewServer(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewServer("localhost:0", "/metrics", nil, zap.NewNop())
+	assert.Error(t, err)
+
+	_, err = NewServer("localhost:0", "/metrics", NewRegistry(), nil)
+	assert.Error(t, err)
+
+	_, err = NewServer("invalid address", "/metrics", NewRegistry(), zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestServer_Start(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+	registry.NewCounter("kava_test_total", "Test counter.").Inc()
+
+	server, err := NewServer("localhost:0", "/metrics", registry, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start(ctx)
+	}()
+
+	response, err := http.Get("http://" + server.Addr().String() + "/metrics")
+	require.NoError(t, err)
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, contentType, response.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), "kava_test_total 1\n")
+
+	response, err = http.Get("http://" + server.Addr().String() + "/other")
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	cancel()
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("metrics server did not stop")
+	}
+}
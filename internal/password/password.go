@@ -0,0 +1,106 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Algorithm -- префикс хэша: PBKDF2 с HMAC-SHA256
+	Algorithm = "pbkdf2_sha256"
+	// DefaultIterations -- число итераций для новых хэшей
+	DefaultIterations = 600_000
+	// MinIterations -- меньшее число итераций не принимается в конфигурации
+	MinIterations = 10_000
+
+	saltLength = 16
+	separator  = "$"
+)
+
+// ErrInvalidHash -- строка не в формате pbkdf2_sha256$<iterations>$<salt hex>$<hash hex>
+var ErrInvalidHash = errors.New("password hash is invalid")
+
+// Hash -- разобранный хэш пароля с солью
+type Hash struct {
+	iterations int
+	salt       []byte
+	key        []byte
+}
+
+// Generate -- хэш пароля со случайной солью в формате
+// pbkdf2_sha256$<iterations>$<salt hex>$<hash hex>
+func Generate(password string, iterations int) (string, error) {
+	if iterations < MinIterations {
+		return "", fmt.Errorf("iterations must be at least %d", MinIterations)
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := pbkdf2(password, salt, iterations)
+	return strings.Join([]string{
+		Algorithm,
+		strconv.Itoa(iterations),
+		hex.EncodeToString(salt),
+		hex.EncodeToString(key),
+	}, separator), nil
+}
+
+// Parse -- разбирает строку, созданную Generate
+func Parse(encoded string) (*Hash, error) {
+	parts := strings.Split(encoded, separator)
+	if len(parts) != 4 || parts[0] != Algorithm {
+		return nil, ErrInvalidHash
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < MinIterations {
+		return nil, fmt.Errorf("%w: iterations must be at least %d", ErrInvalidHash, MinIterations)
+	}
+
+	salt, err := hex.DecodeString(parts[2])
+	if err != nil || len(salt) == 0 {
+		return nil, ErrInvalidHash
+	}
+
+	key, err := hex.DecodeString(parts[3])
+	if err != nil || len(key) != sha256.Size {
+		return nil, ErrInvalidHash
+	}
+
+	return &Hash{iterations: iterations, salt: salt, key: key}, nil
+}
+
+// Match -- сравнивает пароль с хэшем за время, не зависящее от совпадения
+func (h *Hash) Match(password string) bool {
+	key := pbkdf2(password, h.salt, h.iterations)
+	return subtle.ConstantTimeCompare(key, h.key) == 1
+}
+
+// pbkdf2 -- первый блок PBKDF2-HMAC-SHA256 (RFC 8018), его длины хватает для ключа
+func pbkdf2(password string, salt []byte, iterations int) []byte {
+	prf := hmac.New(sha256.New, []byte(password))
+	prf.Write(salt)
+	prf.Write(binary.BigEndian.AppendUint32(nil, 1))
+	block := prf.Sum(nil)
+
+	key := append([]byte(nil), block...)
+	for i := 1; i < iterations; i++ {
+		prf.Reset()
+		prf.Write(block)
+		block = prf.Sum(block[:0])
+		subtle.XORBytes(key, key, block)
+	}
+
+	return key
+}
@@ -0,0 +1,75 @@
+package password
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPBKDF2(t *testing.T) {
+	t.Parallel()
+
+	// тестовые векторы PBKDF2-HMAC-SHA256 из RFC 7914
+	assert.Equal(t,
+		"55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc",
+		hex.EncodeToString(pbkdf2("passwd", []byte("salt"), 1)))
+	assert.Equal(t,
+		"4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56",
+		hex.EncodeToString(pbkdf2("Password", []byte("NaCl"), 80000)))
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	encoded, err := Generate("secret", MinIterations)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "pbkdf2_sha256$10000$"))
+
+	other, err := Generate("secret", MinIterations)
+	require.NoError(t, err)
+	assert.NotEqual(t, encoded, other, "salt must be random")
+
+	hash, err := Parse(encoded)
+	require.NoError(t, err)
+	assert.True(t, hash.Match("secret"))
+	assert.False(t, hash.Match("other"))
+
+	_, err = Generate("secret", MinIterations-1)
+	assert.Error(t, err)
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	const (
+		salt = "00112233445566778899aabbccddeeff"
+		key  = "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56"
+	)
+
+	tests := map[string]string{
+		"sha256 hex":         "5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8",
+		"unknown algorithm":  "bcrypt$10000$" + salt + "$" + key,
+		"too few iterations": "pbkdf2_sha256$1000$" + salt + "$" + key,
+		"invalid iterations": "pbkdf2_sha256$many$" + salt + "$" + key,
+		"empty salt":         "pbkdf2_sha256$10000$$" + key,
+		"invalid salt":       "pbkdf2_sha256$10000$salt$" + key,
+		"short hash":         "pbkdf2_sha256$10000$" + salt + "$" + key[:32],
+		"extra part":         "pbkdf2_sha256$10000$" + salt + "$" + key + "$",
+	}
+
+	for name, encoded := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(encoded)
+			assert.ErrorIs(t, err, ErrInvalidHash)
+		})
+	}
+
+	hash, err := Parse("pbkdf2_sha256$10000$" + salt + "$" + key)
+	require.NoError(t, err)
+	assert.Equal(t, 10000, hash.iterations)
+}
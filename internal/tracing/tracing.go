@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kava/internal/common"
+)
+
+// Tracer -- создает спаны выполнения запроса, реализация подключается снаружи,
+// например, адаптером к OpenTelemetry
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span -- интервал выполнения операции, End вызывается ровно один раз
+type Span interface {
+	SetAttribute(key, value string)
+	End(err error)
+}
+
+type tracerKey struct{}
+
+// ContextWithTracer -- трейсер, которым создаются спаны в нижних слоях
+func ContextWithTracer(parent context.Context, tracer Tracer) context.Context {
+	return context.WithValue(parent, tracerKey{}, tracer)
+}
+
+// StartSpan -- начинает спан трейсером из контекста, без трейсера спан ничего не делает
+func StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	tracer, ok := ctx.Value(tracerKey{}).(Tracer)
+	if !ok || tracer == nil {
+		return ctx, noopSpan{}
+	}
+
+	return tracer.Start(ctx, name)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, string) {}
+
+func (noopSpan) End(error) {}
+
+// LogTracer -- пишет завершенные спаны в лог на уровне debug
+type LogTracer struct {
+	logger *zap.Logger
+}
+
+// NewLogTracer -- конструктор
+func NewLogTracer(logger *zap.Logger) *LogTracer {
+	return &LogTracer{logger: logger}
+}
+
+// Start -- начинает спан
+func (t *LogTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, &logSpan{
+		ctx:     ctx,
+		name:    name,
+		started: time.Now(),
+		logger:  t.logger,
+	}
+}
+
+type logSpan struct {
+	ctx        context.Context
+	name       string
+	started    time.Time
+	attributes []zap.Field
+	logger     *zap.Logger
+}
+
+func (s *logSpan) SetAttribute(key, value string) {
+	s.attributes = append(s.attributes, zap.String(key, value))
+}
+
+func (s *logSpan) End(err error) {
+	fields := append(s.attributes, zap.String("span", s.name), zap.Duration("duration", time.Since(s.started)))
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+
+	s.logger.Debug("span finished", common.LogFields(s.ctx, fields...)...)
+}
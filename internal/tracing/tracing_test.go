@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"kava/internal/common"
+)
+
+func TestStartSpanWithoutTracer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	spanCtx, span := StartSpan(ctx, "parse")
+	assert.Equal(t, ctx, spanCtx)
+	require.NotPanics(t, func() {
+		span.SetAttribute("key", "value")
+		span.End(errors.New("error"))
+	})
+}
+
+func TestLogTracer(t *testing.T) {
+	t.Parallel()
+
+	core, logs := observer.New(zap.DebugLevel)
+	ctx := ContextWithTracer(context.Background(), NewLogTracer(zap.New(core)))
+	ctx = common.ContextWithConnectionID(ctx, "7")
+	ctx = common.ContextWithRequestID(ctx, "00000000000000ff")
+
+	_, span := StartSpan(ctx, "wal.wait")
+	span.SetAttribute("command", "SET")
+	span.End(errors.New("wal error"))
+
+	_, span = StartSpan(common.ContextWithTxID(ctx, 42), "engine.apply")
+	span.End(nil)
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "span finished", entries[0].Message)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "wal.wait", fields["span"])
+	assert.Equal(t, "SET", fields["command"])
+	assert.Equal(t, "00000000000000ff", fields["request_id"])
+	assert.Equal(t, "7", fields["connection_id"])
+	assert.Equal(t, "wal error", fields["error"])
+	assert.Contains(t, fields, "duration")
+
+	fields = entries[1].ContextMap()
+	assert.Equal(t, "engine.apply", fields["span"])
+	assert.Equal(t, int64(42), fields["tx_id"])
+	assert.NotContains(t, fields, "error")
+}
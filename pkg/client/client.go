@@ -0,0 +1,327 @@
+// Package client -- клиент KaVa: типизированные команды, контекст и дедлайн
+// на каждый запрос, пул соединений с проверкой простаивающих соединений и
+// повтор идемпотентных команд после обрыва соединения.
+//
+//	kava, err := client.New("localhost:8080", client.WithPoolSize(4))
+//	if err != nil {
+//		return err
+//	}
+//	defer kava.Close()
+//
+//	value, err := kava.Get(ctx, "key")
+//	if errors.Is(err, client.ErrNotFound) {
+//		...
+//	}
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultPoolSize            = 10
+	defaultDialTimeout         = 5 * time.Second
+	defaultRequestTimeout      = 5 * time.Second
+	defaultHealthCheckInterval = 30 * time.Second
+	defaultMaxRetries          = 2
+	defaultRetryBackoff        = 50 * time.Millisecond
+	// defaultMaxMessageSize -- ответы INFO и SLOWLOG GET длиннее запросов,
+	// поэтому буфер больше max_message_size сервера по умолчанию
+	defaultMaxMessageSize = 64 << 10
+)
+
+type options struct {
+	tlsConfig           *tls.Config
+	user                string
+	password            string
+	poolSize            int
+	dialTimeout         time.Duration
+	requestTimeout      time.Duration
+	healthCheckInterval time.Duration
+	maxRetries          int
+	retryBackoff        time.Duration
+	maxMessageSize      int
+}
+
+// Option -- настройка клиента
+type Option func(*options)
+
+// WithTLS -- подключение через TLS
+func WithTLS(config *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = config
+	}
+}
+
+// WithAuth -- каждое новое соединение выполняет AUTH user password
+func WithAuth(user, password string) Option {
+	return func(o *options) {
+		o.user = user
+		o.password = password
+	}
+}
+
+// WithPoolSize -- максимум одновременно открытых соединений, по умолчанию 10
+func WithPoolSize(size int) Option {
+	return func(o *options) {
+		o.poolSize = size
+	}
+}
+
+// WithDialTimeout -- время на подключение и AUTH, по умолчанию 5s
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.dialTimeout = timeout
+	}
+}
+
+// WithRequestTimeout -- дедлайн запроса, если в контексте его нет,
+// по умолчанию 5s, 0 - без дедлайна
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.requestTimeout = timeout
+	}
+}
+
+// WithHealthCheckInterval -- соединения, простаивающие дольше interval,
+// проверяются PING, по умолчанию 30s, 0 - без проверок. Интервал должен
+// быть меньше idle_timeout сервера, иначе он закрывает свободные соединения
+func WithHealthCheckInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.healthCheckInterval = interval
+	}
+}
+
+// WithRetries -- повторы идемпотентных команд после ошибки соединения,
+// пауза перед n-м повтором - n * backoff. По умолчанию 2 повтора через 50ms
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.maxRetries = maxRetries
+		o.retryBackoff = backoff
+	}
+}
+
+// WithMaxMessageSize -- максимальный размер ответа, по умолчанию 64KB
+func WithMaxMessageSize(size int) Option {
+	return func(o *options) {
+		o.maxMessageSize = size
+	}
+}
+
+// Client -- клиент KaVa, безопасен для конкурентного использования
+type Client struct {
+	address string
+	options options
+	pool    *pool
+
+	closeOnce sync.Once
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// New -- создание клиента, соединения открываются при первых запросах.
+// Адрес unix:///path/kava.sock подключает к unix сокету
+func New(address string, opts ...Option) (*Client, error) {
+	if address == "" {
+		return nil, errors.New("address is invalid")
+	}
+
+	o := options{
+		poolSize:            defaultPoolSize,
+		dialTimeout:         defaultDialTimeout,
+		requestTimeout:      defaultRequestTimeout,
+		healthCheckInterval: defaultHealthCheckInterval,
+		maxRetries:          defaultMaxRetries,
+		retryBackoff:        defaultRetryBackoff,
+		maxMessageSize:      defaultMaxMessageSize,
+	}
+	for _, option := range opts {
+		option(&o)
+	}
+
+	switch {
+	case o.poolSize <= 0:
+		return nil, errors.New("pool size must be positive")
+	case o.maxMessageSize <= 0:
+		return nil, errors.New("max message size must be positive")
+	case o.maxRetries < 0:
+		return nil, errors.New("max retries must not be negative")
+	}
+
+	client := &Client{
+		address: address,
+		options: o,
+		done:    make(chan struct{}),
+	}
+	client.pool = newPool(o.poolSize, client.dial)
+
+	if o.healthCheckInterval > 0 {
+		client.wg.Add(1)
+		go client.checkHealth()
+	}
+
+	return client, nil
+}
+
+// Close -- закрывает соединения, запросы после Close возвращают ErrClosed
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.wg.Wait()
+		c.pool.close()
+	})
+
+	return nil
+}
+
+// Warmup -- открывает соединения заранее, чтобы первые запросы не ждали
+// подключения: берет из пула n соединений одновременно и возвращает их
+// свободными. n больше WithPoolSize уменьшается до размера пула
+func (c *Client) Warmup(ctx context.Context, n int) error {
+	conns := make([]*conn, 0, min(n, c.options.poolSize))
+	defer func() {
+		for _, conn := range conns {
+			c.pool.put(conn)
+		}
+	}()
+
+	for len(conns) < cap(conns) {
+		conn, err := c.pool.get(ctx)
+		if err != nil {
+			return err
+		}
+		conns = append(conns, conn)
+	}
+
+	return nil
+}
+
+// Do -- выполняет произвольную команду и возвращает значение из ответа
+// "[ok] value". Команда не повторяется, так как ее идемпотентность неизвестна
+func (c *Client) Do(ctx context.Context, command string, args ...string) (string, error) {
+	return c.do(ctx, false, command, args...)
+}
+
+func (c *Client) do(ctx context.Context, idempotent bool, command string, args ...string) (string, error) {
+	for _, arg := range args {
+		if arg == "" || strings.ContainsFunc(arg, unicode.IsSpace) {
+			return "", fmt.Errorf("%w: %q", ErrInvalidValue, arg)
+		}
+	}
+
+	query := strings.Join(append([]string{command}, args...), " ")
+	for attempt := 0; ; attempt++ {
+		value, err := c.execute(ctx, command, query)
+		if err == nil || attempt >= c.options.maxRetries || !retryable(err, idempotent) {
+			return value, err
+		}
+
+		timer := time.NewTimer(c.options.retryBackoff * time.Duration(attempt+1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) execute(ctx context.Context, command, query string) (string, error) {
+	if _, exist := ctx.Deadline(); !exist && c.options.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.options.requestTimeout)
+		defer cancel()
+	}
+
+	conn, err := c.pool.get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := conn.roundTrip(ctx, query)
+	if err != nil {
+		c.pool.put(conn)
+		return "", err
+	}
+
+	value, err := parseResponse(command, response)
+	// после этих ответов сервер закрывает соединение
+	if errors.Is(err, ErrTooManyConnections) || errors.Is(err, ErrQueryTimeout) || errors.Is(err, ErrMessageTooLarge) {
+		conn.broken = true
+	}
+	c.pool.put(conn)
+
+	return value, err
+}
+
+// retryable -- запрос можно повторить: он не дошел до сервера
+// или команда идемпотентна и соединение оборвалось
+func retryable(err error, idempotent bool) bool {
+	if errors.Is(err, ErrTooManyConnections) {
+		return true
+	}
+
+	var connectionErr *ConnectionError
+	if !errors.As(err, &connectionErr) {
+		return false
+	}
+
+	return connectionErr.Op == "dial" || idempotent
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.options.dialTimeout)
+	defer cancel()
+
+	conn, err := dial(ctx, c.address, c.options.tlsConfig, c.options.maxMessageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.options.user == "" {
+		return conn, nil
+	}
+
+	response, err := conn.roundTrip(ctx, "AUTH "+c.options.user+" "+c.options.password)
+	if err == nil {
+		_, err = parseResponse("AUTH", response)
+	}
+	if err != nil {
+		_ = conn.close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (c *Client) checkHealth() {
+	defer c.wg.Done()
+
+	interval := c.options.healthCheckInterval
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		c.pool.healthCheck(interval, func(conn *conn) error {
+			ctx, cancel := context.WithTimeout(context.Background(), c.options.dialTimeout)
+			defer cancel()
+
+			// любой ответ, в том числе отказ в правах на PING, означает, что соединение живо
+			_, err := conn.roundTrip(ctx, pingCommand)
+			return err
+		})
+	}
+}
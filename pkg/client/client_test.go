@@ -0,0 +1,519 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"kava/internal/configuration"
+	"kava/internal/database"
+	"kava/internal/database/auth"
+	"kava/internal/database/compute"
+	"kava/internal/database/server"
+	"kava/internal/database/slowlog"
+	"kava/internal/database/storage"
+	"kava/internal/database/storage/engine/in_memory"
+)
+
+// readerPasswordHash -- хэш пароля "reader"
+const readerPasswordHash = "pbkdf2_sha256$10000$528bc7c0bb5c51ee08ddee156ec4f832$9cbf699aa40d38b81ffe360c2506ea071af468d14247d8160252e8502a69802a"
+
+// startServer -- сервер KaVa на свободном порту, останавливается в конце теста
+func startServer(t *testing.T, idleTimeout time.Duration, options ...server.TCPServerOption) string {
+	t.Helper()
+
+	logger := zap.NewNop()
+	engine, err := in_memory.NewEngine(logger)
+	require.NoError(t, err)
+	storageLayer, err := storage.NewStorage(engine, nil, logger)
+	require.NoError(t, err)
+	computeLayer, err := compute.NewCompute(logger)
+	require.NoError(t, err)
+	slowLog, err := slowlog.NewSlowLog(0, 10, nil)
+	require.NoError(t, err)
+	db, err := database.NewDatabase(computeLayer, storageLayer, logger,
+		database.WithAdminCommands(true), database.WithSlowLog(slowLog))
+	require.NoError(t, err)
+
+	tcpServer, err := server.NewTCPServer(&configuration.TCPServerConfig{
+		Host:           "localhost",
+		MaxConnections: 10,
+		MaxMessageSize: 4096,
+		IdleTimeout:    idleTimeout,
+		DrainTimeout:   time.Second,
+	}, db, logger, options...)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = tcpServer.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return tcpServer.Addr().String()
+}
+
+func newClient(t *testing.T, address string, options ...Option) *Client {
+	t.Helper()
+
+	client, err := New(address, options...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+func TestClient_Commands(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t, startServer(t, time.Minute))
+
+	require.NoError(t, client.Ping(ctx))
+	require.NoError(t, client.Set(ctx, "key", "value"))
+
+	value, err := client.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	message, err := client.Echo(ctx, "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", message)
+
+	size, err := client.DBSize(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, size)
+
+	info, err := client.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), info.Keys)
+
+	entries, err := client.SlowLogGet(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "INFO", entries[0].Command)
+	assert.Equal(t, "DBSIZE", entries[1].Command)
+
+	length, err := client.SlowLogLen(ctx)
+	require.NoError(t, err)
+	assert.Positive(t, length)
+	require.NoError(t, client.SlowLogReset(ctx))
+
+	require.NoError(t, client.Del(ctx, "key"))
+	_, err = client.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	var serverErr *ServerError
+	require.ErrorAs(t, err, &serverErr)
+	assert.Equal(t, "GET", serverErr.Command)
+	assert.Equal(t, "key not exist", serverErr.Message)
+
+	require.NoError(t, client.Set(ctx, "other", "value"))
+	require.NoError(t, client.FlushAll(ctx))
+	size, err = client.DBSize(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, size)
+
+	_, err = client.Do(ctx, "UNKNOWN")
+	assert.ErrorIs(t, err, ErrInvalidCommand)
+}
+
+func TestClient_InvalidValue(t *testing.T) {
+	client := newClient(t, "localhost:1")
+
+	// значение с пробелом сервер разобрал бы как лишний аргумент
+	err := client.Set(context.Background(), "key", "two words")
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	_, err = client.Get(context.Background(), "")
+	assert.ErrorIs(t, err, ErrInvalidValue)
+}
+
+func TestClient_Auth(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator([]configuration.UserConfig{{
+		Name:         "reader",
+		PasswordHash: readerPasswordHash,
+		Commands:     []string{"GET"},
+		Keys:         []string{"*"},
+	}})
+	require.NoError(t, err)
+	address := startServer(t, time.Minute, server.WithAuthenticator(authenticator))
+	ctx := context.Background()
+
+	client := newClient(t, address, WithAuth("reader", "reader"))
+	_, err = client.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, client.Set(ctx, "key", "value"), ErrPermissionDenied)
+
+	client = newClient(t, address, WithAuth("reader", "wrong"))
+	_, err = client.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrAuthenticationFailed)
+
+	client = newClient(t, address)
+	_, err = client.Get(ctx, "key")
+	assert.ErrorIs(t, err, ErrAuthenticationNeeded)
+}
+
+// startFakeServer -- сервер, который обрабатывает соединения handle
+func startFakeServer(t *testing.T, handle func(number int, connection net.Conn)) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		_ = listener.Close()
+		wg.Wait()
+	})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for number := 0; ; number++ {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer connection.Close()
+				handle(number, connection)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestClient_Context(t *testing.T) {
+	// сервер читает запросы, но не отвечает
+	address := startFakeServer(t, func(_ int, connection net.Conn) {
+		_, _ = bufio.NewReader(connection).ReadString('\n')
+		time.Sleep(time.Second)
+	})
+	client := newClient(t, address, WithRequestTimeout(0), WithRetries(0, 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, err := client.Get(ctx, "key")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), 500*time.Millisecond)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = client.Get(ctx, "key")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// дедлайн по умолчанию для контекста без дедлайна
+	client = newClient(t, address, WithRequestTimeout(50*time.Millisecond), WithRetries(0, 0))
+	_, err = client.Get(context.Background(), "key")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_Retry(t *testing.T) {
+	// первое соединение обрывается после запроса, остальные отвечают
+	dropFirst := func(response string) func(int, net.Conn) {
+		return func(number int, connection net.Conn) {
+			reader := bufio.NewReader(connection)
+			for {
+				if _, err := reader.ReadString('\n'); err != nil || number == 0 {
+					return
+				}
+				if _, err := connection.Write([]byte(response + "\n")); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	t.Run("idempotent command", func(t *testing.T) {
+		address := startFakeServer(t, dropFirst("[ok] value"))
+		client := newClient(t, address, WithRetries(2, time.Millisecond))
+		value, err := client.Get(context.Background(), "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+	})
+
+	t.Run("idempotent write command", func(t *testing.T) {
+		// DEL отсутствующего ключа отвечает [ok], повтор безопасен
+		address := startFakeServer(t, dropFirst("[ok]"))
+		client := newClient(t, address, WithRetries(2, time.Millisecond))
+		assert.NoError(t, client.Del(context.Background(), "key"))
+	})
+
+	t.Run("not idempotent command", func(t *testing.T) {
+		var connections atomic.Int32
+		address := startFakeServer(t, func(_ int, connection net.Conn) {
+			connections.Add(1)
+			_, _ = bufio.NewReader(connection).ReadString('\n')
+		})
+
+		// повтор дошедшего SET перезаписал бы более новое значение
+		client := newClient(t, address, WithRetries(2, time.Millisecond))
+		err := client.Set(context.Background(), "key", "value")
+
+		var connectionErr *ConnectionError
+		assert.ErrorAs(t, err, &connectionErr)
+		assert.Equal(t, int32(1), connections.Load())
+	})
+
+	t.Run("too many connections", func(t *testing.T) {
+		address := startFakeServer(t, func(number int, connection net.Conn) {
+			if number == 0 {
+				_, _ = connection.Write([]byte("[error] too many connections\n"))
+				return
+			}
+
+			reader := bufio.NewReader(connection)
+			for {
+				if _, err := reader.ReadString('\n'); err != nil {
+					return
+				}
+				if _, err := connection.Write([]byte("[ok]\n")); err != nil {
+					return
+				}
+			}
+		})
+
+		// отказ по max_connections означает, что команда не выполнялась
+		client := newClient(t, address, WithRetries(1, time.Millisecond))
+		assert.NoError(t, client.Del(context.Background(), "key"))
+	})
+}
+
+func TestClient_Pool(t *testing.T) {
+	release := make(chan struct{})
+	address := startFakeServer(t, func(_ int, connection net.Conn) {
+		reader := bufio.NewReader(connection)
+		for {
+			if _, err := reader.ReadString('\n'); err != nil {
+				return
+			}
+			<-release
+			if _, err := connection.Write([]byte("[ok] value\n")); err != nil {
+				return
+			}
+		}
+	})
+
+	client := newClient(t, address, WithPoolSize(1))
+
+	done := make(chan error)
+	go func() {
+		_, err := client.Get(context.Background(), "key")
+		done <- err
+	}()
+
+	// единственное соединение занято, второй запрос ждет его до дедлайна
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.Get(ctx, "key")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	require.NoError(t, <-done)
+	assert.Equal(t, 1, client.pool.idleCount())
+
+	value, err := client.Get(context.Background(), "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+}
+
+func TestClient_HealthCheck(t *testing.T) {
+	// сервер закрывает соединения, простаивающие 200ms
+	address := startServer(t, 200*time.Millisecond)
+	ctx := context.Background()
+
+	client := newClient(t, address, WithHealthCheckInterval(50*time.Millisecond))
+	require.NoError(t, client.Set(ctx, "key", "value"))
+
+	// проверки не дают серверу закрыть свободное соединение,
+	// и неидемпотентный SET выполняется без ошибки соединения.
+	// Проверка на время PING забирает соединение из свободных
+	time.Sleep(500 * time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return client.pool.idleCount() == 1
+	}, time.Second, time.Millisecond)
+	require.NoError(t, client.Set(ctx, "key", "other"))
+
+	// без проверок соединение закрывается сервером и удаляется из пула только при ошибке
+	unchecked := newClient(t, address, WithHealthCheckInterval(0), WithRetries(0, 0))
+	require.NoError(t, unchecked.Set(ctx, "key", "value"))
+	time.Sleep(400 * time.Millisecond)
+
+	var connectionErr *ConnectionError
+	assert.ErrorAs(t, unchecked.Set(ctx, "key", "other"), &connectionErr)
+	assert.Zero(t, unchecked.pool.idleCount())
+}
+
+func TestClient_HealthCheckRemovesBroken(t *testing.T) {
+	var connections atomic.Int32
+	address := startFakeServer(t, func(_ int, connection net.Conn) {
+		connections.Add(1)
+		reader := bufio.NewReader(connection)
+		// на первый запрос отвечает, затем закрывает соединение
+		if _, err := reader.ReadString('\n'); err != nil {
+			return
+		}
+		_, _ = connection.Write([]byte("[ok] PONG\n"))
+	})
+
+	client := newClient(t, address, WithHealthCheckInterval(30*time.Millisecond))
+	require.NoError(t, client.Ping(context.Background()))
+	assert.Equal(t, 1, client.pool.idleCount())
+
+	assert.Eventually(t, func() bool {
+		return client.pool.idleCount() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPool_HealthCheckHoldsSlot(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	p := newPool(1, nil)
+	p.release(&conn{connection: client, reader: bufio.NewReader(client)})
+	p.idle[0].lastUsed = time.Now().Add(-time.Minute)
+
+	checks := 0
+	check := func(*conn) error {
+		checks++
+		// на время проверки соединение занимает единственное место пула
+		assert.False(t, p.slots.TryAcquire())
+		return nil
+	}
+
+	// место занято выданным соединением, проверка откладывается
+	p.slots.Acquire()
+	p.healthCheck(time.Second, check)
+	assert.Zero(t, checks)
+	assert.Equal(t, 1, p.idleCount())
+
+	p.slots.Release()
+	p.healthCheck(time.Second, check)
+	assert.Equal(t, 1, checks)
+	assert.Equal(t, 1, p.idleCount())
+	assert.True(t, p.slots.TryAcquire())
+}
+
+func TestClient_Warmup(t *testing.T) {
+	var connections atomic.Int32
+	address := startFakeServer(t, func(_ int, connection net.Conn) {
+		connections.Add(1)
+		_, _ = bufio.NewReader(connection).ReadString('\n')
+	})
+
+	client := newClient(t, address, WithPoolSize(3))
+	require.NoError(t, client.Warmup(context.Background(), 5))
+	assert.Equal(t, 3, client.pool.idleCount())
+	assert.Eventually(t, func() bool {
+		return connections.Load() == 3
+	}, time.Second, 10*time.Millisecond)
+
+	// свободные соединения переиспользуются
+	require.NoError(t, client.Warmup(context.Background(), 2))
+	assert.Equal(t, 3, client.pool.idleCount())
+	assert.Equal(t, int32(3), connections.Load())
+}
+
+func TestClient_Close(t *testing.T) {
+	client := newClient(t, startServer(t, time.Minute))
+	require.NoError(t, client.Ping(context.Background()))
+
+	require.NoError(t, client.Close())
+	require.NoError(t, client.Close())
+	assert.ErrorIs(t, client.Ping(context.Background()), ErrClosed)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("")
+	assert.Error(t, err)
+	_, err = New("localhost:8080", WithPoolSize(0))
+	assert.Error(t, err)
+	_, err = New("localhost:8080", WithRetries(-1, 0))
+	assert.Error(t, err)
+}
+
+func TestParseResponse(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		response string
+		value    string
+		err      error
+	}{
+		"ok with value":      {response: "[ok] value", value: "value"},
+		"ok without value":   {response: "[ok]"},
+		"not found":          {response: "[error] key not exist", err: ErrNotFound},
+		"permission denied":  {response: "[error] permission denied", err: ErrPermissionDenied},
+		"query timeout":      {response: "[error] query timeout exceeded", err: ErrQueryTimeout},
+		"unexpected":         {response: "value", err: ErrUnexpectedResponse},
+		"unknown error text": {response: "[error] something new"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			value, err := parseResponse("GET", test.response)
+			assert.Equal(t, test.value, value)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+			} else if name == "unknown error text" {
+				var serverErr *ServerError
+				require.ErrorAs(t, err, &serverErr)
+				assert.Nil(t, errors.Unwrap(err))
+				assert.Equal(t, "kava: GET: something new", err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// slowDatabase -- отвечает после отмены контекста запроса, как база данных,
+// которая не успела выполнить запрос
+type slowDatabase struct{}
+
+func (slowDatabase) HandleQuery(ctx context.Context, _ string) string {
+	<-ctx.Done()
+	return "[error] " + ctx.Err().Error()
+}
+
+func TestClient_QueryTimeout(t *testing.T) {
+	tcpServer, err := server.NewTCPServer(&configuration.TCPServerConfig{
+		Host:           "localhost",
+		MaxConnections: 10,
+		MaxMessageSize: 4096,
+		IdleTimeout:    time.Minute,
+		QueryTimeout:   20 * time.Millisecond,
+	}, slowDatabase{}, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = tcpServer.Start(ctx)
+	}()
+
+	client := newClient(t, tcpServer.Addr().String(), WithRetries(0, 0))
+	_, err = client.Get(context.Background(), "key")
+	assert.ErrorIs(t, err, ErrQueryTimeout)
+}
@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const pingCommand = "PING"
+
+// Get -- значение ключа, ErrNotFound, если ключа нет
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	return c.do(ctx, true, "GET", key)
+}
+
+// Set -- записывает значение ключа. Не повторяется после обрыва соединения:
+// запрос мог дойти до сервера, и повтор перезаписал бы более новое значение,
+// записанное другим клиентом в промежутке
+func (c *Client) Set(ctx context.Context, key, value string) error {
+	_, err := c.do(ctx, false, "SET", key, value)
+	return err
+}
+
+// Del -- удаляет ключ, удаление отсутствующего ключа не ошибка, поэтому
+// команда идемпотентна и повторяется после обрыва соединения
+func (c *Client) Del(ctx context.Context, key string) error {
+	_, err := c.do(ctx, true, "DEL", key)
+	return err
+}
+
+// Ping -- проверка соединения с сервером
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.do(ctx, true, pingCommand)
+	return err
+}
+
+// Echo -- возвращает message
+func (c *Client) Echo(ctx context.Context, message string) (string, error) {
+	return c.do(ctx, true, "ECHO", message)
+}
+
+// DBSize -- количество ключей, требует включенных административных команд
+func (c *Client) DBSize(ctx context.Context) (int, error) {
+	value, err := c.do(ctx, true, "DBSIZE")
+	if err != nil {
+		return 0, err
+	}
+
+	return parseInt("DBSIZE", value)
+}
+
+// FlushAll -- удаляет все ключи, требует включенных административных команд
+func (c *Client) FlushAll(ctx context.Context) error {
+	_, err := c.do(ctx, false, "FLUSHALL")
+	return err
+}
+
+// Info -- статистика сервера из ответа INFO
+type Info struct {
+	Uptime      time.Duration
+	Keys        int64
+	UsedMemory  int64
+	WALSegments int64
+	WALSize     int64
+	LastLSN     int64
+	Connections int64
+}
+
+// Info -- статистика сервера, требует включенных административных команд
+func (c *Client) Info(ctx context.Context) (Info, error) {
+	value, err := c.do(ctx, true, "INFO")
+	if err != nil {
+		return Info{}, err
+	}
+
+	fields, err := parseFields("INFO", value)
+	if err != nil {
+		return Info{}, err
+	}
+
+	var info Info
+	var uptime int64
+	for name, target := range map[string]*int64{
+		"uptime_seconds": &uptime,
+		"keys":           &info.Keys,
+		"used_memory":    &info.UsedMemory,
+		"wal_segments":   &info.WALSegments,
+		"wal_size":       &info.WALSize,
+		"last_lsn":       &info.LastLSN,
+		"connections":    &info.Connections,
+	} {
+		if *target, err = parseInt64("INFO", fields[name]); err != nil {
+			return Info{}, err
+		}
+	}
+	info.Uptime = time.Duration(uptime) * time.Second
+
+	return info, nil
+}
+
+// SlowLogEntry -- запись журнала медленных запросов
+type SlowLogEntry struct {
+	ID        int64
+	Timestamp time.Time
+	Duration  time.Duration
+	Command   string
+	Key       string
+	Client    string
+}
+
+// SlowLogGet -- последние count записей журнала медленных запросов от новых
+// к старым, count 0 - количество по умолчанию сервера
+func (c *Client) SlowLogGet(ctx context.Context, count int) ([]SlowLogEntry, error) {
+	args := []string{"GET"}
+	if count > 0 {
+		args = append(args, strconv.Itoa(count))
+	}
+
+	value, err := c.do(ctx, true, "SLOWLOG", args...)
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	var entries []SlowLogEntry
+	for _, record := range strings.Split(value, "; ") {
+		fields, err := parseFields("SLOWLOG", record)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := parseInt64("SLOWLOG", fields["id"])
+		if err != nil {
+			return nil, err
+		}
+		timestamp, err := parseInt64("SLOWLOG", fields["timestamp"])
+		if err != nil {
+			return nil, err
+		}
+		duration, err := parseInt64("SLOWLOG", fields["duration_us"])
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, SlowLogEntry{
+			ID:        id,
+			Timestamp: time.Unix(timestamp, 0),
+			Duration:  time.Duration(duration) * time.Microsecond,
+			Command:   fields["command"],
+			Key:       fields["key"],
+			Client:    fields["client"],
+		})
+	}
+
+	return entries, nil
+}
+
+// SlowLogLen -- количество записей журнала медленных запросов
+func (c *Client) SlowLogLen(ctx context.Context) (int, error) {
+	value, err := c.do(ctx, true, "SLOWLOG", "LEN")
+	if err != nil {
+		return 0, err
+	}
+
+	return parseInt("SLOWLOG", value)
+}
+
+// SlowLogReset -- очищает журнал медленных запросов
+func (c *Client) SlowLogReset(ctx context.Context) error {
+	_, err := c.do(ctx, false, "SLOWLOG", "RESET")
+	return err
+}
+
+// parseFields -- поля "name:value" через пробел
+func parseFields(command, value string) (map[string]string, error) {
+	fields := make(map[string]string)
+	for _, field := range strings.Fields(value) {
+		name, fieldValue, found := strings.Cut(field, ":")
+		if !found {
+			return nil, fmt.Errorf("%w: %s: field %q", ErrUnexpectedResponse, command, field)
+		}
+		fields[name] = fieldValue
+	}
+
+	return fields, nil
+}
+
+func parseInt(command, value string) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %q is not a number", ErrUnexpectedResponse, command, value)
+	}
+
+	return number, nil
+}
+
+func parseInt64(command, value string) (int64, error) {
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %q is not a number", ErrUnexpectedResponse, command, value)
+	}
+
+	return number, nil
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const unixScheme = "unix://"
+
+// conn -- соединение пула, запросы и ответы разделяются переводом строки
+type conn struct {
+	connection net.Conn
+	reader     *bufio.Reader
+	bufferSize int
+	// lastUsed -- время возврата в пул, меняется только владельцем соединения
+	lastUsed time.Time
+	// broken -- поток ответов разошелся с запросами, соединение нельзя
+	// вернуть в пул
+	broken bool
+}
+
+func dial(ctx context.Context, address string, tlsConfig *tls.Config, bufferSize int) (*conn, error) {
+	network := "tcp"
+	if path, ok := strings.CutPrefix(address, unixScheme); ok {
+		network, address = "unix", path
+	}
+
+	var dialer interface {
+		DialContext(ctx context.Context, network, address string) (net.Conn, error)
+	} = &net.Dialer{}
+	if tlsConfig != nil {
+		dialer = &tls.Dialer{Config: tlsConfig}
+	}
+
+	connection, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, &ConnectionError{Op: "dial", Err: err}
+	}
+
+	return &conn{
+		connection: connection,
+		reader:     bufio.NewReaderSize(connection, bufferSize),
+		bufferSize: bufferSize,
+		lastUsed:   time.Now(),
+	}, nil
+}
+
+// roundTrip -- отправляет запрос и читает ответ до дедлайна ctx. При отмене
+// ctx или ошибке соединения оно помечается сломанным
+func (c *conn) roundTrip(ctx context.Context, query string) (string, error) {
+	deadline, _ := ctx.Deadline()
+	if err := c.connection.SetDeadline(deadline); err != nil {
+		c.broken = true
+		return "", &ConnectionError{Op: "set deadline", Err: err}
+	}
+
+	// отмена ctx прерывает чтение и запись немедленно
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.connection.SetDeadline(time.Now())
+	})
+	defer func() {
+		// дедлайн мог быть сброшен уже после ответа, повторно
+		// использовать такое соединение нельзя
+		if !stop() {
+			c.broken = true
+		}
+	}()
+
+	response, err := c.exchange(query)
+	if err != nil {
+		c.broken = true
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		// дедлайн соединения срабатывает раньше таймера контекста
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return "", context.DeadlineExceeded
+		}
+		return "", err
+	}
+
+	return response, nil
+}
+
+func (c *conn) exchange(query string) (string, error) {
+	if _, err := c.connection.Write([]byte(query + "\n")); err != nil {
+		return "", &ConnectionError{Op: "write", Err: err}
+	}
+
+	line, err := c.reader.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, c.bufferSize)
+	} else if err != nil {
+		return "", &ConnectionError{Op: "read", Err: err}
+	}
+
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+func (c *conn) close() error {
+	return c.connection.Close()
+}
+
+// ConnectionError -- ошибка сети: соединение не установлено или оборвалось
+type ConnectionError struct {
+	Op  string
+	Err error
+}
+
+func (e *ConnectionError) Error() string {
+	return "kava: " + e.Op + ": " + e.Err.Error()
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"errors"
+	"strings"
+)
+
+// Ошибки из ответов сервера, проверяются через errors.Is
+var (
+	ErrNotFound             = errors.New("key not exist")
+	ErrInvalidArguments     = errors.New("invalid arguments")
+	ErrInvalidCommand       = errors.New("invalid command")
+	ErrAuthenticationFailed = errors.New("invalid credentials")
+	ErrAuthenticationNeeded = errors.New("authentication required")
+	ErrPermissionDenied     = errors.New("permission denied")
+	ErrAdminDisabled        = errors.New("admin commands are disabled")
+	ErrSlowLogDisabled      = errors.New("slow log is disabled")
+	ErrReadOnly             = errors.New("storage is read-only")
+	ErrTooManyConnections   = errors.New("too many connections")
+	ErrMessageTooLarge      = errors.New("message is too large")
+	ErrQueryTimeout         = errors.New("query timeout exceeded")
+	ErrInternal             = errors.New("internal error")
+)
+
+// Ошибки клиента
+var (
+	// ErrClosed -- клиент закрыт
+	ErrClosed = errors.New("client is closed")
+	// ErrInvalidValue -- ключ или значение нельзя передать в протоколе:
+	// пустая строка или пробельные символы
+	ErrInvalidValue = errors.New("key and value must be non-empty and must not contain whitespace")
+	// ErrResponseTooLarge -- ответ не помещается в буфер MaxMessageSize
+	ErrResponseTooLarge = errors.New("response is too large")
+	// ErrUnexpectedResponse -- ответ не соответствует протоколу
+	ErrUnexpectedResponse = errors.New("unexpected response")
+)
+
+// serverErrors -- сообщения сервера и соответствующие им ошибки
+var serverErrors = map[string]error{
+	ErrNotFound.Error():             ErrNotFound,
+	ErrInvalidArguments.Error():     ErrInvalidArguments,
+	"invalid query":                 ErrInvalidArguments,
+	ErrInvalidCommand.Error():       ErrInvalidCommand,
+	ErrAuthenticationFailed.Error(): ErrAuthenticationFailed,
+	ErrAuthenticationNeeded.Error(): ErrAuthenticationNeeded,
+	"authentication is disabled":    ErrAuthenticationFailed,
+	ErrPermissionDenied.Error():     ErrPermissionDenied,
+	ErrAdminDisabled.Error():        ErrAdminDisabled,
+	ErrSlowLogDisabled.Error():      ErrSlowLogDisabled,
+	ErrReadOnly.Error():             ErrReadOnly,
+	ErrTooManyConnections.Error():   ErrTooManyConnections,
+	ErrMessageTooLarge.Error():      ErrMessageTooLarge,
+	ErrQueryTimeout.Error():         ErrQueryTimeout,
+	ErrInternal.Error():             ErrInternal,
+}
+
+// ServerError -- сервер ответил на запрос ошибкой
+type ServerError struct {
+	// Command -- команда запроса
+	Command string
+	// Message -- сообщение сервера без префикса [error]
+	Message string
+
+	err error
+}
+
+func (e *ServerError) Error() string {
+	return "kava: " + e.Command + ": " + e.Message
+}
+
+// Unwrap -- одна из ошибок ErrNotFound, ErrPermissionDenied и других,
+// nil для неизвестного сообщения
+func (e *ServerError) Unwrap() error {
+	return e.err
+}
+
+// parseResponse -- значение из ответа "[ok] value" или ServerError из "[error] message"
+func parseResponse(command, response string) (string, error) {
+	if message, failed := strings.CutPrefix(response, "[error]"); failed {
+		message = strings.TrimSpace(message)
+		return "", &ServerError{Command: command, Message: message, err: serverErrors[message]}
+	}
+
+	if value, ok := strings.CutPrefix(response, "[ok]"); ok {
+		return strings.TrimPrefix(value, " "), nil
+	}
+
+	return "", &ServerError{Command: command, Message: response, err: ErrUnexpectedResponse}
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"kava/pkg/concurrency"
+)
+
+// pool -- соединения с сервером, не больше size одновременно. Свободные
+// соединения переиспользуются, последние возвращенные - первыми
+type pool struct {
+	dial  func(ctx context.Context) (*conn, error)
+	size  int
+	slots concurrency.Semaphore
+
+	mutex  sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+func newPool(size int, dial func(ctx context.Context) (*conn, error)) *pool {
+	return &pool{
+		dial:  dial,
+		size:  size,
+		slots: concurrency.NewSemaphore(size),
+	}
+}
+
+// get -- свободное соединение или новое, ждет освобождения места до отмены ctx
+func (p *pool) get(ctx context.Context) (*conn, error) {
+	if err := p.slots.AcquireWithContext(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		p.slots.Release()
+		return nil, ErrClosed
+	}
+
+	if count := len(p.idle); count != 0 {
+		c := p.idle[count-1]
+		p.idle = p.idle[:count-1]
+		p.mutex.Unlock()
+		return c, nil
+	}
+	p.mutex.Unlock()
+
+	c, err := p.dial(ctx)
+	if err != nil {
+		p.slots.Release()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// put -- возвращает соединение, полученное через get, сломанное закрывается
+func (p *pool) put(c *conn) {
+	defer p.slots.Release()
+	p.release(c)
+}
+
+func (p *pool) release(c *conn) {
+	if c.broken {
+		_ = c.close()
+		return
+	}
+
+	c.lastUsed = time.Now()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// пока соединение проверялось, пул мог открыть новое вместо него
+	if p.closed || len(p.idle) >= p.size {
+		_ = c.close()
+		return
+	}
+	p.idle = append(p.idle, c)
+}
+
+// takeIdle -- забирает из пула соединение, простаивающее дольше idleTime,
+// nil, если таких нет
+func (p *pool) takeIdle(idleTime time.Duration) *conn {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for i, c := range p.idle {
+		if time.Since(c.lastUsed) >= idleTime {
+			p.idle = append(p.idle[:i], p.idle[i+1:]...)
+			return c
+		}
+	}
+
+	return nil
+}
+
+// healthCheck -- проверяет соединения, простаивающие дольше interval:
+// рабочие возвращаются в пул, остальные закрываются. Проверка продлевает
+// соединению idle_timeout сервера. Проверяемое соединение занимает место
+// в пуле, как выданное get, поэтому без свободного места проверка откладывается
+// до следующего интервала, а соединений не становится больше size
+func (p *pool) healthCheck(interval time.Duration, check func(*conn) error) {
+	for p.slots.TryAcquire() {
+		c := p.takeIdle(interval)
+		if c == nil {
+			p.slots.Release()
+			return
+		}
+
+		if err := check(c); err != nil {
+			c.broken = true
+		}
+		p.put(c)
+	}
+}
+
+func (p *pool) idleCount() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.idle)
+}
+
+func (p *pool) close() {
+	p.mutex.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mutex.Unlock()
+
+	for _, c := range idle {
+		_ = c.close()
+	}
+}
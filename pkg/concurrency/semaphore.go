@@ -1,30 +1,95 @@
 package concurrency
 
-// Semaphore -- ограничивает количество конкуретных задач в момент времени
+import (
+	"context"
+	"sync"
+)
+
+// Semaphore -- ограничивает количество конкуретных задач в момент времени,
+// предел можно изменить на лету через Resize
 type Semaphore struct {
-	tickets chan struct{}
+	state *semaphoreState
+}
+
+type semaphoreState struct {
+	mutex sync.Mutex
+	limit int
+	count int
+	// changed закрывается при освобождении места или изменении предела
+	changed chan struct{}
 }
 
 // NewSemaphore -- возвращает новый объект Semaphore
 func NewSemaphore(n int) Semaphore {
-	return Semaphore{tickets: make(chan struct{}, n)}
+	return Semaphore{state: &semaphoreState{
+		limit:   n,
+		changed: make(chan struct{}),
+	}}
 }
 
 // Acquire -- попытка пройти за семафор
 func (s *Semaphore) Acquire() {
-	if s == nil || s.tickets == nil {
-		return
+	_ = s.AcquireWithContext(context.Background())
+}
+
+// TryAcquire -- попытка пройти за семафор без ожидания
+func (s *Semaphore) TryAcquire() bool {
+	if s == nil || s.state == nil {
+		return true
+	}
+
+	acquired, _ := s.state.tryAcquire()
+	return acquired
+}
+
+// AcquireWithContext -- ожидание прохода за семафор до отмены контекста
+func (s *Semaphore) AcquireWithContext(ctx context.Context) error {
+	if s == nil || s.state == nil {
+		return nil
+	}
+
+	for {
+		acquired, changed := s.state.tryAcquire()
+		if acquired {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	s.tickets <- struct{}{}
 }
 
-// Release -- освобождение семафора для других задач
+// Release -- освобождение семафора для других задач, освобождение
+// без соответствующего прохода - ошибка программы, как и у sync.Mutex
 func (s *Semaphore) Release() {
-	if s == nil || s.tickets == nil {
+	if s == nil || s.state == nil {
 		return
 	}
-	
-	<-s.tickets
+
+	WithLock(&s.state.mutex, func() {
+		if s.state.count == 0 {
+			panic("concurrency: release of unacquired semaphore")
+		}
+
+		s.state.count--
+		s.state.notify()
+	})
+}
+
+// Resize -- меняет предел, задачи сверх нового предела не прерываются,
+// новые проходят после их завершения
+func (s *Semaphore) Resize(n int) {
+	if s == nil || s.state == nil {
+		return
+	}
+
+	WithLock(&s.state.mutex, func() {
+		s.state.limit = n
+		s.state.notify()
+	})
 }
 
 // WithSemaphore -- helper
@@ -37,3 +102,21 @@ func (s *Semaphore) WithSemaphore(action func()) {
 	action()
 	s.Release()
 }
+
+// tryAcquire -- при неудаче возвращает канал, который закроется при изменении состояния
+func (s *semaphoreState) tryAcquire() (bool, <-chan struct{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.count < s.limit {
+		s.count++
+		return true, nil
+	}
+
+	return false, s.changed
+}
+
+func (s *semaphoreState) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
@@ -0,0 +1,245 @@
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitAcquire -- запускает AcquireWithContext в горутине, результат придет в канал
+func waitAcquire(ctx context.Context, semaphore *Semaphore) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- semaphore.AcquireWithContext(ctx)
+	}()
+
+	return result
+}
+
+func requireBlocked(t *testing.T, result <-chan error) {
+	t.Helper()
+
+	select {
+	case err := <-result:
+		require.FailNow(t, "acquire is not blocked", "result: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func requireAcquired(t *testing.T, result <-chan error) {
+	t.Helper()
+
+	select {
+	case err := <-result:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "acquire is blocked")
+	}
+}
+
+func TestSemaphoreAcquireRelease(t *testing.T) {
+	t.Parallel()
+
+	semaphore := NewSemaphore(2)
+	assert.True(t, semaphore.TryAcquire())
+	assert.NoError(t, semaphore.AcquireWithContext(context.Background()))
+	assert.False(t, semaphore.TryAcquire())
+
+	semaphore.Release()
+	assert.True(t, semaphore.TryAcquire())
+
+	semaphore.Release()
+	semaphore.Release()
+	assert.True(t, semaphore.TryAcquire())
+	assert.True(t, semaphore.TryAcquire())
+}
+
+func TestSemaphoreWakeupAfterRelease(t *testing.T) {
+	t.Parallel()
+
+	semaphore := NewSemaphore(1)
+	semaphore.Acquire()
+
+	result := waitAcquire(context.Background(), &semaphore)
+	requireBlocked(t, result)
+
+	semaphore.Release()
+	requireAcquired(t, result)
+	assert.False(t, semaphore.TryAcquire())
+}
+
+func TestSemaphoreReleaseWakesOneWaiter(t *testing.T) {
+	t.Parallel()
+
+	semaphore := NewSemaphore(1)
+	semaphore.Acquire()
+
+	first := waitAcquire(context.Background(), &semaphore)
+	second := waitAcquire(context.Background(), &semaphore)
+	requireBlocked(t, first)
+	requireBlocked(t, second)
+
+	semaphore.Release()
+	select {
+	case err := <-first:
+		require.NoError(t, err)
+		requireBlocked(t, second)
+	case err := <-second:
+		require.NoError(t, err)
+		requireBlocked(t, first)
+	case <-time.After(time.Second):
+		require.FailNow(t, "acquire is blocked")
+	}
+}
+
+func TestSemaphoreAcquireWithContext(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		ctx func() (context.Context, context.CancelFunc)
+
+		expectedErr error
+	}{
+		"canceled context": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			expectedErr: context.Canceled,
+		},
+		"context with timeout": {
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+			expectedErr: context.DeadlineExceeded,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			semaphore := NewSemaphore(1)
+			semaphore.Acquire()
+
+			ctx, cancel := test.ctx()
+			defer cancel()
+
+			err := semaphore.AcquireWithContext(ctx)
+			assert.ErrorIs(t, err, test.expectedErr)
+
+			// отмененное ожидание не занимает место
+			semaphore.Release()
+			assert.True(t, semaphore.TryAcquire())
+		})
+	}
+}
+
+func TestSemaphoreUnmatchedRelease(t *testing.T) {
+	t.Parallel()
+
+	semaphore := NewSemaphore(1)
+	assert.Panics(t, semaphore.Release)
+
+	semaphore.Acquire()
+	semaphore.Release()
+	assert.Panics(t, semaphore.Release)
+	assert.True(t, semaphore.TryAcquire())
+}
+
+func TestNilSemaphore(t *testing.T) {
+	t.Parallel()
+
+	var semaphore *Semaphore
+	assert.True(t, semaphore.TryAcquire())
+	assert.NoError(t, semaphore.AcquireWithContext(context.Background()))
+	assert.NotPanics(t, semaphore.Release)
+	assert.NotPanics(t, func() { semaphore.Resize(1) })
+}
+
+func TestSemaphoreWithSemaphore(t *testing.T) {
+	t.Parallel()
+
+	semaphore := NewSemaphore(1)
+	called := false
+	semaphore.WithSemaphore(func() {
+		called = true
+		assert.False(t, semaphore.TryAcquire())
+	})
+
+	assert.True(t, called)
+	assert.True(t, semaphore.TryAcquire())
+}
+
+func TestSemaphoreShrinkWhileHeld(t *testing.T) {
+	t.Parallel()
+
+	semaphore := NewSemaphore(3)
+	for i := 0; i < 3; i++ {
+		require.True(t, semaphore.TryAcquire())
+	}
+
+	// занятые места не отбираются, новые проходы ждут, пока занятых станет меньше предела
+	semaphore.Resize(1)
+	result := waitAcquire(context.Background(), &semaphore)
+	requireBlocked(t, result)
+
+	semaphore.Release()
+	requireBlocked(t, result)
+	semaphore.Release()
+	requireBlocked(t, result)
+
+	semaphore.Release()
+	requireAcquired(t, result)
+	assert.False(t, semaphore.TryAcquire())
+}
+
+func TestSemaphoreGrowWakesWaiters(t *testing.T) {
+	t.Parallel()
+
+	semaphore := NewSemaphore(1)
+	semaphore.Acquire()
+
+	waiters := make([]<-chan error, 3)
+	for i := range waiters {
+		waiters[i] = waitAcquire(context.Background(), &semaphore)
+	}
+	for _, result := range waiters {
+		requireBlocked(t, result)
+	}
+
+	// расширение будит ожидающих без Release
+	semaphore.Resize(4)
+	for _, result := range waiters {
+		requireAcquired(t, result)
+	}
+	assert.False(t, semaphore.TryAcquire())
+}
+
+func TestSemaphoreGrowPartially(t *testing.T) {
+	t.Parallel()
+
+	semaphore := NewSemaphore(1)
+	semaphore.Acquire()
+
+	first := waitAcquire(context.Background(), &semaphore)
+	second := waitAcquire(context.Background(), &semaphore)
+	requireBlocked(t, first)
+	requireBlocked(t, second)
+
+	semaphore.Resize(2)
+	select {
+	case err := <-first:
+		require.NoError(t, err)
+		requireBlocked(t, second)
+	case err := <-second:
+		require.NoError(t, err)
+		requireBlocked(t, first)
+	case <-time.After(time.Second):
+		require.FailNow(t, "acquire is blocked")
+	}
+}